@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/verifier"
+)
+
+// registerCacheAdmin serves the cache flush, invalidation and stats
+// endpoints on router's /admin/cache.
+func registerCacheAdmin(router *mux.Router) {
+	protect(router, "/admin/cache", handleFlushCache).Methods(http.MethodDelete)
+	protect(router, "/admin/cache/stats", handleCacheStats).Methods(http.MethodGet)
+	protect(router, "/admin/cache/{id:[a-f0-9]{64}}", handleInvalidateClaim).Methods(http.MethodDelete)
+}
+
+type CacheEvictResponse struct {
+	Evicted int `json:"evicted"`
+}
+
+type CacheStatsResponse struct {
+	Caches []verifier.CacheStats `json:"caches"`
+}
+
+func handleFlushCache(w http.ResponseWriter, r *http.Request) {
+	n := verify.FlushCaches()
+	requestLog(r).Info("Flushed caches", "evicted", n)
+	RespondJSON(w, http.StatusOK, CacheEvictResponse{Evicted: n})
+}
+
+func handleInvalidateClaim(w http.ResponseWriter, r *http.Request) {
+	txid := mux.Vars(r)["id"]
+	n := verify.InvalidateClaim(txid)
+	requestLog(r).Info("Invalidated cached claim", "txid", txid, "evicted", n)
+	RespondJSON(w, http.StatusOK, CacheEvictResponse{Evicted: n})
+}
+
+func handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, http.StatusOK, CacheStatsResponse{Caches: verify.CacheStats()})
+}
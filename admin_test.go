@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCacheAdminRoutes(t *testing.T) {
+	fakeUpstreams(t)
+	oldKeys := apiKeys.keys
+	t.Cleanup(func() { apiKeys.set(oldKeys) })
+	apiKeys.set(map[string]string{"secret": "ops"})
+
+	// only served with -enable-cache-admin
+	req := httptest.NewRequest(http.MethodDelete, "/verified/admin/cache", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without -enable-cache-admin: got %d, want 404", rec.Code)
+	}
+
+	r := mux.NewRouter()
+	registerCacheAdmin(r)
+	if code := get("/verified/publisher/check/" + verifiedClaim).Code; code != http.StatusOK {
+		t.Fatalf("check: got %d", code)
+	}
+	tests := []struct {
+		method, path, key string
+		code              int
+	}{
+		{http.MethodGet, "/admin/cache/stats", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/admin/cache/" + verifiedClaim, "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/cache/stats", "secret", http.StatusOK},
+		{http.MethodDelete, "/admin/cache/" + verifiedClaim, "secret", http.StatusOK},
+		{http.MethodDelete, "/admin/cache", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s with key %q: got %d, want %d", tt.method, tt.path, tt.key, rec.Code, tt.code)
+		}
+		if rec.Code == http.StatusOK && tt.method == http.MethodDelete {
+			var res CacheEvictResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Errorf("%s %s: %v", tt.method, tt.path, err)
+			} else if tt.path != "/admin/cache" && res.Evicted == 0 {
+				t.Errorf("%s %s: evicted nothing", tt.method, tt.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/verifier"
+)
+
+// apiKeys are the keys accepted by protected routes, mapped to their
+// labels. While there are none, protected routes refuse every request,
+// unless insecureOpenAdmin is set.
+var apiKeys keyring
+
+// insecureOpenAdmin opens protected routes to everyone while there are no
+// apiKeys, for servers that are only reachable by their operators.
+var insecureOpenAdmin bool
+
+type keyring struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+func (k *keyring) set(keys map[string]string) {
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+}
+
+// lookup returns the label of key, comparing in constant time.
+func (k *keyring) lookup(key string) (label string, ok bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for known, l := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(key)) == 1 {
+			label, ok = l, true
+		}
+	}
+	return label, ok
+}
+
+func (k *keyring) empty() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys) == 0
+}
+
+// parseKey parses label:key, or a key without a label.
+func parseKey(s string) (key, label string) {
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[i+1:], s[:i]
+	}
+	return s, ""
+}
+
+// loadKeys reads the comma separated keys of list and, if path is set, the
+// keys in the file at path, one per line. Blank lines and lines starting
+// with # are ignored.
+func loadKeys(list, path string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, s := range splitList(list) {
+		key, label := parseKey(s)
+		keys[key] = label
+	}
+	if path == "" {
+		return keys, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, label := parseKey(line)
+		keys[key] = label
+	}
+	return keys, scanner.Err()
+}
+
+// reloadKeysOnHUP rereads the keys whenever the process gets SIGHUP,
+// keeping the current ones if that fails.
+func reloadKeysOnHUP(list, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		keys, err := loadKeys(list, path)
+		if err != nil {
+			slog.Error("Unable to reload api keys, keeping the current ones", "err", err, "file", path)
+			continue
+		}
+		apiKeys.set(keys)
+		slog.Info("Reloaded api keys", "file", path, "keys", len(keys))
+	}
+}
+
+// requestKey returns the api key of r, from Authorization: Bearer or
+// X-API-Key.
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// requireKey rejects requests without one of apiKeys with a 401, and all
+// requests while there are none.
+func requireKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiKeys.empty() {
+			if insecureOpenAdmin {
+				next(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="verifier"`)
+			RespondJSON(w, http.StatusUnauthorized, ErrorResponse{Code: verifier.CodeUnauthorized, Msg: "This endpoint requires an api key and the server has none configured"})
+			return
+		}
+		label, ok := apiKeys.lookup(requestKey(r))
+		if !ok {
+			prefix := "unknown"
+			if ip := clientIP(r); ip != nil {
+				prefix = ipPrefix(ip)
+			}
+			authFailures.WithLabelValues(prefix).Inc()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="verifier"`)
+			RespondJSON(w, http.StatusUnauthorized, ErrorResponse{Code: verifier.CodeUnauthorized, Msg: "A valid api key is required in Authorization: Bearer or X-API-Key"})
+			return
+		}
+		setKeyLabel(r.Context(), label)
+		next(w, r)
+	}
+}
+
+// protect registers h on router at path, behind requireKey.
+func protect(router *mux.Router, path string, h http.HandlerFunc) *mux.Route {
+	return router.HandleFunc(path, requireKey(h))
+}
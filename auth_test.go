@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireKey(t *testing.T) {
+	oldKeys, oldOpen := apiKeys.keys, insecureOpenAdmin
+	t.Cleanup(func() { apiKeys.set(oldKeys); insecureOpenAdmin = oldOpen })
+	h := requireKey(func(w http.ResponseWriter, r *http.Request) { RespondText(w, http.StatusOK, "ok") })
+	serve := func(header ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/verified/v1/publisher/debug/"+verifiedClaim, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		keys   map[string]string
+		open   bool
+		header []string
+		code   int
+	}{
+		// without keys, protected routes fail closed
+		{nil, false, nil, http.StatusUnauthorized},
+		{nil, false, []string{"Authorization", "Bearer anything"}, http.StatusUnauthorized},
+		{map[string]string{}, false, []string{"X-API-Key", ""}, http.StatusUnauthorized},
+		{nil, true, nil, http.StatusOK},
+		{map[string]string{"secret": "ops"}, false, nil, http.StatusUnauthorized},
+		{map[string]string{"secret": "ops"}, false, []string{"Authorization", "Bearer wrong"}, http.StatusUnauthorized},
+		{map[string]string{"secret": "ops"}, false, []string{"Authorization", "Bearer secret"}, http.StatusOK},
+		{map[string]string{"secret": "ops"}, false, []string{"Authorization", "bearer secret"}, http.StatusOK},
+		{map[string]string{"secret": "ops"}, false, []string{"X-API-Key", "secret"}, http.StatusOK},
+		// keys apply even with -insecure-open-admin
+		{map[string]string{"secret": "ops"}, true, nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		apiKeys.set(tt.keys)
+		insecureOpenAdmin = tt.open
+		if code := serve(tt.header...); code != tt.code {
+			t.Errorf("keys %v open %v header %q: got %d, want %d", tt.keys, tt.open, tt.header, code, tt.code)
+		}
+	}
+}
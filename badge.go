@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/verifier"
+)
+
+func init() {
+	rootRouter.HandleFunc("/publisher/badge/{id:[a-f0-9]{64}}.svg", handleBadge).Methods(http.MethodGet)
+}
+
+// badgeMaxAge is how long browsers may reuse a badge.
+var badgeMaxAge = 5 * time.Minute
+
+const (
+	badgeVerified   = "#4c1"
+	badgeUnverified = "#9f9f9f"
+)
+
+// handleBadge renders the check of a claim as a shields.io style SVG badge,
+// with the label given by ?label= (default OIP) and ?style= flat or plastic.
+func handleBadge(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	label := q.Get("label")
+	if label == "" {
+		label = "OIP"
+	}
+	style := q.Get("style")
+	if style != "" && style != "flat" && style != "plastic" {
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "style must be flat or plastic"})
+		return
+	}
+
+	status, err := sharedCheckClaim(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		// the client went away before the check finished
+		return
+	}
+
+	message, color := "unverified", badgeUnverified
+	if status.Verified {
+		var passed []string
+		for _, name := range verify.Platforms() {
+			if ps, ok := status.Platforms[name]; ok && ps.Verified {
+				passed = append(passed, name)
+			}
+		}
+		message, color = "verified: "+strings.Join(passed, ", "), badgeVerified
+	}
+	svg := renderBadge(label, message, color, style == "plastic")
+	RespondCacheable(w, r, badgeMaxAge, "image/svg+xml", []byte(svg))
+}
+
+// renderBadge draws a two part badge with label on grey and message on
+// color. The plastic style adds a gloss gradient and more rounding.
+func renderBadge(label, message, color string, plastic bool) string {
+	lw := textWidth(label) + 10
+	mw := textWidth(message) + 10
+	width := lw + mw
+	radius, gradient := 3, `<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`
+	if plastic {
+		radius, gradient = 4, `<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#fff" stop-opacity=".7"/><stop offset=".1" stop-color="#aaa" stop-opacity=".1"/><stop offset=".9" stop-opacity=".3"/><stop offset="1" stop-opacity=".5"/></linearGradient>`
+	}
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, message)
+	b.WriteString(gradient)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="%d" fill="#fff"/></clipPath>`, width, radius)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`, lw, lw, mw, color, width)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&b, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, float64(lw)/2, label, float64(lw)/2, label)
+	fmt.Fprintf(&b, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, float64(lw)+float64(mw)/2, message, float64(lw)+float64(mw)/2, message)
+	b.WriteString(`</g></svg>`)
+	return b.String()
+}
+
+// verdanaWidths are the advance widths at 11px of the characters of
+// Verdana that differ much from the average.
+var verdanaWidths = map[rune]float64{
+	' ': 3.9, '!': 4.3, '"': 5.1, '\'': 3, '(': 4.3, ')': 4.3, ',': 3.9, '-': 4.3, '.': 3.9, '/': 4.3,
+	':': 4.3, ';': 4.3, 'I': 4.3, 'J': 4.9, 'M': 8.5, 'W': 10.8, 'f': 3.9, 'i': 3, 'j': 3.3, 'l': 3,
+	'm': 10.7, 'r': 4.6, 't': 4.3, 'w': 9.2, '|': 5.5,
+}
+
+// textWidth estimates the width in pixels of s in 11px Verdana, rounded up.
+func textWidth(s string) int {
+	var w float64
+	for _, c := range s {
+		switch cw, ok := verdanaWidths[c]; {
+		case ok:
+			w += cw
+		case c >= 'A' && c <= 'Z':
+			w += 7.5
+		case c < 0x80:
+			w += 6.6
+		default:
+			w += 8
+		}
+	}
+	return int(w + 0.999)
+}
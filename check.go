@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+// runCheck verifies a single claim, prints the response as JSON to stdout,
+// and returns the process exit code: 0 when every platform the claim
+// references verified, 1 when not, and 2 on usage or setup errors.
+func runCheck(args []string) int {
+	flags, opts := newFlagSet("check")
+	platform := flags.String("platform", "", "Only check the claim on this platform (e.g. twitter or gab)")
+	pretty := flags.Bool("pretty", false, "Indent the JSON output")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: verifier check [flags] <claim-txid>")
+		flags.PrintDefaults()
+	}
+	err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || !txidRegex.MatchString(flags.Arg(0)) {
+		flags.Usage()
+		return 2
+	}
+
+	err = setup(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *platform != "" {
+		err = verify.Restrict(*platform)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	status, err := verify.CheckClaim(context.Background(), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if *pretty {
+		enc.SetIndent("", "  ")
+	}
+	err = enc.Encode(status.Legacy())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if !fullyVerified(status) {
+		return 1
+	}
+	return 0
+}
+
+// fullyVerified reports whether the claim was checked on at least one
+// platform and every checked platform verified the same publisher.
+func fullyVerified(status *verifier.Result) bool {
+	if status.Code != verifier.CodeOK || len(status.Platforms) == 0 || status.CrossPlatformMsg != "" {
+		return false
+	}
+	for _, ps := range status.Platforms {
+		if !ps.Verified {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+var (
+	// gzipMinSize is the smallest response compressed for clients that
+	// accept gzip. Zero disables compression.
+	gzipMinSize = 1024
+	// maxRequestBody is the largest request body limitBody accepts.
+	maxRequestBody int64 = 64 << 10
+
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+)
+
+// compressible are the content types worth compressing; images other than
+// SVG, and anything already encoded, are sent as is.
+var compressible = map[string]bool{
+	"application/json": true,
+	"text/plain":       true,
+	"text/html":        true,
+	"image/svg+xml":    true,
+}
+
+// acceptsGzip reports whether r's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		f, err := strconv.ParseFloat(q, 64)
+		return err == nil && f > 0
+	}
+	return false
+}
+
+// compress gzips responses of at least gzipMinSize bytes in a compressible
+// content type, for clients that accept it.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gzipMinSize <= 0 || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipWriter holds back the start of a response until it knows whether the
+// response is large enough to compress.
+type gzipWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	started bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.started || w.status != 0 {
+		return
+	}
+	w.status = code
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		w.start(false)
+	}
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < gzipMinSize {
+			return len(b), nil
+		}
+		err := w.start(w.compressible())
+		return len(b), err
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return compressible[mediaType]
+}
+
+// start sends the header and whatever was held back, compressed or not.
+func (w *gzipWriter) start(compress bool) error {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if compress {
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// Flush sends a response that hasn't reached gzipMinSize uncompressed, so
+// that /verified/stream events aren't held back.
+func (w *gzipWriter) Flush() {
+	if !w.started {
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipWriter) close() {
+	if !w.started {
+		if w.status == 0 && len(w.buf) == 0 {
+			return
+		}
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+// limitBody limits the request body of h to maxRequestBody, answering larger
+// requests with a 413.
+func limitBody(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxRequestBody {
+			respondTooLarge(w)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		h(w, r)
+	}
+}
+
+// tooLarge reports whether err is from reading past a limitBody limit.
+func tooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+func respondTooLarge(w http.ResponseWriter) {
+	RespondJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Code: verifier.CodeTooLarge, Msg: "Request body must be at most " + strconv.FormatInt(maxRequestBody, 10) + " bytes"})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rs/cors"
+)
+
+func TestCheckVary(t *testing.T) {
+	fakeUpstreams(t)
+	oldMin := gzipMinSize
+	t.Cleanup(func() { gzipMinSize = oldMin })
+	// small enough that the check response is compressed
+	gzipMinSize = 64
+	h := cors.New(newCorsOptions("https://*.oip.io", "", "", 0, false)).Handler(compress(router))
+
+	for _, accept := range []string{"application/json", "text/plain"} {
+		req := httptest.NewRequest(http.MethodGet, "/verified/publisher/check/"+verifiedClaim, nil)
+		req.Header.Set("Origin", "https://app.oip.io")
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d", accept, rec.Code)
+		}
+
+		var vary []string
+		for _, v := range rec.Header().Values("Vary") {
+			for _, f := range strings.Split(v, ",") {
+				vary = append(vary, strings.TrimSpace(f))
+			}
+		}
+		for _, want := range []string{"Origin", "Accept-Encoding", "Accept"} {
+			if !slices.Contains(vary, want) {
+				t.Errorf("%s: got Vary %q, want %s among them", accept, vary, want)
+			}
+		}
+		if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.oip.io" {
+			t.Errorf("%s: got Access-Control-Allow-Origin %q", accept, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		// the text/plain summary is too short to compress
+		if accept == "application/json" && rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Errorf("%s: response not compressed", accept)
+		}
+	}
+}
+
+// discardWriter is a ResponseWriter that throws the response away.
+type discardWriter struct{ h http.Header }
+
+func (w discardWriter) Header() http.Header         { return w.h }
+func (w discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardWriter) WriteHeader(int)             {}
+
+func BenchmarkCompress(b *testing.B) {
+	body := bytes.Repeat([]byte(`{"code":"OK","verified":true,"platforms":{"twitter":{"verified":true,"code":"OK"}}},`), 200)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	b.Run("pooled", func(b *testing.B) {
+		h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		}))
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			h.ServeHTTP(discardWriter{make(http.Header)}, req)
+		}
+	})
+	// what every response would cost without the pool
+	b.Run("new-writer", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(body)))
+		for i := 0; i < b.N; i++ {
+			gz := gzip.NewWriter(ioutil.Discard)
+			gz.Write(body)
+			gz.Close()
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RespondCacheable writes body as a 200 response with a strong ETag derived
+// from it and a Cache-Control max-age, or a 304 when the request's
+// If-None-Match already has that ETag.
+func RespondCacheable(w http.ResponseWriter, r *http.Request, maxAge time.Duration, contentType string, body []byte) {
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	n, err := w.Write(body)
+	if err != nil {
+		slog.Error("Unable to write response", "n", n, "err", err, "contentType", contentType)
+	}
+}
+
+// etagMatch reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 7232 requires.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the -config file flags were read from, if any.
+var configFile string
+
+// readConfigFile reads the YAML or TOML file at path, telling them apart by
+// extension, into a map of flag names to values.
+func readConfigFile(path string) (map[string]interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &values)
+	case ".toml":
+		err = toml.Unmarshal(b, &values)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml, or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return values, nil
+}
+
+// configValues converts the values of a config file to what each flag is
+// Set with, rejecting keys that aren't flags. Lists are set one item at a
+// time for repeatable flags and joined with commas for the others.
+func configValues(flags *flag.FlagSet, path string, values map[string]interface{}) (map[string][]string, error) {
+	set := make(map[string][]string, len(values))
+	for key, value := range values {
+		f := flags.Lookup(key)
+		if f == nil || key == "config" || key == "print-config" {
+			return nil, fmt.Errorf("unknown key %q in config file %s", key, path)
+		}
+		switch v := value.(type) {
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			if _, repeatable := f.Value.(*stringsFlag); repeatable {
+				set[key] = items
+			} else {
+				set[key] = []string{strings.Join(items, ",")}
+			}
+		case map[string]interface{}:
+			return nil, fmt.Errorf("key %q in config file %s must be a value or a list, not a table", key, path)
+		default:
+			set[key] = []string{fmt.Sprint(v)}
+		}
+	}
+	return set, nil
+}
+
+// applyConfigFile sets the flags not given on the command line or in the
+// environment from the config file at path.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	set, err := configValues(flags, path, values)
+	if err != nil {
+		return err
+	}
+	configExplicit = make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		configExplicit[f.Name] = true
+	})
+	for name, items := range set {
+		if configExplicit[name] {
+			continue
+		}
+		for _, item := range items {
+			err := flags.Set(name, item)
+			if err != nil {
+				return fmt.Errorf("invalid value for %q in config file %s: %v", name, path, err)
+			}
+		}
+	}
+	configFile = path
+	return nil
+}
+
+// redacted reports whether the value of the named flag is hidden by
+// -print-config.
+func redacted(name string) bool {
+	if name == "api-keys" {
+		return true
+	}
+	for _, secret := range secretFlags {
+		if name == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// printConfig writes the effective value of every flag to stdout as a YAML
+// config file, with secrets redacted.
+func printConfig(flags *flag.FlagSet) error {
+	config := make(map[string]interface{})
+	flags.VisitAll(func(f *flag.Flag) {
+		switch {
+		case f.Name == "config" || f.Name == "print-config":
+		case redacted(f.Name) && f.Value.String() != "":
+			config[f.Name] = "REDACTED"
+		default:
+			config[f.Name] = f.Value.String()
+			if list, ok := f.Value.(*stringsFlag); ok {
+				config[f.Name] = []string(*list)
+			} else if g, ok := f.Value.(flag.Getter); ok {
+				switch v := g.Get().(type) {
+				case bool, int, int64, uint, uint64, float64:
+					config[f.Name] = v
+				}
+			}
+		}
+	})
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(config)
+}
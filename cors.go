@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/cors"
+)
+
+// corsPolicy is the *cors.Cors of the http api, replaced when the config is
+// reloaded. No origin is allowed until runServe configures it.
+var corsPolicy atomic.Value
+
+func init() {
+	corsPolicy.Store(cors.New(newCorsOptions("", "", "", 0, false)))
+}
+
+// withCORS applies the current corsPolicy.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corsPolicy.Load().(*cors.Cors).ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
+
+// newCorsOptions builds the CORS policy from the comma separated origins,
+// which may have one * wildcard each (https://*.oip.io), and request and
+// response headers.
+func newCorsOptions(origins, headers, expose string, maxAge time.Duration, allowAll bool) cors.Options {
+	o := cors.Options{
+		AllowedOrigins: splitList(origins),
+		AllowedMethods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		AllowedHeaders: splitList(headers),
+		ExposedHeaders: splitList(expose),
+		MaxAge:         int(maxAge.Seconds()),
+	}
+	switch {
+	case allowAll:
+		o.AllowedOrigins = []string{"*"}
+	case len(o.AllowedOrigins) == 0:
+		// cors allows every origin when none are listed
+		o.AllowOriginFunc = func(string) bool { return false }
+	}
+	return o
+}
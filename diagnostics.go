@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// enablePprof serves the pprof profiles and /admin/runtime, behind the
+	// api keys.
+	enablePprof bool
+	// pprofOnMetrics serves them on the -metrics-listen listener instead of
+	// the http api's.
+	pprofOnMetrics bool
+)
+
+type RuntimeResponse struct {
+	Goroutines int       `json:"goroutines"`
+	Heap       HeapStats `json:"heap"`
+	GC         GCStats   `json:"gc"`
+	// OpenFDs is the number of open file descriptors, or -1 where that is
+	// unknown.
+	OpenFDs int `json:"open_fds"`
+}
+
+type HeapStats struct {
+	AllocBytes    uint64 `json:"alloc_bytes"`
+	InuseBytes    uint64 `json:"inuse_bytes"`
+	IdleBytes     uint64 `json:"idle_bytes"`
+	ReleasedBytes uint64 `json:"released_bytes"`
+	SysBytes      uint64 `json:"sys_bytes"`
+	Objects       uint64 `json:"objects"`
+}
+
+type GCStats struct {
+	Count             uint32  `json:"count"`
+	PauseTotalSeconds float64 `json:"pause_total_seconds"`
+	// RecentPausesSeconds are the latest pauses, newest first.
+	RecentPausesSeconds []float64  `json:"recent_pauses_seconds"`
+	Last                *time.Time `json:"last,omitempty"`
+}
+
+// registerDiagnostics serves the pprof profiles on router's
+// /admin/debug/pprof/ and the runtime stats on /admin/runtime.
+func registerDiagnostics(router *mux.Router) {
+	protect(router, "/admin/runtime", handleRuntime).Methods(http.MethodGet)
+
+	// pprof.Index only serves the named profiles under /debug/pprof/
+	protect(router, "/admin/debug/pprof/", pprof.Index).Methods(http.MethodGet)
+	protect(router, "/admin/debug/pprof/cmdline", pprof.Cmdline).Methods(http.MethodGet)
+	protect(router, "/admin/debug/pprof/profile", pprof.Profile).Methods(http.MethodGet)
+	protect(router, "/admin/debug/pprof/symbol", pprof.Symbol).Methods(http.MethodGet, http.MethodPost)
+	protect(router, "/admin/debug/pprof/trace", pprof.Trace).Methods(http.MethodGet)
+	protect(router, "/admin/debug/pprof/{profile}", handleProfile).Methods(http.MethodGet)
+}
+
+// diagnosticsHandler serves registerDiagnostics' routes under /verified, for
+// the metrics listener.
+func diagnosticsHandler() http.Handler {
+	r := mux.NewRouter()
+	registerDiagnostics(r.PathPrefix("/verified").Subrouter())
+	return r
+}
+
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(mux.Vars(r)["profile"]).ServeHTTP(w, r)
+}
+
+func handleRuntime(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	res := RuntimeResponse{
+		Goroutines: runtime.NumGoroutine(),
+		Heap: HeapStats{
+			AllocBytes:    m.HeapAlloc,
+			InuseBytes:    m.HeapInuse,
+			IdleBytes:     m.HeapIdle,
+			ReleasedBytes: m.HeapReleased,
+			SysBytes:      m.HeapSys,
+			Objects:       m.HeapObjects,
+		},
+		GC: GCStats{
+			Count:               m.NumGC,
+			PauseTotalSeconds:   time.Duration(m.PauseTotalNs).Seconds(),
+			RecentPausesSeconds: []float64{},
+		},
+		OpenFDs: openFDs(),
+	}
+	if m.NumGC > 0 {
+		last := time.Unix(0, int64(m.LastGC)).UTC()
+		res.GC.Last = &last
+	}
+	// PauseNs is a circular buffer with the latest pause at (NumGC+255)%256
+	for i := uint32(0); i < m.NumGC && i < 16; i++ {
+		pause := m.PauseNs[(m.NumGC-1-i)%uint32(len(m.PauseNs))]
+		res.GC.RecentPausesSeconds = append(res.GC.RecentPausesSeconds, time.Duration(pause).Seconds())
+	}
+	RespondJSON(w, http.StatusOK, res)
+}
+
+// openFDs counts the entries of /proc/self/fd, which only exists on Linux.
+func openFDs() int {
+	f, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	// one of them is f itself
+	return len(names) - 1
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPprofRoutes(t *testing.T) {
+	oldKeys := apiKeys.keys
+	t.Cleanup(func() { apiKeys.set(oldKeys) })
+	apiKeys.set(map[string]string{"secret": "ops"})
+	serve := func(h http.Handler, path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	paths := []string{
+		"/verified/admin/debug/pprof/",
+		"/verified/admin/debug/pprof/cmdline",
+		"/verified/admin/debug/pprof/heap?debug=1",
+		"/verified/admin/debug/pprof/goroutine?debug=1",
+		"/verified/admin/runtime",
+	}
+
+	// without -enable-pprof, router has none of them
+	for _, path := range paths {
+		if code := serve(router, path, "secret"); code != http.StatusNotFound {
+			t.Errorf("disabled %s: got %d, want 404", path, code)
+		}
+	}
+
+	// as registered on the http api or, with -pprof-on-metrics, the
+	// metrics listener
+	h := diagnosticsHandler()
+	for _, path := range paths {
+		if code := serve(h, path, "secret"); code != http.StatusOK {
+			t.Errorf("enabled %s: got %d, want 200", path, code)
+		}
+		if code := serve(h, path, ""); code != http.StatusUnauthorized {
+			t.Errorf("enabled %s without a key: got %d, want 401", path, code)
+		}
+	}
+	if code := serve(h, "/verified/admin/debug/pprof/nonexistent", "secret"); code != http.StatusNotFound {
+		t.Errorf("unknown profile: got %d, want 404", code)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixtureCredentials(t *testing.T) {
+	dir := t.TempDir()
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: ioutil.NopCloser(strings.NewReader(`{"items": []}`)), Request: req}, nil
+	})
+	do := func(rt http.RoundTripper, method, rawurl, form string) (*http.Response, error) {
+		var body io.Reader
+		if form != "" {
+			body = strings.NewReader(form)
+		}
+		req, _ := http.NewRequest(method, rawurl, body)
+		if form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		return rt.RoundTrip(req)
+	}
+	record, err := newFixtureTransport(dir, false, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = do(record, http.MethodGet, "https://www.googleapis.com/youtube/v3/videos?part=snippet&id=abc&key=youtube-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = do(record, http.MethodGet, "https://api-v2.soundcloud.com/resolve?url=https%3A%2F%2Fsoundcloud.com%2Fx&client_id=soundcloud-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = do(record, http.MethodPost, "https://id.twitch.tv/oauth2/token", "client_id=twitch-id&client_secret=twitch-secret&grant_type=client_credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("recorded %d fixtures, want 3", len(files))
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"youtube-secret", "soundcloud-secret", "twitch-id", "twitch-secret", "key=", "client_id"} {
+			if strings.Contains(string(b), secret) {
+				t.Errorf("%s holds %s:\n%s", filepath.Base(file), secret, b)
+			}
+		}
+	}
+
+	// the fixtures replay with other credentials, or none
+	replay, err := newFixtureTransport(dir, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := []struct{ method, url, form string }{
+		{http.MethodGet, "https://www.googleapis.com/youtube/v3/videos?key=other&id=abc&part=snippet", ""},
+		{http.MethodGet, "https://www.googleapis.com/youtube/v3/videos?part=snippet&id=abc", ""},
+		{http.MethodGet, "https://api-v2.soundcloud.com/resolve?client_id=other&url=" + url.QueryEscape("https://soundcloud.com/x"), ""},
+		{http.MethodPost, "https://id.twitch.tv/oauth2/token", "grant_type=client_credentials&client_secret=other&client_id=other"},
+	}
+	for _, r := range replayed {
+		res, err := do(replay, r.method, r.url, r.form)
+		if err != nil {
+			t.Errorf("replaying %s %s: %v", r.method, r.url, err)
+			continue
+		}
+		res.Body.Close()
+	}
+	if _, err := do(replay, http.MethodGet, "https://www.googleapis.com/youtube/v3/videos?part=snippet&id=other&key=youtube-secret", ""); err != errNoFixture {
+		t.Errorf("replaying another video: got %v, want errNoFixture", err)
+	}
+}
+
+func TestFixturesWrapSetupTransport(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		err := setupFlags(t, "-fixtures", dir, "-fixtures-mode", fixturesRecord)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rt := upstream.current.Load().(*swappedTransport).rt
+	ft, ok := rt.(*fixtureTransport)
+	if !ok {
+		t.Fatalf("requests are sent with %T, want fixtures", rt)
+	}
+	if _, ok := ft.base.(userAgentTransport); !ok {
+		t.Errorf("fixtures record through %T, want the transport setup built", ft.base)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/oipwg/verifier/verifier"
+	"github.com/oipwg/verifier/verifierpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer serves verifierpb.VerifierService with the same verifier as
+// the http api. Checks run on the caller's context, so its deadline bounds
+// the upstream requests.
+type grpcServer struct {
+	verifierpb.UnimplementedVerifierServiceServer
+}
+
+// ServeGRPC serves the gRPC api, with reflection, on its own listener.
+func ServeGRPC(listen string) error {
+	ln, err := Listen(listen)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer()
+	verifierpb.RegisterVerifierServiceServer(srv, grpcServer{})
+	reflection.Register(srv)
+	go func() {
+		slog.Info("Serving grpc api", "listen", ln.Addr().String())
+		err := srv.Serve(ln)
+		if err != nil {
+			slog.Error("Error serving grpc api", "err", err, "listen", listen)
+		}
+	}()
+	return nil
+}
+
+func (grpcServer) CheckClaim(ctx context.Context, req *verifierpb.CheckRequest) (*verifierpb.CheckResponse, error) {
+	if !txidRegex.MatchString(req.Txid) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid verification claim ID "+req.Txid)
+	}
+	if req.Refresh {
+		ctx = verifier.WithRefresh(ctx)
+	}
+	res, err := checkClaim(ctx, req.Txid)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return toProto(req.Txid, res), nil
+}
+
+func (grpcServer) BatchCheck(req *verifierpb.BatchCheckRequest, stream verifierpb.VerifierService_BatchCheckServer) error {
+	if len(req.Txids) > batchMaxIds {
+		return status.Error(codes.InvalidArgument, "At most "+strconv.Itoa(batchMaxIds)+" claim IDs may be checked at once")
+	}
+	ctx := stream.Context()
+	if req.Refresh {
+		ctx = verifier.WithRefresh(ctx)
+	}
+
+	results := make(chan *verifierpb.CheckResponse)
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers && i < len(req.Txids); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for txid := range jobs {
+				res, err := checkClaim(ctx, txid)
+				if err != nil {
+					continue
+				}
+				select {
+				case results <- toProto(txid, res):
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(jobs)
+		seen := make(map[string]bool, len(req.Txids))
+		for _, txid := range req.Txids {
+			if seen[txid] {
+				continue
+			}
+			seen[txid] = true
+			if !txidRegex.MatchString(txid) {
+				res := &verifierpb.CheckResponse{Txid: txid, Code: verifier.CodeInvalidId, Msg: "Invalid verification claim ID " + txid}
+				select {
+				case results <- res:
+				case <-ctx.Done():
+				}
+				continue
+			}
+			select {
+			case jobs <- txid:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var err error
+	for res := range results {
+		if err == nil {
+			err = stream.Send(res)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return nil
+}
+
+// toProto converts the check of claim txid to its gRPC message.
+func toProto(txid string, v *verifier.Result) *verifierpb.CheckResponse {
+	res := &verifierpb.CheckResponse{
+		Txid:             txid,
+		Code:             v.Code,
+		Verified:         v.Verified,
+		Policy:           v.Policy,
+		Msg:              v.Msg,
+		CrossPlatformMsg: v.CrossPlatformMsg,
+		RetryAfter:       int32(v.RetryAfter),
+	}
+	if v.Claim != nil {
+		res.Claim = &verifierpb.ClaimMeta{Txid: v.Claim.Txid, SignedBy: v.Claim.SignedBy, Time: v.Claim.Time}
+	}
+	if v.Publisher != nil {
+		res.Publisher = &verifierpb.PublisherMeta{
+			Txid:         v.Publisher.Txid,
+			Name:         v.Publisher.Name,
+			FloBip44Xpub: v.Publisher.FloBip44XPub,
+			SignedBy:     v.Publisher.SignedBy,
+			Time:         v.Publisher.Time,
+		}
+	}
+	if len(v.Platforms) > 0 {
+		res.Platforms = make(map[string]*verifierpb.PlatformStatus, len(v.Platforms))
+		for name, ps := range v.Platforms {
+			res.Platforms[name] = &verifierpb.PlatformStatus{
+				Verified:   ps.Verified,
+				Code:       ps.Code,
+				Msg:        ps.Msg,
+				Post:       ps.Post,
+				Author:     ps.Author,
+				Source:     ps.Source,
+				Details:    ps.Details,
+				Template:   ps.Template,
+				Name:       ps.Name,
+				Txid:       ps.Txid,
+				Note:       ps.Note,
+				RetryAfter: int32(ps.RetryAfter),
+			}
+		}
+	}
+	return res
+}
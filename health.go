@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/oipwg/verifier/verifier"
+)
+
+var (
+	healthInterval = 30 * time.Second
+	// healthTimeout bounds each upstream check, which doesn't end with the
+	// probe that started it since its result is shared.
+	healthTimeout = 10 * time.Second
+	ready         int32
+
+	// dependencies are the upstreams /health checks, and dropped those of
+	// platforms that aren't configured, both guarded by dependenciesMu.
+	dependencies = []*dependency{
+		{name: "oip", required: true, check: checkOipHealth},
+		{name: "twitter", required: true, check: checkTwitterHealth},
+		{name: "gab", required: false, check: checkGabHealth},
+	}
+	dropped        []*dependency
+	dependenciesMu sync.RWMutex
+)
+
+func init() {
+	rootRouter.HandleFunc("/health", handleHealth).Methods(http.MethodGet)
+	rootRouter.HandleFunc("/ready", handleReady).Methods(http.MethodGet)
+}
+
+type HealthResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+	// Reverify is the progress of claim re-verification, when enabled.
+	Reverify *ReverifyStatus `json:"reverify,omitempty"`
+	// Breakers are the upstream hosts whose circuit breaker isn't closed.
+	Breakers []verifier.BreakerStatus `json:"breakers,omitempty"`
+}
+
+type DependencyStatus struct {
+	Status    string    `json:"status"`
+	Required  bool      `json:"required"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// dependency is an upstream whose reachability is reported by /health. The
+// result of the last check is reused for healthInterval so that frequent
+// probes don't turn into upstream traffic.
+type dependency struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// dropDependency stops /health from checking the named upstream, for
+// platforms that aren't configured.
+func dropDependency(name string) {
+	dependenciesMu.Lock()
+	defer dependenciesMu.Unlock()
+	var kept []*dependency
+	for _, d := range dependencies {
+		if d.name == name {
+			dropped = append(dropped, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	dependencies = kept
+}
+
+// restoreDependency makes /health check the named upstream again after
+// dropDependency, for platforms that have since been configured. It returns
+// the dependency, or nil if there is none by that name.
+func restoreDependency(name string) *dependency {
+	dependenciesMu.Lock()
+	defer dependenciesMu.Unlock()
+	for _, d := range dependencies {
+		if d.name == name {
+			return d
+		}
+	}
+	for i, d := range dropped {
+		if d.name == name {
+			dropped = append(dropped[:i], dropped[i+1:]...)
+			dependencies = append(dependencies, d)
+			return d
+		}
+	}
+	return nil
+}
+
+// twitterCredentialsChanged makes /health check Twitter afresh with the
+// Twitter credentials of verify, or stop checking it when there are none.
+func twitterCredentialsChanged() {
+	if !verify.Configured("twitter") {
+		dropDependency("twitter")
+		return
+	}
+	d := restoreDependency("twitter")
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.checkedAt = time.Time{}
+	twitterProbe.at, twitterProbe.err = time.Time{}, nil
+	d.mu.Unlock()
+}
+
+func (d *dependency) status(ctx context.Context) DependencyStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Since(d.checkedAt) >= healthInterval {
+		// a probe that gives up mustn't fail the check for everyone else
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), healthTimeout)
+		d.err = d.check(ctx)
+		cancel()
+		d.checkedAt = time.Now()
+		if d.err != nil {
+			slog.Error("Health check failed", "dependency", d.name, "err", d.err)
+		}
+	}
+
+	ds := DependencyStatus{Status: "ok", Required: d.required, CheckedAt: d.checkedAt}
+	if d.err != nil {
+		ds.Status = "failing"
+		ds.Error = d.err.Error()
+	}
+	return ds
+}
+
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	dependenciesMu.RLock()
+	deps := dependencies
+	dependenciesMu.RUnlock()
+	hr := HealthResponse{Status: "ok", Dependencies: make(map[string]DependencyStatus, len(deps))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, d := range deps {
+		wg.Add(1)
+		go func(d *dependency) {
+			defer wg.Done()
+			ds := d.status(r.Context())
+			mu.Lock()
+			defer mu.Unlock()
+			hr.Dependencies[d.name] = ds
+			if ds.Status != "ok" {
+				if d.required {
+					hr.Status = "failing"
+				} else if hr.Status == "ok" {
+					hr.Status = "degraded"
+				}
+			}
+		}(d)
+	}
+	wg.Wait()
+	// requests to an upstream fail while its breaker is open, whatever the
+	// health check says
+	hr.Breakers = verify.OpenBreakers()
+	for _, b := range hr.Breakers {
+		if b.State != verifier.BreakerOpen {
+			continue
+		}
+		required := false
+		if ds, ok := hr.Dependencies[b.Target]; ok {
+			required = ds.Required
+			ds.Status = "failing"
+			ds.Error = "circuit breaker open for " + b.Host
+			hr.Dependencies[b.Target] = ds
+		}
+		if required {
+			hr.Status = "failing"
+		} else if hr.Status == "ok" {
+			hr.Status = "degraded"
+		}
+	}
+	if reverifyInterval > 0 {
+		hr.Reverify = reverify.status()
+	}
+
+	code := http.StatusOK
+	if hr.Status == "failing" {
+		code = http.StatusServiceUnavailable
+	}
+	RespondJSON(w, code, hr)
+}
+
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&ready) == 0 {
+		RespondJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	}
+	RespondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// httpPing reports an error if url can't be reached or responds with a status
+// of minFailStatus or above.
+func httpPing(ctx context.Context, url string, minFailStatus int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode >= minFailStatus {
+		return &verifier.StatusError{URL: url, StatusCode: res.StatusCode}
+	}
+	return nil
+}
+
+func checkOipHealth(ctx context.Context) error {
+	return httpPing(ctx, verify.OipURL("daemon", "version"), 300)
+}
+
+func checkGabHealth(ctx context.Context) error {
+	return httpPing(ctx, "https://gab.com/", 500)
+}
+
+func checkTwitterHealth(ctx context.Context) error {
+	client, _ := verify.TwitterCredentials()
+	if client == nil {
+		return checkTwitterV2Health(ctx)
+	}
+	_, _, err := client.RateLimits.Status(&twitter.RateLimitParams{Resources: []string{"statuses"}})
+	return err
+}
+
+// twitterProbeInterval is how often checkTwitterV2Health looks up a tweet
+// of its own when no check has looked one up since, as each lookup uses up
+// the bearer token's quota.
+var twitterProbeInterval = 15 * time.Minute
+
+// twitterProbe is the outcome of checkTwitterV2Health's latest lookup. It
+// is only used under the lock of the twitter dependency.
+var twitterProbe struct {
+	at  time.Time
+	err error
+}
+
+// checkTwitterV2Health reports the outcome of the latest tweet lookup of a
+// check, or when there was none in the last twitterProbeInterval, looks up a
+// long-lived tweet with the bearer token.
+func checkTwitterV2Health(ctx context.Context) error {
+	at, err := verify.LastTweetLookup()
+	if time.Since(at) < twitterProbeInterval {
+		return err
+	}
+	if time.Since(twitterProbe.at) < twitterProbeInterval {
+		return twitterProbe.err
+	}
+	err = probeTwitterV2(ctx)
+	twitterProbe.at, twitterProbe.err = time.Now(), err
+	return err
+}
+
+func probeTwitterV2(ctx context.Context) error {
+	url := "https://api.twitter.com/2/tweets/20"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	_, bearerToken := verify.TwitterCredentials()
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode >= 300 {
+		return &verifier.StatusError{URL: url, StatusCode: res.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+func TestTwitterV2Health(t *testing.T) {
+	ct := fakeUpstreams(t)
+	verify.Twitter, verify.TwitterBearerToken = nil, "token"
+	twitterProbe.at, twitterProbe.err = time.Time{}, nil
+	t.Cleanup(func() { twitterProbe.at, twitterProbe.err = time.Time{}, nil })
+	probe := "https://api.twitter.com/2/tweets/20"
+	ctx := context.Background()
+
+	// without a lookup to go by, it looks up a tweet of its own, but only
+	// once every twitterProbeInterval
+	for i := 0; i < 3; i++ {
+		if err := checkTwitterV2Health(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ct.count(probe); n != 1 {
+		t.Fatalf("got %d probes, want 1", n)
+	}
+
+	_, _, _, err := verify.VerifyTwitter(ctx, "1724567800000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	twitterProbe.at = time.Time{}
+	if err := checkTwitterV2Health(ctx); err != nil {
+		t.Errorf("after a lookup: %v", err)
+	}
+	if n := ct.count(probe); n != 1 {
+		t.Errorf("got %d probes after a lookup, want 1", n)
+	}
+
+	// a revoked token fails the lookups of checks, and so the health check
+	ct.base = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody, Header: make(http.Header), Request: req}, nil
+	})
+	_, _, _, err = verify.VerifyTwitter(ctx, "1724567800000000002")
+	if err == nil {
+		t.Fatal("lookup with a revoked token succeeded")
+	}
+	if err := checkTwitterV2Health(ctx); err == nil {
+		t.Error("got no error after a lookup was refused")
+	}
+	if n := ct.count(probe); n != 1 {
+		t.Errorf("got %d probes after a failed lookup, want 1", n)
+	}
+}
+
+func TestTwitterCredentialsRotated(t *testing.T) {
+	dependenciesMu.Lock()
+	oldDeps, oldDropped := append([]*dependency(nil), dependencies...), append([]*dependency(nil), dropped...)
+	dependenciesMu.Unlock()
+	oldSecret, _ := webhookSecret.Load().(string)
+	t.Cleanup(func() {
+		dependencies, dropped = oldDeps, oldDropped
+		webhookSecret.Store(oldSecret)
+		twitterProbe.at, twitterProbe.err = time.Time{}, nil
+		for _, d := range dependencies {
+			d.checkedAt = time.Time{}
+		}
+	})
+
+	flags, o, err := setupFlagSet(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		serveFixture(rec, req)
+		return rec.Result(), nil
+	})
+	// as runServe does without credentials
+	dropDependency("twitter")
+
+	check := func() string {
+		rec := get("/verified/v1/publisher/check/" + verifiedClaim)
+		var res verifier.Result
+		err := json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil || res.Platforms["twitter"] == nil {
+			t.Fatalf("got %d %s", rec.Code, rec.Body)
+		}
+		return res.Platforms["twitter"].Code
+	}
+	health := func() map[string]DependencyStatus {
+		rec := httptest.NewRecorder()
+		handleHealth(rec, httptest.NewRequest(http.MethodGet, "/verified/health", nil))
+		var hr HealthResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &hr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hr.Dependencies
+	}
+	if code := check(); code != verifier.CodeNotConfigured {
+		t.Fatalf("without credentials: got %s", code)
+	}
+	if _, ok := health()["twitter"]; ok {
+		t.Error("health checks twitter without credentials")
+	}
+
+	path := filepath.Join(t.TempDir(), "bearer-token")
+	err = os.WriteFile(path, []byte("token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = flags.Set("bearer-token-file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloadSecrets(flags, o)
+
+	if code := check(); code != verifier.CodeOK {
+		t.Errorf("after the credentials rotated in: got %s, want %s", code, verifier.CodeOK)
+	}
+	if ds, ok := health()["twitter"]; !ok || ds.Status != "ok" {
+		t.Errorf("after the credentials rotated in: got twitter health %+v, %v", ds, ok)
+	}
+
+	// new credentials don't inherit the health of the old ones
+	fail := &verifier.StatusError{URL: "twitter tweet lookup", StatusCode: http.StatusUnauthorized}
+	twitterProbe.at, twitterProbe.err = time.Now(), fail
+	verify.SetTwitter(nil, "token2")
+	if at, _ := verify.LastTweetLookup(); !at.IsZero() {
+		t.Errorf("SetTwitter kept the last tweet lookup of %v", at)
+	}
+	twitterCredentialsChanged()
+	if ds := health()["twitter"]; ds.Status != "ok" {
+		t.Errorf("after rotating again: got twitter health %+v", ds)
+	}
+}
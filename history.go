@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/store"
+	"github.com/oipwg/verifier/verifier"
+)
+
+var (
+	// history, when set, records every claim check.
+	history      *store.Store
+	historyQueue = make(chan historyEntry, 256)
+
+	historyMaxLimit = 100
+)
+
+type historyEntry struct {
+	txid   string
+	time   time.Time
+	status *verifier.Result
+}
+
+// recordCheck queues the check of claim txid to be written to history, so
+// that a slow database never holds up responses. Checks are dropped, with a
+// log, when the queue is full.
+func recordCheck(txid string, status *verifier.Result) {
+	if history == nil {
+		return
+	}
+	select {
+	case historyQueue <- historyEntry{txid, time.Now(), status.Clone()}:
+	default:
+		slog.Error("History queue is full, dropping check", "txid", txid)
+	}
+}
+
+// writeHistory writes queued checks to history until the queue is closed.
+func writeHistory() {
+	for e := range historyQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		transition, err := history.Record(ctx, e.txid, e.time, e.status)
+		cancel()
+		if err != nil {
+			slog.Error("Unable to record check", "txid", e.txid, "err", err)
+		}
+		if transition != nil {
+			notifyTransition(e.txid, e.status, transition)
+		}
+	}
+}
+
+type historyResponse struct {
+	Checks []*store.Check `json:"checks"`
+	// Next is the before parameter for the next page, if there is one.
+	Next int64 `json:"next,omitempty"`
+}
+
+// handleHistory responds with the recorded checks of a claim, newest first,
+// a page of ?limit= at a time starting ?before= a check id.
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 20
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > historyMaxLimit {
+			RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "limit must be a number from 1 to " + strconv.Itoa(historyMaxLimit)})
+			return
+		}
+		limit = n
+	}
+	var before int64
+	if s := q.Get("before"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 1 {
+			RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "before must be a check id"})
+			return
+		}
+		before = n
+	}
+
+	checks, err := history.History(r.Context(), mux.Vars(r)["id"], limit, before)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		slog.Error("Unable to read history", "err", err)
+		RespondJSON(w, http.StatusInternalServerError, ErrorResponse{Code: verifier.CodeUpstreamError, Msg: "Unable to read check history"})
+		return
+	}
+	res := historyResponse{Checks: checks}
+	if res.Checks == nil {
+		res.Checks = []*store.Check{}
+	}
+	if len(checks) == limit {
+		res.Next = checks[len(checks)-1].ID
+	}
+	RespondJSON(w, http.StatusOK, res)
+}
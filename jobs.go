@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/verifier"
+)
+
+func init() {
+	protect(rootRouter, "/publisher/check/{id:[a-f0-9]{64}}/async", limitBody(handleAsyncCheck)).Methods(http.MethodPost)
+	protect(rootRouter, "/jobs/{job:[a-f0-9]{32}}", handleJob).Methods(http.MethodGet).Name("job")
+}
+
+var (
+	jobWorkers = 4
+	// jobTTL is how long a finished job's result is kept.
+	jobTTL   = 10 * time.Minute
+	jobQueue = make(chan *job, 256)
+
+	jobsMu sync.Mutex
+	jobs   = make(map[string]*job)
+	// claimJobs are the unfinished jobs by claim txid, with refresh requests
+	// kept apart, so that repeat requests join the queued job.
+	claimJobs = make(map[string]*job)
+)
+
+// Job statuses.
+const (
+	jobPending = "pending"
+	jobRunning = "running"
+	jobDone    = "done"
+)
+
+// A job is an asynchronous check of a claim.
+type job struct {
+	ID      string    `json:"id"`
+	Claim   string    `json:"claim"`
+	Status  string    `json:"status"`
+	Created time.Time `json:"created"`
+	// Result is the check, in the schema of the api version that was
+	// polled.
+	Result interface{} `json:"result,omitempty"`
+
+	result   *verifier.Result
+	refresh  bool
+	key      string
+	finished time.Time
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// handleAsyncCheck queues a check of the claim, or joins the one already
+// queued, and responds 202 with the job to poll.
+func handleAsyncCheck(w http.ResponseWriter, r *http.Request) {
+	txid := mux.Vars(r)["id"]
+	refresh := r.URL.Query().Get("refresh") == "true"
+	key := txid
+	if refresh {
+		key = "refresh:" + txid
+	}
+
+	jobsMu.Lock()
+	j, ok := claimJobs[key]
+	if !ok {
+		j = &job{ID: newJobID(), Claim: txid, Status: jobPending, Created: time.Now(), refresh: refresh, key: key}
+		select {
+		case jobQueue <- j:
+			jobs[j.ID] = j
+			claimJobs[key] = j
+		default:
+			jobsMu.Unlock()
+			RespondJSON(w, http.StatusServiceUnavailable, ErrorResponse{Code: verifier.CodeBusy, Msg: "Too many checks are queued, try again later"})
+			return
+		}
+	}
+	res := *j
+	jobsMu.Unlock()
+
+	route := "job"
+	if isV1(r) {
+		route = "v1-job"
+	}
+	u, err := router.Get(route).URL("job", res.ID)
+	if err == nil {
+		w.Header().Set("Location", u.String())
+	}
+	RespondJSON(w, http.StatusAccepted, &res)
+}
+
+// handleJob responds with the status of a job, and its result once done.
+func handleJob(w http.ResponseWriter, r *http.Request) {
+	jobsMu.Lock()
+	j, ok := jobs[mux.Vars(r)["job"]]
+	var res job
+	if ok {
+		res = *j
+	}
+	jobsMu.Unlock()
+
+	if !ok {
+		RespondJSON(w, http.StatusNotFound, ErrorResponse{Code: verifier.CodeNotFound, Msg: "No such job, or it has expired"})
+		return
+	}
+	if res.result != nil {
+		res.Result = versioned(w, r, res.result)
+	}
+	RespondJSON(w, http.StatusOK, &res)
+}
+
+// runJobs starts jobWorkers workers on the queued jobs and expires finished
+// ones, until ctx is done.
+func runJobs(ctx context.Context) {
+	for i := 0; i < jobWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-jobQueue:
+					runJob(ctx, j)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expireJobs()
+		}
+	}
+}
+
+func runJob(ctx context.Context, j *job) {
+	jobsMu.Lock()
+	j.Status = jobRunning
+	jobsMu.Unlock()
+
+	if j.refresh {
+		ctx = verifier.WithRefresh(ctx)
+	}
+	status, err := sharedCheckClaim(ctx, j.Claim)
+	if err != nil {
+		// shutting down
+		slog.Error("Unable to run check job", "job", j.ID, "txid", j.Claim, "err", err)
+		return
+	}
+
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	j.Status, j.result, j.finished = jobDone, status, time.Now()
+	delete(claimJobs, j.key)
+}
+
+// expireJobs forgets jobs that finished more than jobTTL ago.
+func expireJobs() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for id, j := range jobs {
+		if j.Status == jobDone && time.Since(j.finished) > jobTTL {
+			delete(jobs, id)
+		}
+	}
+}
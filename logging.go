@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+// logLevel is the level of the default logger, which reloads change.
+var logLevel = new(slog.LevelVar)
+
+// parseLevel parses debug, info, warn, or error.
+func parseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q (expected debug, info, warn, or error)", s)
+}
+
+// setupLogging makes the default logger write to w at level, as text or
+// json.
+func setupLogging(w io.Writer, level, format string) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (expected text or json)", format)
+	}
+	logLevel.Set(l)
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// requestLog returns the logger of r, which carries its request id.
+func requestLog(r *http.Request) *slog.Logger {
+	return verifier.Logger(r.Context())
+}
@@ -5,38 +5,61 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
-	"io/ioutil"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"regexp"
+	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/azer/logger"
 	"github.com/coreos/pkg/flagutil"
 	"github.com/dghubble/go-twitter/twitter"
 	"github.com/dghubble/oauth1"
 	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/store"
+	"github.com/oipwg/verifier/verifier"
 	"github.com/rs/cors"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 )
 
-var rootRouter = mux.NewRouter().PathPrefix("/verified").Subrouter()
-
-var log = logger.New("verify")
+var (
+	router     = mux.NewRouter()
+	rootRouter = router.PathPrefix("/verified").Subrouter()
+)
 
 func init() {
+	router.NotFoundHandler = http.HandlerFunc(handle404)
 	rootRouter.NotFoundHandler = http.HandlerFunc(handle404)
-	rootRouter.HandleFunc("/publisher/check/{id:[a-f0-9]{64}}", handleCheck)
+	rootRouter.HandleFunc("/publisher/check/{id:[a-f0-9]{64}}", handleCheck).Methods(http.MethodGet, http.MethodHead)
+	rootRouter.HandleFunc("/publisher/check", limitBody(handleBatchCheck)).Methods(http.MethodPost)
+	rootRouter.HandleFunc("/publisher/by-publisher/{id:[a-f0-9]{64}}", handleByPublisher).Methods(http.MethodGet)
+	rootRouter.HandleFunc("/publisher/by-name/{name}", handleByName).Methods(http.MethodGet)
+	rootRouter.HandleFunc("/adhoc", handleAdhoc).Methods(http.MethodGet)
+	rootRouter.HandleFunc("/publisher/template/{id:[a-f0-9]{64}}", handleTemplate).Methods(http.MethodGet)
+	rootRouter.HandleFunc("/platforms", handlePlatforms).Methods(http.MethodGet)
 }
 
 func RespondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	b, err := json.Marshal(payload)
 	if err != nil {
-		log.Error("Unable to marshal response payload", logger.Attrs{"err": err, "payload": payload})
+		slog.Error("Unable to marshal response payload", "err", err, "payload", payload)
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(500)
 		n, err := w.Write([]byte("Internal server error"))
 		if err != nil {
-			log.Error("Unable to write json response", logger.Attrs{"n": n, "err": err, "payload": payload, "code": code})
+			slog.Error("Unable to write json response", "n", n, "err", err, "payload", payload, "code", code)
 		}
 		return
 	}
@@ -45,278 +68,1299 @@ func RespondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	n, err := w.Write(b)
 	if err != nil {
-		log.Error("Unable to write json response", logger.Attrs{"n": n, "err": err, "payload": payload, "code": code})
+		slog.Error("Unable to write json response", "n", n, "err", err, "payload", payload, "code", code)
+	}
+}
+
+// RespondText writes text as a text/plain response.
+func RespondText(w http.ResponseWriter, code int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	n, err := w.Write([]byte(text + "\n"))
+	if err != nil {
+		slog.Error("Unable to write text response", "n", n, "err", err, "code", code)
 	}
 }
 
+// handleCheck responds with the check of a claim as JSON or, if the client
+// prefers it, a text/plain summary. With format=short it responds just
+// true or false, with a 404 for false so that scripts can rely on curl
+// --fail. Successful responses carry an ETag, and are never 304 with
+// refresh=true.
 func handleCheck(w http.ResponseWriter, r *http.Request) {
 	var opts = mux.Vars(r)
+	ctx := r.Context()
+	refresh := r.URL.Query().Get("refresh") == "true"
+	if refresh {
+		ctx = verifier.WithRefresh(ctx)
+	}
+	short := r.URL.Query().Get("format") == "short"
+	contentType, ok := negotiate(r, "application/json", "text/plain")
+	w.Header().Add("Vary", "Accept")
+	if !ok && !short {
+		RespondJSON(w, http.StatusNotAcceptable, ErrorResponse{Code: verifier.CodeNotAcceptable, Msg: "Check results are available as application/json or text/plain"})
+		return
+	}
 
-	var nameTwitter, txidTwitter string
+	status, err := sharedCheckClaim(ctx, opts["id"])
+	if err != nil {
+		// the client went away before the check finished
+		return
+	}
+	if status.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+	}
+	code := httpStatus(status)
+	if short && code == http.StatusOK && !status.Verified {
+		code = http.StatusNotFound
+	}
+	if code != http.StatusOK {
+		switch {
+		case short:
+			RespondText(w, code, strconv.FormatBool(status.Verified))
+		case contentType == "text/plain":
+			RespondText(w, code, summary(status))
+		default:
+			RespondJSON(w, code, versioned(w, r, status))
+		}
+		return
+	}
 
-	status := VerificationResponse{}
+	var body []byte
+	switch {
+	case short:
+		contentType, body = "text/plain; charset=utf-8", []byte(strconv.FormatBool(status.Verified)+"\n")
+	case contentType == "text/plain":
+		contentType, body = "text/plain; charset=utf-8", []byte(summary(status)+"\n")
+	default:
+		payload := versioned(w, r, status)
+		body, err = json.Marshal(payload)
+		if err != nil {
+			RespondJSON(w, code, payload)
+			return
+		}
+	}
+	if refresh {
+		r.Header.Del("If-None-Match")
+	}
+	RespondCacheable(w, r, verify.CurrentSettings().CacheTTL, contentType, body)
+}
+
+// handleByPublisher checks the latest verification claim of a publisher
+// record, for clients that don't know the claim's txid.
+func handleByPublisher(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.URL.Query().Get("refresh") == "true" {
+		ctx = verifier.WithRefresh(ctx)
+	}
 
-	vc, err := getVerificationClaim(opts["id"])
+	status, err := verify.CheckPublisherClaim(ctx, mux.Vars(r)["id"])
 	if err != nil {
-		status.Msg = "Unable to locate verification claim with ID " + opts["id"]
-		RespondJSON(w, 200, status)
+		// the client went away before the check finished
 		return
 	}
+	if status.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+	}
+	RespondJSON(w, httpStatus(status), versioned(w, r, status))
+}
 
-	if len(vc.TwitterId) == 0 {
-		status.TwitterMsg = "No tweet ID provided"
-	} else {
-		nameTwitter, txidTwitter, err = getTwitter(client, vc.TwitterId)
-		if err != nil {
-			if err == ErrBadFormat {
-				status.TwitterMsg = "Tweet contents not properly formatted"
-			} else {
-				status.TwitterMsg = "Unable to locate tweet with ID " + vc.TwitterId
-			}
+// handleByName checks every publisher with a name, which there can be
+// several of when someone is impersonating a publisher. With fuzzy=1 names
+// are matched by the OIP api's search analyzer instead of exactly.
+func handleByName(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.URL.Query().Get("refresh") == "true" {
+		ctx = verifier.WithRefresh(ctx)
+	}
+	fuzzy := r.URL.Query().Get("fuzzy") == "1"
+
+	checks, err := verify.CheckPublisherName(ctx, mux.Vars(r)["name"], fuzzy)
+	if err != nil {
+		if ctx.Err() != nil {
+			// the client went away before the checks finished
+			return
 		}
+		msg, _ := verifier.UpstreamMsg(err)
+		RespondJSON(w, http.StatusBadGateway, ErrorResponse{Code: verifier.CodeUpstreamError, Msg: "Unable to search for publishers: " + msg})
+		return
+	}
+	if isV1(r) {
+		if checks == nil {
+			checks = []*verifier.PublisherCheck{}
+		}
+		RespondJSON(w, http.StatusOK, checks)
+		return
+	}
+	res := make([]legacyPublisherCheck, len(checks))
+	for i, c := range checks {
+		res[i] = legacyPublisherCheck{c.Publisher, versioned(w, r, c.Result)}
+	}
+	RespondJSON(w, http.StatusOK, res)
+}
 
-		pubTwitter, err := getPublisher(txidTwitter)
-		if err != nil {
-			status.TwitterMsg = "Unable to locate publisher with ID" + txidTwitter
-		} else {
-			if pubTwitter.Name != nameTwitter {
-				status.TwitterMsg = "Claimed name doesn't match publisher name"
-			}
+// handleAdhoc checks posts against a publisher record before there is a
+// claim record for them: /adhoc?publisher=<txid>&tweet=<url or id>, with
+// any platform's name as a parameter for its post.
+func handleAdhoc(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ctx := r.Context()
+	if q.Get("refresh") == "true" {
+		ctx = verifier.WithRefresh(ctx)
+	}
+
+	publisher := q.Get("publisher")
+	if !txidRegex.MatchString(publisher) {
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeInvalidId, Msg: "publisher must be a publisher record txid"})
+		return
+	}
+	ids := make(map[string]string)
+	for _, name := range verify.Platforms() {
+		if id := q.Get(name); id != "" {
+			ids[name] = id
 		}
 	}
+	if id := q.Get("tweet"); id != "" {
+		ids["twitter"] = id
+	}
+	if len(ids) == 0 {
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "At least one post to check is required, e.g. tweet=<url or id>"})
+		return
+	}
 
-	if len(vc.GabId) == 0 {
-		status.GabMsg = "No post ID provided"
-	} else {
-		nameGab, txidGab, err := getGab(vc.GabId)
-		if err != nil {
-			if err == ErrBadFormat {
-				status.GabMsg = "Post contents not properly formatted"
-			} else {
-				status.GabMsg = "Unable to locate post with ID " + vc.GabId
-			}
+	status, err := verify.CheckPosts(ctx, publisher, ids)
+	if err != nil {
+		// the client went away before the check finished
+		return
+	}
+	if status.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfter))
+	}
+	RespondJSON(w, httpStatus(status), versioned(w, r, status))
+}
+
+// legacyPublisherCheck is verifier.PublisherCheck with the unversioned
+// result schema.
+type legacyPublisherCheck struct {
+	Publisher verifier.PublisherMeta `json:"publisher"`
+	Result    interface{}            `json:"result"`
+}
+
+type templateResponse struct {
+	*verifier.Statement
+	TwitterIntentURL string `json:"twitter_intent_url"`
+}
+
+// handleTemplate responds with the exact statement a publisher should post
+// to verify, and a link that opens a tweet of it.
+func handleTemplate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s, err := verify.Statement(r.Context(), id)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		if msg, ok := verifier.UpstreamMsg(err); ok {
+			RespondJSON(w, http.StatusBadGateway, ErrorResponse{Code: verifier.CodeUpstreamError, Msg: "Unable to fetch publisher with ID " + id + ": " + msg})
+		} else if err == verifier.ErrNoTemplateFormat {
+			RespondJSON(w, http.StatusNotImplemented, ErrorResponse{Code: verifier.CodeNotConfigured, Msg: "None of the configured verification templates has a format"})
+		} else {
+			RespondJSON(w, http.StatusNotFound, ErrorResponse{Code: verifier.CodePublisherNotFound, Msg: "Unable to locate publisher with ID " + id})
 		}
+		return
+	}
+	intent := "https://twitter.com/intent/tweet?text=" + strings.ReplaceAll(url.QueryEscape(s.Text), "+", "%20")
+	RespondJSON(w, http.StatusOK, templateResponse{s, intent})
+}
 
-		if nameGab != nameTwitter || txidGab != txidTwitter {
-			pubGab, err := getPublisher(txidGab)
-			if err != nil {
-				status.GabMsg = "Unable to locate publisher with ID " + txidGab
-			} else {
-				if pubGab.Name != nameTwitter {
-					status.GabMsg = "Claimed name doesn't match publisher name"
-				}
+// summary describes a check in one line: "verified", or "unverified: "
+// followed by why.
+func summary(status *verifier.Result) string {
+	if status.Verified {
+		return "verified"
+	}
+	var reasons []string
+	switch {
+	case status.Msg != "":
+		reasons = append(reasons, status.Msg)
+	case status.CrossPlatformMsg != "":
+		reasons = append(reasons, status.CrossPlatformMsg)
+	default:
+		for _, name := range verify.Platforms() {
+			if ps, ok := status.Platforms[name]; ok && !ps.Verified {
+				reasons = append(reasons, name+": "+ps.Msg)
 			}
 		}
 	}
+	return "unverified: " + strings.Join(reasons, "; ")
+}
 
-	if len(status.TwitterMsg) == 0 {
-		status.Twitter = true
+// handleDebug checks a claim like handleCheck, always going to the network,
+// and includes the fetched text and what it was compared with for each
+// platform. It is only routed with -enable-debug, since it exposes upstream
+// content.
+func handleDebug(w http.ResponseWriter, r *http.Request) {
+	status, err := verify.CheckClaim(verifier.WithDebug(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		// the client went away before the check finished
+		return
 	}
+	RespondJSON(w, httpStatus(status), versioned(w, r, status))
+}
 
-	if len(status.GabMsg) == 0 {
-		status.Gab = true
+type PlatformsResponse struct {
+	// Enabled are the platforms claims are verified on, in check order.
+	Enabled []string `json:"enabled"`
+	// Disabled are the platforms this server lacks credentials for; their
+	// posts are reported NOT_CONFIGURED.
+	Disabled []string `json:"disabled"`
+}
+
+// platforms splits the verifier's platforms into the configured ones and the
+// rest.
+func platforms() (enabled, disabled []string) {
+	enabled, disabled = []string{}, []string{}
+	for _, name := range verify.Platforms() {
+		if verify.Configured(name) {
+			enabled = append(enabled, name)
+		} else {
+			disabled = append(disabled, name)
+		}
 	}
+	return enabled, disabled
+}
 
-	RespondJSON(w, 200, status)
+func handlePlatforms(w http.ResponseWriter, r *http.Request) {
+	enabled, disabled := platforms()
+	RespondJSON(w, http.StatusOK, PlatformsResponse{Enabled: enabled, Disabled: disabled})
 }
 
-func Serve() {
-	err := http.ListenAndServe(":1607", cors.Default().Handler(rootRouter))
+var (
+	batchMaxIds  = 50
+	batchWorkers = 8
+	txidRegex    = regexp.MustCompile(`^[a-f0-9]{64}$`)
+)
+
+func handleBatchCheck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.URL.Query().Get("refresh") == "true" {
+		ctx = verifier.WithRefresh(ctx)
+	}
+
+	var ids []string
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	if tooLarge(err) {
+		respondTooLarge(w)
+		return
+	}
 	if err != nil {
-		log.Error("Error serving http api", logger.Attrs{"err": err, "listen": ":1607"})
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "Request body must be a JSON array of claim IDs"})
+		return
+	}
+	if len(ids) > batchMaxIds {
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeBadRequest, Msg: "At most " + strconv.Itoa(batchMaxIds) + " claim IDs may be checked at once"})
+		return
+	}
+
+	results := make(map[string]*verifier.Result, len(ids))
+	var pending []string
+	for _, id := range ids {
+		if _, ok := results[id]; ok {
+			continue
+		}
+		if !txidRegex.MatchString(id) {
+			results[id] = &verifier.Result{Code: verifier.CodeInvalidId, Msg: "Invalid verification claim ID " + id}
+			continue
+		}
+		results[id] = nil
+		pending = append(pending, id)
 	}
+
+	var mu sync.Mutex
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers && i < len(pending); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				status, err := sharedCheckClaim(ctx, id)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				results[id] = status
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range pending {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// the client went away before the checks finished
+		return
+	}
+	payload := make(map[string]interface{}, len(results))
+	for id, status := range results {
+		payload[id] = versioned(w, r, status)
+	}
+	RespondJSON(w, http.StatusOK, payload)
 }
 
-func handle404(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusNotFound)
-	_, _ = w.Write([]byte("404 not found"))
-	log.Info("404", logger.Attrs{
-		"url":           r.URL,
-		"httpMethod":    r.Method,
-		"remoteAddr":    r.RemoteAddr,
-		"contentLength": r.ContentLength,
-		"userAgent":     r.UserAgent(),
+var checkGroup singleflight.Group
+
+// sharedCheckClaim runs the check of claim txid, sharing a single in-flight
+// check between all concurrent callers. Each caller gets its own copy of the
+// response. The shared check is detached from ctx's cancellation so that one
+// caller giving up doesn't fail the others.
+func sharedCheckClaim(ctx context.Context, txid string) (*verifier.Result, error) {
+	key := txid
+	if verifier.RefreshRequested(ctx) {
+		key = "refresh:" + txid
+	}
+
+	ch := checkGroup.DoChan(key, func() (interface{}, error) {
+		// detached from ctx, but still cancelled when the server gives up
+		// draining connections on shutdown
+		checkCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(serverCtx, cancel)
+		defer stop()
+		return checkClaim(checkCtx, txid)
 	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*verifier.Result).Clone(), nil
+	}
+}
+
+// checkClaim checks claim txid, recording the check in history and sending
+// it to streams.
+func checkClaim(ctx context.Context, txid string) (*verifier.Result, error) {
+	checksInFlight.Inc()
+	defer checksInFlight.Dec()
+	status, err := verify.CheckClaim(ctx, txid)
+	if err == nil {
+		recordCheck(txid, status)
+		publishCheck(txid, status)
+	}
+	return status, err
 }
 
 var (
-	client *twitter.Client
+	drainTimeout = 15 * time.Second
+
+	// serverCtx is the base context of every http api request. It is cancelled
+	// when draining connections on shutdown times out.
+	serverCtx, cancelServer = context.WithCancel(context.Background())
+
+	errDrainTimeout = errors.New("timed out draining connections")
 )
 
-func main() {
-	flags := flag.NewFlagSet("user-auth", flag.ContinueOnError)
-	consumerKey := flags.String("consumer-key", "", "Twitter Consumer Key")
-	consumerSecret := flags.String("consumer-secret", "", "Twitter Consumer Secret")
-	accessToken := flags.String("access-token", "", "Twitter Access Token")
-	accessSecret := flags.String("access-secret", "", "Twitter Access Secret")
-	err := flags.Parse(os.Args[1:])
+// Serve serves the http api on listen until SIGINT or SIGTERM, then stops
+// accepting connections and waits up to drainTimeout for in-flight requests
+// to finish before cancelling them.
+func Serve(listen string) error {
+	ln, err := Listen(listen)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	err = flagutil.SetFlagsFromEnv(flags, "TWITTER")
+
+	srv := &http.Server{
+		Handler:     traceRequests(withCORS(withRequestID(logAccess(compress(recoverPanics(router)))))),
+		BaseContext: func(net.Listener) context.Context { return serverCtx },
+	}
+	srv.RegisterOnShutdown(closeStreams)
+	useTLS, err := configureTLS(srv)
 	if err != nil {
-		panic(err)
+		ln.Close()
+		return err
 	}
 
-	if *consumerKey == "" || *consumerSecret == "" || *accessToken == "" || *accessSecret == "" {
-		panic("Consumer key/secret and Access token/secret required")
-	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
 
-	config := oauth1.NewConfig(*consumerKey, *consumerSecret)
-	token := oauth1.NewToken(*accessToken, *accessSecret)
-	httpClient := config.Client(context.Background(), token)
+	serveErr := make(chan error, 1)
+	go func() {
+		if useTLS {
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		serveErr <- srv.Serve(ln)
+	}()
+	slog.Info("Serving http api", "listen", ln.Addr().String(), "tls", useTLS)
+	atomic.StoreInt32(&ready, 1)
 
-	client = twitter.NewClient(httpClient)
+	select {
+	case err = <-serveErr:
+		slog.Error("Error serving http api", "err", err, "listen", listen)
+		return err
+	case s := <-sig:
+		slog.Info("Shutting down, draining connections", "signal", s.String(), "timeout", drainTimeout.String())
+	}
+	atomic.StoreInt32(&ready, 0)
 
-	Serve()
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+	if err != nil {
+		slog.Error("Timed out draining connections, cancelling in-flight requests", "err", err)
+		cancelServer()
+		_ = srv.Close()
+		return errDrainTimeout
+	}
+	slog.Info("Shutdown complete")
+	return nil
 }
 
-func getVerificationClaim(txid string) (*VerificationClaim, error) {
-	body, err := httpGet("https://api.oip.io/oip/o5/record/get/" + txid)
+// Listen validates a host:port listen address and binds to it, so that a bad
+// address or a port that is already in use is reported before serving starts.
+// IPv6 hosts must be bracketed, e.g. [::1]:1607.
+func Listen(listen string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(listen)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid listen address %q: %v", listen, err)
 	}
-
-	results := &oipApiResult{}
-	err = json.Unmarshal(body, results)
+	p, err := strconv.ParseUint(port, 10, 16)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid listen address %q: port must be a number between 0 and 65535", listen)
 	}
 
-	if len(results.Results) == 1 {
-		return &results.Results[0].Record.Details.VerificationClaim, nil
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.FormatUint(p, 10)))
+	if err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return nil, fmt.Errorf("unable to listen on %s: address already in use", listen)
+		}
+		return nil, fmt.Errorf("unable to listen on %s: %v", listen, err)
 	}
+	return ln, nil
+}
 
-	return nil, errors.New("unable to find verification claim by txid")
+func handle404(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, http.StatusNotFound, ErrorResponse{Code: verifier.CodeNotFound, Msg: "404 not found"})
+	requestLog(r).Info("404",
+		"url", r.URL.String(),
+		"httpMethod", r.Method,
+		"remoteAddr", r.RemoteAddr,
+		"contentLength", r.ContentLength,
+		"userAgent", r.UserAgent(),
+	)
 }
 
-func getTwitter(client *twitter.Client, id string) (name string, txid string, err error) {
-	intId, err := strconv.ParseInt(id, 10, 64)
-	if err != nil {
-		return "", "", err
+var (
+	// upstream is the transport of httpClient, which setup replaces with
+	// one that refuses to dial private addresses for hosts taken from
+	// claims, whose connection pools are sized for the upstream concurrency
+	// limits and whose proxy is -http-proxy, if set.
+	upstream   = newSwapTransport()
+	httpClient = &http.Client{Timeout: 10 * time.Second, Transport: upstream}
+	verify     *verifier.Verifier
+)
+
+// version is the version of the verifier, set at build time with
+// -ldflags "-X main.version=v1.2.3". Without it, the version of the module
+// the binary was built from is used.
+var version string
+
+func buildVersion() string {
+	if version != "" {
+		return version
 	}
-	tweet, _, err := client.Statuses.Show(intId, nil)
-	if err != nil {
-		return "", "", err
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
 	}
-	tweetTokens := verificationRegex.FindStringSubmatch(tweet.Text)
-	if len(tweetTokens) != 3 {
-		return "", "", ErrBadFormat
+	return "dev"
+}
+
+// userAgentTransport sends agent on requests without a User-Agent, e.g.
+// those of the Twitter client and the health checks.
+type userAgentTransport struct {
+	base  http.RoundTripper
+	agent string
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" && t.agent != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.agent)
 	}
-	return tweetTokens[1], tweetTokens[2], nil
+	return t.base.RoundTrip(req)
 }
 
-func httpGet(url string) ([]byte, error) {
-	res, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// swapTransport sends each request with the transport last swapped in, so
+// that setup replaces the transport of httpClient whole instead of changing
+// one that requests are under way on.
+type swapTransport struct {
+	current atomic.Value // *swappedTransport
+}
+
+type swappedTransport struct {
+	// base holds the connection pools, rt sends requests over it.
+	base *http.Transport
+	rt   http.RoundTripper
+}
+
+func newSwapTransport() *swapTransport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	s := &swapTransport{}
+	s.swap(t, userAgentTransport{t, verifier.DefaultUserAgent})
+	return s
+}
+
+func (s *swapTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return s.current.Load().(*swappedTransport).rt.RoundTrip(req)
+}
+
+// base returns the *http.Transport requests are sent over.
+func (s *swapTransport) base() *http.Transport {
+	return s.current.Load().(*swappedTransport).base
+}
+
+// swap sends the requests from now on with rt, which sends them over base,
+// and closes the idle connections of the transport it replaces. Requests
+// already under way finish on theirs.
+func (s *swapTransport) swap(base *http.Transport, rt http.RoundTripper) {
+	old, _ := s.current.Swap(&swappedTransport{base, rt}).(*swappedTransport)
+	if old != nil {
+		old.base.CloseIdleConnections()
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
+}
+
+func main() {
+	args := os.Args[1:]
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "serve":
+		runServe(args)
+	case "check":
+		os.Exit(runCheck(args))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q (expected serve or check)\n", cmd)
+		os.Exit(2)
 	}
-	return body, nil
 }
 
-func getGab(postId string) (name string, txid string, err error) {
-	body, err := httpGet("https://gab.com/posts/" + postId)
-	if err != nil {
-		return "", "", err
+// options are the flags shared by every subcommand.
+type options struct {
+	consumerKey        *string
+	consumerSecret     *string
+	accessToken        *string
+	accessSecret       *string
+	bearerToken        *string
+	oipApi             *string
+	oipTimeout         *time.Duration
+	oipAttempts        *int
+	rateLimitWait      *time.Duration
+	rateLimit          *int
+	rateWindow         *time.Duration
+	rateBurst          *int
+	oipRetryDelay      *time.Duration
+	blueskyAppView     *string
+	nostrRelays        *string
+	nostrTimeout       *time.Duration
+	githubToken        *string
+	dnsResolver        *string
+	dnsTimeout         *time.Duration
+	youtubeAPIKey      *string
+	pgpKeyServer       *string
+	matrixHomeserver   *string
+	matrixToken        *string
+	farcasterHub       *string
+	hiveNode           *string
+	lbryApi            *string
+	instagramSession   *string
+	twitchClientID     *string
+	twitchClientSecret *string
+	tumblrAPIKey       *string
+	facebookToken      *string
+	soundcloudClientID *string
+	vimeoToken         *string
+	gitlabURL          *string
+	gitlabToken        *string
+	discordBotToken    *string
+	floAddressIndex    *uint
+	templates          *string
+	nameNFKC           *bool
+	policy             *string
+	maxBodySize        *int64
+	cacheTTL           *time.Duration
+	cacheNegativeTTL   *time.Duration
+	cacheMaxEntries    *int
+	skipSignerCheck    *bool
+	breakerFailureRate *float64
+	breakerMinReqs     *int
+	breakerWindow      *time.Duration
+	breakerCooldown    *time.Duration
+	breakerProbes      *int
+	oipMaxConcurrent   *int64
+	twitterMaxConc     *int64
+	userAgent          *string
+	platformUserAgents *string
+	httpProxy          *string
+	fixtures           *string
+	fixturesMode       *string
+}
+
+// commonEnv lists the options flags read from unprefixed environment variables.
+var commonEnv = []string{"oip-api", "oip-timeout", "oip-attempts", "oip-retry-delay", "twitter-rate-limit-wait", "twitter-rate-limit", "twitter-rate-window", "twitter-rate-burst", "bluesky-appview", "nostr-relays", "nostr-timeout", "github-token", "dns-resolver", "dns-timeout", "youtube-api-key", "pgp-keyserver", "matrix-homeserver", "matrix-token", "farcaster-hub", "hive-node", "lbry-api", "instagram-session", "twitch-client-id", "twitch-client-secret", "tumblr-api-key", "facebook-token", "soundcloud-client-id", "vimeo-token", "gitlab-url", "gitlab-token", "discord-bot-token", "flo-address-index", "templates", "name-nfkc", "policy", "max-body-size", "http-timeout", "cache-ttl", "cache-negative-ttl", "cache-max-entries", "skip-signer-check", "breaker-failure-rate", "breaker-min-requests", "breaker-window", "breaker-cooldown", "breaker-probes", "oip-max-concurrent", "twitter-max-concurrent", "user-agent", "platform-user-agents", "fixtures", "fixtures-mode", "config", "log-level", "log-format"}
+
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return flags, &options{
+		consumerKey:        flags.String("consumer-key", "", "Twitter Consumer Key"),
+		consumerSecret:     flags.String("consumer-secret", "", "Twitter Consumer Secret"),
+		accessToken:        flags.String("access-token", "", "Twitter Access Token"),
+		accessSecret:       flags.String("access-secret", "", "Twitter Access Secret"),
+		bearerToken:        flags.String("bearer-token", "", "Twitter api v2 Bearer Token, used when the OAuth1 keys aren't given"),
+		oipApi:             flags.String("oip-api", "https://api.oip.io/oip", "Base URL of the OIP daemon api"),
+		oipTimeout:         flags.Duration("oip-timeout", 0, "Timeout for each OIP api request, if shorter than -http-timeout"),
+		oipAttempts:        flags.Int("oip-attempts", 3, "Times to try an OIP api request that fails with a 5xx, timeout, or dropped connection"),
+		oipRetryDelay:      flags.Duration("oip-retry-delay", 200*time.Millisecond, "Base delay of the exponential backoff between OIP api attempts"),
+		rateLimitWait:      flags.Duration("twitter-rate-limit-wait", 5*time.Second, "Longest to wait for the Twitter rate limit to reset before responding RATE_LIMITED"),
+		rateLimit:          flags.Int("twitter-rate-limit", 900, "Tweet lookups allowed per -twitter-rate-window, 0 for no limit"),
+		rateWindow:         flags.Duration("twitter-rate-window", 15*time.Minute, "Window for -twitter-rate-limit"),
+		rateBurst:          flags.Int("twitter-rate-burst", 10, "Tweet lookups allowed at once before -twitter-rate-limit applies"),
+		blueskyAppView:     flags.String("bluesky-appview", "https://public.api.bsky.app", "Base URL of the Bluesky appview or PDS used to resolve posts"),
+		nostrRelays:        flags.String("nostr-relays", "wss://relay.damus.io,wss://nos.lol,wss://relay.nostr.band", "Comma separated websocket URLs of the relays nostr events are requested from"),
+		nostrTimeout:       flags.Duration("nostr-timeout", 5*time.Second, "How long to wait for relays to return a nostr event"),
+		githubToken:        flags.String("github-token", "", "GitHub token used for gist lookups to raise the rate limit"),
+		dnsResolver:        flags.String("dns-resolver", "", "Nameserver (host:port) for DNS proof lookups instead of the system resolver"),
+		dnsTimeout:         flags.Duration("dns-timeout", 5*time.Second, "Timeout for each DNS proof lookup"),
+		youtubeAPIKey:      flags.String("youtube-api-key", "", "YouTube Data API key for checking video description proofs"),
+		pgpKeyServer:       flags.String("pgp-keyserver", "https://keys.openpgp.org", "Base URL of the keyserver publishers' PGP keys are fetched from"),
+		matrixHomeserver:   flags.String("matrix-homeserver", "https://matrix.org", "Base URL of the homeserver Matrix events are fetched from"),
+		matrixToken:        flags.String("matrix-token", "", "Matrix access token, instead of registering a guest account"),
+		farcasterHub:       flags.String("farcaster-hub", "https://hub.pinata.cloud", "Base URL of the Farcaster hub HTTP api casts are fetched from"),
+		hiveNode:           flags.String("hive-node", "https://api.hive.blog", "URL of the Hive RPC node posts are fetched from"),
+		lbryApi:            flags.String("lbry-api", "https://api.na-backend.odysee.com/api/v1/proxy", "URL of the LBRY SDK api Odysee claims are resolved with"),
+		instagramSession:   flags.String("instagram-session", "", "sessionid cookie of an Instagram account to fetch posts as"),
+		twitchClientID:     flags.String("twitch-client-id", "", "Client id of the Twitch app channels are looked up as"),
+		twitchClientSecret: flags.String("twitch-client-secret", "", "Client secret of the Twitch app channels are looked up as"),
+		tumblrAPIKey:       flags.String("tumblr-api-key", "", "Tumblr api key posts are fetched with, instead of the public v1 api"),
+		facebookToken:      flags.String("facebook-token", "", "Facebook app access token posts are read from the Graph API with"),
+		soundcloudClientID: flags.String("soundcloud-client-id", "", "SoundCloud client id URLs are resolved with, instead of oEmbed"),
+		vimeoToken:         flags.String("vimeo-token", "", "Vimeo api token videos are fetched with, instead of oEmbed"),
+		gitlabURL:          flags.String("gitlab-url", "https://gitlab.com", "Base URL of the GitLab instance snippet ids refer to"),
+		gitlabToken:        flags.String("gitlab-token", "", "GitLab access token for the -gitlab-url instance"),
+		discordBotToken:    flags.String("discord-bot-token", "", "Token of the bot Discord messages are read as"),
+		floAddressIndex:    flags.Uint("flo-address-index", 0, "Index of the publisher address signed proofs must be signed by on the external chain of its floBip44XPub"),
+		templates:          flags.String("templates", "", "JSON file of the verification statement templates to accept, instead of the built-in one"),
+		nameNFKC:           flags.Bool("name-nfkc", false, "Also treat compatibility variants, e.g. fullwidth letters, as the same when comparing publisher names"),
+		policy:             flags.String("policy", verifier.PolicyAny, "When a claim is verified: any (one platform verifies), all (every referenced platform verifies), or a platform name"),
+		maxBodySize:        flags.Int64("max-body-size", 4<<20, "Maximum size in bytes of an upstream response body"),
+		cacheTTL:           flags.Duration("cache-ttl", 10*time.Minute, "How long successful claim, publisher, and post lookups are cached"),
+		cacheNegativeTTL:   flags.Duration("cache-negative-ttl", 30*time.Second, "How long failed lookups (not found, bad format) are cached"),
+		cacheMaxEntries:    flags.Int("cache-max-entries", 100000, "Most entries each lookup cache holds, 0 for no limit; once full, entries close to expiring are evicted"),
+		skipSignerCheck:    flags.Bool("skip-signer-check", false, "Don't require the claim and publisher records to be signed by the same address (for legacy claims)"),
+		breakerFailureRate: flags.Float64("breaker-failure-rate", 0.5, "Fraction of requests to an upstream host in -breaker-window that must fail for its circuit breaker to open"),
+		breakerMinReqs:     flags.Int("breaker-min-requests", 10, "Requests to an upstream host in -breaker-window before its circuit breaker can open, 0 to disable the breakers"),
+		breakerWindow:      flags.Duration("breaker-window", time.Minute, "Window the failures of each upstream host are counted over"),
+		breakerCooldown:    flags.Duration("breaker-cooldown", 30*time.Second, "How long an open circuit breaker fails requests fast before letting probes through"),
+		breakerProbes:      flags.Int("breaker-probes", 1, "Probe requests that must succeed for a half-open circuit breaker to close"),
+		oipMaxConcurrent:   flags.Int64("oip-max-concurrent", 32, "Most OIP api requests in flight at once, 0 for no limit; more wait for their turn"),
+		twitterMaxConc:     flags.Int64("twitter-max-concurrent", 16, "Most Twitter api requests in flight at once, 0 for no limit; more wait for their turn"),
+		userAgent:          flags.String("user-agent", "oip-verifier/"+buildVersion(), "User-Agent sent on outbound requests"),
+		platformUserAgents: flags.String("platform-user-agents", "", "Comma separated platform=User-Agent overrides of -user-agent, e.g. for reddit or instagram (oip for the OIP api)"),
+		httpProxy:          flags.String("http-proxy", "", "URL of the proxy to make outbound requests through, instead of the one HTTPS_PROXY or HTTP_PROXY name"),
+		fixtures:           flags.String("fixtures", "", "Directory of upstream http responses to record to or replay from, see -fixtures-mode; nostr relays and DNS are not recorded"),
+		fixturesMode:       flags.String("fixtures-mode", fixturesReplay, "With -fixtures, record the responses of upstreams or replay them without making requests, in which case no Twitter credentials are needed: without any, tweets are replayed from api v2 responses"),
 	}
+}
 
-	gp := &gabPost{}
-	err = json.Unmarshal(body, gp)
+// parseFlags parses args into flags, then fills any flags not given on the
+// command line from the environment: Twitter credentials from TWITTER_*
+// variables and the common options plus env from unprefixed ones, then the
+// rest from the -config file. Last, secrets are read from the files of their
+// -file flags. With -print-config it prints the result and exits.
+func parseFlags(flags *flag.FlagSet, args []string, env ...string) error {
+	flags.DurationVar(&httpClient.Timeout, "http-timeout", httpClient.Timeout, "Timeout for each outbound http request")
+	config := flags.String("config", "", "YAML (.yaml, .yml) or TOML (.toml) file of flag values, by flag name, for flags not given on the command line or in the environment")
+	level := flags.String("log-level", "info", "Log at debug, info, warn, or error level and above; reloaded with the config")
+	format := flags.String("log-format", "text", "Log as text or json lines")
+	printOnly := flags.Bool("print-config", false, "Print the effective configuration as YAML, with secrets redacted, and exit")
+	addSecretFileFlags(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = flagutil.SetFlagsFromEnv(flags, "TWITTER")
+	if err == nil {
+		err = setFlagsFromEnv(flags, append(append(commonEnv, env...), secretFileFlags(flags)...)...)
+	}
+	if err == nil && *config != "" {
+		err = applyConfigFile(flags, *config)
+	}
+	if err == nil {
+		err = readSecretFiles(flags)
+	}
+	if err == nil {
+		err = setupLogging(os.Stderr, *level, *format)
+	}
 	if err != nil {
-		return "", "", err
+		// like the errors of flags.Parse
+		fmt.Fprintln(flags.Output(), err)
+		return err
 	}
-	gabTokens := verificationRegex.FindStringSubmatch(gp.Body)
+	if *printOnly {
+		err = printConfig(flags)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	return nil
+}
 
-	if len(gabTokens) != 3 {
-		return "", "", ErrBadFormat
+// newTwitterClient returns a v1.1 api client for the OAuth1 keys of o, or nil
+// when none are given. It names the keys that are missing when only some are.
+func newTwitterClient(o *options) (*twitter.Client, error) {
+	keys := []struct {
+		name  string
+		value string
+	}{{"consumer-key", *o.consumerKey}, {"consumer-secret", *o.consumerSecret}, {"access-token", *o.accessToken}, {"access-secret", *o.accessSecret}}
+	var missing []string
+	for _, k := range keys {
+		if k.value == "" {
+			missing = append(missing, "-"+k.name)
+		}
+	}
+	if len(missing) == len(keys) {
+		return nil, nil
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing %s: the Twitter consumer key/secret and access token/secret must all be given", strings.Join(missing, ", "))
 	}
 
-	return gabTokens[1], gabTokens[2], nil
+	config := oauth1.NewConfig(*o.consumerKey, *o.consumerSecret)
+	token := oauth1.NewToken(*o.accessToken, *o.accessSecret)
+	twitterHttpClient := config.Client(context.WithValue(context.Background(), oauth1.HTTPClient, httpClient), token)
+	twitterHttpClient.Timeout = httpClient.Timeout
+	return twitter.NewClient(twitterHttpClient), nil
 }
 
-func getPublisher(txid string) (*Publisher, error) {
-	body, err := httpGet("https://api.oip.io/oip/o5/record/get/" + txid)
+// setup creates the Twitter client and the verifier from o.
+func setup(o *options) error {
+	if *o.fixtures != "" && *o.fixturesMode != fixturesRecord && *o.fixturesMode != fixturesReplay {
+		return fmt.Errorf("-fixtures-mode must be %s or %s", fixturesRecord, fixturesReplay)
+	}
+	replaying := *o.fixtures != "" && *o.fixturesMode == fixturesReplay
+	client, err := newTwitterClient(o)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	results := &oipApiResult{}
-	err = json.Unmarshal(body, results)
+	err = validateBaseURL(*o.oipApi)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("invalid OIP api %q: %v", *o.oipApi, err)
+	}
+	err = validateBaseURL(*o.blueskyAppView)
+	if err != nil {
+		return fmt.Errorf("invalid Bluesky appview %q: %v", *o.blueskyAppView, err)
+	}
+	if *o.dnsResolver != "" {
+		if _, _, err := net.SplitHostPort(*o.dnsResolver); err != nil {
+			return fmt.Errorf("invalid DNS resolver %q: %v", *o.dnsResolver, err)
+		}
+	}
+	templates := verifier.BuiltinTemplates
+	if *o.templates != "" {
+		templates, err = verifier.LoadTemplates(*o.templates)
+		if err != nil {
+			return fmt.Errorf("invalid templates: %v", err)
+		}
 	}
 
-	if len(results.Results) == 1 {
-		return &results.Results[0].Record.Details.Publisher, nil
+	verify = verifier.New(client, *o.oipApi, httpClient)
+	verify.TwitterBearerToken = *o.bearerToken
+	if replaying && client == nil && *o.bearerToken == "" {
+		// tweets are replayed from v2 api fixtures, which need no credentials
+		verify.TwitterBearerToken = fixtureBearerToken
+	}
+	verify.OipTimeout = *o.oipTimeout
+	verify.OipAttempts = *o.oipAttempts
+	verify.OipRetryDelay = *o.oipRetryDelay
+	verify.TwitterRateLimitWait = *o.rateLimitWait
+	verify.BlueskyAppView = strings.TrimSuffix(*o.blueskyAppView, "/")
+	verify.NostrRelays = splitList(*o.nostrRelays)
+	verify.NostrTimeout = *o.nostrTimeout
+	verify.GitHubToken = *o.githubToken
+	verify.DNSResolver = *o.dnsResolver
+	verify.DNSTimeout = *o.dnsTimeout
+	verify.YouTubeAPIKey = *o.youtubeAPIKey
+	verify.PGPKeyServer = strings.TrimSuffix(*o.pgpKeyServer, "/")
+	verify.MatrixHomeserver = strings.TrimSuffix(*o.matrixHomeserver, "/")
+	verify.MatrixToken = *o.matrixToken
+	verify.FarcasterHub = strings.TrimSuffix(*o.farcasterHub, "/")
+	verify.HiveNode = *o.hiveNode
+	verify.LBRYApi = *o.lbryApi
+	verify.InstagramSession = *o.instagramSession
+	verify.TwitchClientID = *o.twitchClientID
+	verify.TwitchClientSecret = *o.twitchClientSecret
+	verify.TumblrAPIKey = *o.tumblrAPIKey
+	verify.FacebookToken = *o.facebookToken
+	verify.SoundCloudClientID = *o.soundcloudClientID
+	verify.VimeoToken = *o.vimeoToken
+	verify.GitLabURL = strings.TrimSuffix(*o.gitlabURL, "/")
+	verify.GitLabToken = *o.gitlabToken
+	verify.DiscordBotToken = *o.discordBotToken
+	verify.FLOAddressIndex = uint32(*o.floAddressIndex)
+	verify.Templates = templates
+	verify.NameNFKC = *o.nameNFKC
+	err = verify.SetPolicy(*o.policy)
+	if err != nil {
+		return err
+	}
+	if *o.rateLimit > 0 {
+		verify.TwitterLimiter = rate.NewLimiter(twitterRate(*o.rateLimit, *o.rateWindow), *o.rateBurst)
 	}
+	verify.MaxBodySize = *o.maxBodySize
+	verify.CacheTTL = *o.cacheTTL
+	verify.CacheNegativeTTL = *o.cacheNegativeTTL
+	if *o.cacheMaxEntries < 0 {
+		return errors.New("-cache-max-entries must not be negative")
+	}
+	verify.CacheMaxEntries = *o.cacheMaxEntries
+	verify.SkipSignerCheck = *o.skipSignerCheck
+	if *o.breakerFailureRate <= 0 || *o.breakerFailureRate > 1 {
+		return errors.New("-breaker-failure-rate must be above 0 and at most 1")
+	}
+	if *o.breakerProbes < 1 {
+		return errors.New("-breaker-probes must be at least 1")
+	}
+	verify.Breakers = verifier.BreakerSettings{
+		FailureRate: *o.breakerFailureRate,
+		MinRequests: *o.breakerMinReqs,
+		Window:      *o.breakerWindow,
+		Cooldown:    *o.breakerCooldown,
+		Probes:      *o.breakerProbes,
+	}
+	verify.MaxConcurrent = map[string]int64{"oip": *o.oipMaxConcurrent, "twitter": *o.twitterMaxConc}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	limitConnections(transport, verify.MaxConcurrent)
 
-	return nil, errors.New("unable to find publisher by txid")
+	verify.UserAgent = *o.userAgent
+	agents, err := parseUserAgents(*o.platformUserAgents)
+	if err != nil {
+		return fmt.Errorf("invalid -platform-user-agents: %v", err)
+	}
+	known := append(verify.Platforms(), "oip")
+	for target, ua := range agents {
+		if !slices.Contains(known, target) {
+			return fmt.Errorf("invalid -platform-user-agents: unknown platform %q", target)
+		}
+		verify.UserAgents[target] = ua
+	}
+	if *o.httpProxy != "" {
+		proxy, err := parseProxy(*o.httpProxy)
+		if err != nil {
+			return fmt.Errorf("invalid -http-proxy: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+		verify.Proxy = transport.Proxy
+	}
+	transport.DialContext = verifier.GuardDialer(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}, proxyAddrs(*o.httpProxy)...)
+	var rt http.RoundTripper = userAgentTransport{transport, *o.userAgent}
+	if *o.fixtures != "" {
+		rt, err = newFixtureTransport(*o.fixtures, replaying, rt)
+		if err != nil {
+			return fmt.Errorf("invalid -fixtures: %v", err)
+		}
+		slog.Info("Using fixtures", "dir", *o.fixtures, "mode", *o.fixturesMode)
+	}
+	upstream.swap(transport, rt)
+	return nil
 }
 
-type gabPost struct {
-	Body string `json:"body"`
+// proxyAddrs returns the host:port of the proxies outbound requests may be
+// made through: httpProxy, or those of HTTPS_PROXY and HTTP_PROXY.
+func proxyAddrs(httpProxy string) []string {
+	proxies := []string{httpProxy}
+	if httpProxy == "" {
+		env := httpproxy.FromEnvironment()
+		proxies = []string{env.HTTPSProxy, env.HTTPProxy}
+	}
+	var addrs []string
+	for _, p := range proxies {
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" {
+			// schemeless proxies are http, as for http.ProxyFromEnvironment
+			u, err = url.Parse("http://" + p)
+			if err != nil || u.Host == "" {
+				continue
+			}
+		}
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[u.Scheme]
+		}
+		addrs = append(addrs, net.JoinHostPort(u.Hostname(), port))
+	}
+	return addrs
 }
 
-type elasticOip5Record struct {
-	Record record `json:"record"`
-	Meta   RMeta  `json:"meta"`
-}
+// userAgentEntry matches the start of a platform=User-Agent entry.
+var userAgentEntry = regexp.MustCompile(`^\s*([a-z0-9-]+)=`)
 
-type RMeta struct {
-	Deactivated bool   `json:"deactivated"`
-	SignedBy    string `json:"signed_by"`
-	Time        int64  `json:"time"`
-	Txid        string `json:"txid"`
+// parseUserAgents parses comma separated platform=User-Agent entries. Only
+// a comma followed by the platform= of the next entry separates them, so
+// user agents may contain commas.
+func parseUserAgents(s string) (map[string]string, error) {
+	agents := make(map[string]string)
+	var target string
+	for _, part := range strings.Split(s, ",") {
+		if m := userAgentEntry.FindStringSubmatch(part); m != nil {
+			target = m[1]
+			agents[target] = part[len(m[0]):]
+			continue
+		}
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		if target == "" {
+			return nil, fmt.Errorf("%q is not platform=User-Agent", part)
+		}
+		agents[target] += "," + part
+	}
+	for target, ua := range agents {
+		agents[target] = strings.TrimSpace(ua)
+	}
+	return agents, nil
 }
 
-type oipApiResult struct {
-	Count   int
-	Total   int
-	Results []elasticOip5Record
-	After   string
+// parseProxy parses an http(s) or socks5 proxy URL.
+func parseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New("scheme must be http, https, or socks5")
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	return u, nil
 }
 
-type record struct {
-	Details details `json:"details"`
+// limitConnections sizes the connection pools of t for the upstream
+// concurrency limits: each host keeps as many idle connections as the
+// largest limit allows in flight, and opens no more than that. Without
+// limits for every upstream, the number of connections isn't capped.
+func limitConnections(t *http.Transport, limits map[string]int64) {
+	var largest int64
+	capped := true
+	for _, n := range limits {
+		if n <= 0 {
+			capped = false
+		} else if n > largest {
+			largest = n
+		}
+	}
+	if largest == 0 {
+		return
+	}
+	t.MaxIdleConnsPerHost = int(largest)
+	if t.MaxIdleConns < 2*int(largest) {
+		t.MaxIdleConns = 2 * int(largest)
+	}
+	if capped {
+		t.MaxConnsPerHost = int(largest)
+	}
 }
 
-type details struct {
-	Publisher         Publisher         `json:"tmpl_433C2783"`
-	VerificationClaim VerificationClaim `json:"tmpl_F471DFF9"`
+// twitterRate is the rate of limit tweet lookups per window, or no limit
+// when limit is 0.
+func twitterRate(limit int, window time.Duration) rate.Limit {
+	if limit <= 0 {
+		return rate.Inf
+	}
+	return rate.Every(window / time.Duration(limit))
 }
 
-type tmpl433C2783 struct {
-	Name         string `json:"name"`
-	FloBip44XPub string `json:"floBip44XPub"`
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
-type tmplF471DFF9 struct {
-	GabId     string `json:"gabId"`
-	TwitterId string `json:"twitterId"`
-	// RegisteredPublisher string `json:"registeredPublisher"`
+// validateBaseURL checks that base is an absolute http(s) URL without a
+// query or fragment, so paths can be appended to it.
+func validateBaseURL(base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must not have a query or fragment")
+	}
+	return nil
 }
 
-type VerificationClaim struct {
-	tmplF471DFF9
-}
+func runServe(args []string) {
+	flags, opts := newFlagSet("serve")
+	listen := flags.String("listen", ":1607", "Address (host:port) to serve the http api on")
+	metricsListen := flags.String("metrics-listen", "", "Address (host:port) to serve /metrics on instead of the http api address")
+	grpcListen := flags.String("grpc-listen", "", "Address (host:port) to serve the grpc api on, if set")
+	flags.DurationVar(&healthInterval, "health-interval", healthInterval, "How long /health reuses the result of each upstream check")
+	flags.DurationVar(&drainTimeout, "drain-timeout", drainTimeout, "How long to wait for in-flight requests to finish on shutdown")
+	flags.IntVar(&batchMaxIds, "batch-max-ids", batchMaxIds, "Maximum number of claim IDs accepted by the batch check endpoint")
+	flags.IntVar(&batchWorkers, "batch-workers", batchWorkers, "Number of claims checked concurrently for each batch request")
+	ipRate := flags.Float64("ip-rate", 1, "Requests per second allowed from each client IP, 0 for no limit")
+	ipBurst := flags.Int("ip-burst", 20, "Requests allowed at once from each client IP before -ip-rate applies")
+	ipIdle := flags.Duration("ip-idle", 10*time.Minute, "How long an idle client IP's rate limit state is kept")
+	trusted := flags.String("trusted-proxies", "", "Comma separated CIDRs of proxies whose X-Forwarded-For and X-Real-IP headers are trusted")
+	ipExempt := flags.String("ip-exempt", "", "Comma separated CIDRs of clients exempt from -ip-rate")
+	flags.StringVar(&tlsCert, "tls-cert", "", "Certificate file to serve the http api with TLS, reloaded on SIGHUP")
+	flags.StringVar(&tlsKey, "tls-key", "", "Key file of -tls-cert")
+	acmeDomain := flags.String("acme-domain", "", "Comma separated domains to serve the http api with TLS for, using Let's Encrypt certificates")
+	flags.StringVar(&acmeCache, "acme-cache", acmeCache, "Directory to cache Let's Encrypt certificates in")
+	flags.StringVar(&acmeHTTPListen, "acme-http-listen", acmeHTTPListen, "Address (host:port) to answer ACME challenges and redirect to https on")
+	keys := flags.String("api-keys", "", "Comma separated api keys, each optionally label:key, that debug, job and admin endpoints require; without keys they refuse every request")
+	keyFile := flags.String("api-key-file", "", "File of api keys, one label:key or key per line, reloaded on SIGHUP")
+	flags.BoolVar(&insecureOpenAdmin, "insecure-open-admin", false, "Serve the debug, job and admin endpoints to anyone while there are no api keys")
+	flags.BoolVar(&accessLog, "access-log", false, "Log every request, with the label of its api key")
+	flags.IntVar(&gzipMinSize, "gzip-min-size", gzipMinSize, "Gzip responses of at least this many bytes for clients that accept it; 0 disables compression")
+	flags.Int64Var(&maxRequestBody, "max-request-body", maxRequestBody, "Maximum size in bytes of a batch or async check request body")
+	corsOrigins := flags.String("cors-origins", "", "Comma separated origins allowed to call the http api from browsers, e.g. https://*.oip.io")
+	corsHeaders := flags.String("cors-headers", "Accept,Content-Type,If-None-Match,X-Request-ID", "Comma separated request headers browsers may send cross-origin")
+	corsExpose := flags.String("cors-expose", "ETag,Retry-After,X-Request-ID", "Comma separated response headers browsers may read cross-origin")
+	corsMaxAge := flags.Duration("cors-max-age", 10*time.Minute, "How long browsers may cache a preflight response")
+	allowAllOrigins := flags.Bool("allow-all-origins", false, "Allow browsers on any origin to call the http api, ignoring -cors-origins")
+	flags.DurationVar(&badgeMaxAge, "badge-max-age", badgeMaxAge, "How long browsers may cache verification badges")
+	db := flags.String("db", "", "SQLite file or Postgres DSN to record every check in, served at /verified/publisher/history/{id}")
+	enableDebug := flags.Bool("enable-debug", false, "Serve /verified/publisher/debug/{id}, which exposes the fetched post contents")
+	flags.DurationVar(&reverifyInterval, "reverify-interval", 0, "How often to re-verify every claim recorded in -db, 0 to never")
+	seed := flags.String("reverify-seed", "", "File of claim txids, one per line, to re-verify besides those recorded in -db")
+	flags.IntVar(&reverifyWorkers, "reverify-workers", reverifyWorkers, "Number of claims re-verified concurrently")
+	enableCacheAdmin := flags.Bool("enable-cache-admin", false, "Serve /verified/admin/cache to flush, invalidate and inspect the caches, behind the api keys")
+	enableDocs := flags.Bool("enable-docs", false, "Serve Swagger UI for /verified/openapi.json at /verified/docs")
+	flags.BoolVar(&enablePprof, "enable-pprof", false, "Serve pprof profiles at /verified/admin/debug/pprof/ and runtime stats at /verified/admin/runtime, behind the api keys")
+	flags.BoolVar(&pprofOnMetrics, "pprof-on-metrics", false, "Serve the -enable-pprof endpoints on the -metrics-listen listener instead of the http api's")
+	deprecation := flags.String("legacy-deprecation", "", "Date (YYYY-MM-DD) sent in the Deprecation header of unversioned check responses")
+	sunset := flags.String("legacy-sunset", "", "Date (YYYY-MM-DD) sent in the Sunset header of unversioned check responses")
+	flags.IntVar(&jobWorkers, "job-workers", jobWorkers, "Number of asynchronous check jobs run concurrently")
+	flags.DurationVar(&jobTTL, "job-ttl", jobTTL, "How long the result of an asynchronous check job is kept")
+	flags.IntVar(&streamMaxSubscribers, "stream-max-subscribers", streamMaxSubscribers, "Maximum number of concurrent /verified/stream subscribers")
+	flags.DurationVar(&streamKeepAlive, "stream-keep-alive", streamKeepAlive, "How often /verified/stream sends a comment to keep idle connections open")
+	flags.Var(&webhookURLs, "webhook-url", "URL to POST to when a claim's verification changes, may be repeated")
+	secret := flags.String("webhook-secret", "", "Secret to sign webhook payloads with, as an HMAC-SHA256 in X-Verifier-Signature")
+	err := parseFlags(flags, args, "listen", "metrics-listen", "grpc-listen", "tls-cert", "tls-key", "acme-domain", "acme-cache", "acme-http-listen", "batch-max-ids", "batch-workers", "health-interval", "drain-timeout", "ip-rate", "ip-burst", "ip-idle", "trusted-proxies", "ip-exempt", "api-keys", "api-key-file", "insecure-open-admin", "access-log", "gzip-min-size", "max-request-body", "cors-origins", "cors-headers", "cors-expose", "cors-max-age", "allow-all-origins", "badge-max-age", "db", "enable-debug", "enable-cache-admin", "enable-docs", "enable-pprof", "pprof-on-metrics", "legacy-deprecation", "legacy-sunset", "reverify-interval", "reverify-seed", "reverify-workers", "job-workers", "job-ttl", "stream-max-subscribers", "stream-keep-alive", "webhook-url", "webhook-secret")
+	if err != nil {
+		panic(err)
+	}
+	if reverifyInterval > 0 && *db == "" {
+		panic(errors.New("-reverify-interval needs -db to record transitions in"))
+	}
+	if len(webhookURLs) > 0 && *db == "" {
+		panic(errors.New("-webhook-url needs -db to compare checks against"))
+	}
+	if jobWorkers < 1 {
+		panic(errors.New("-job-workers must be at least 1"))
+	}
+	if reverifyWorkers < 1 {
+		panic(errors.New("-reverify-workers must be at least 1"))
+	}
+	apiKeyMap, err := loadKeys(*keys, *keyFile)
+	if err != nil {
+		panic(err)
+	}
+	apiKeys.set(apiKeyMap)
+	if len(apiKeyMap) == 0 {
+		if insecureOpenAdmin {
+			slog.Warn("No api keys given, the debug, job and admin endpoints are open to anyone")
+		} else {
+			slog.Info("No api keys given, the debug, job and admin endpoints refuse every request")
+		}
+	}
+	if *keyFile != "" {
+		go reloadKeysOnHUP(*keys, *keyFile)
+	}
+	acmeDomains = splitList(*acmeDomain)
+	corsPolicy.Store(cors.New(newCorsOptions(*corsOrigins, *corsHeaders, *corsExpose, *corsMaxAge, *allowAllOrigins)))
+	webhookTargets.Store(append([]string(nil), webhookURLs...))
+	legacyDeprecation, err = parseDate(*deprecation)
+	if err != nil {
+		panic(err)
+	}
+	legacySunset, err = parseDate(*sunset)
+	if err != nil {
+		panic(err)
+	}
+	if *seed != "" {
+		reverifySeed, err = readSeed(*seed)
+		if err != nil {
+			panic(err)
+		}
+	}
 
-type Publisher struct {
-	tmpl433C2783
+	err = setup(opts)
+	if err != nil {
+		panic(err)
+	}
+	verify.Hooks = metricsHooks
+	webhookSecret.Store(*secret)
+	go reloadSecretsOnHUP(flags, opts)
+	enabled, disabled := platforms()
+	slog.Info("Verifying platforms", "enabled", strings.Join(enabled, ","), "disabled", strings.Join(disabled, ","))
+	if !verify.Configured("twitter") {
+		slog.Info("Twitter credentials not given, Twitter verification is disabled")
+		dropDependency("twitter")
+	}
+
+	trustedProxies, err = parseCIDRs(*trusted)
+	if err != nil {
+		panic(err)
+	}
+	var limiter *ipLimiter
+	if *ipRate > 0 {
+		exempt, err := parseCIDRs(*ipExempt)
+		if err != nil {
+			panic(err)
+		}
+		limiter = newIPLimiter(rate.Limit(*ipRate), *ipBurst, *ipIdle, exempt)
+		go limiter.evictIdle()
+		rootRouter.Use(limiter.Middleware)
+	}
+
+	if *db != "" {
+		history, err = store.Open(context.Background(), *db)
+		if err != nil {
+			panic(err)
+		}
+		go writeHistory()
+		rootRouter.HandleFunc("/publisher/history/{id:[a-f0-9]{64}}", handleHistory).Methods(http.MethodGet)
+	}
+	go runJobs(serverCtx)
+	if reverifyInterval > 0 {
+		go runReverify(serverCtx)
+	}
+	if history != nil {
+		go deliverWebhooks(serverCtx)
+	}
+	if configFile != "" {
+		go newReloader(flags, limiter).reloadOnHUP()
+	}
+	if *enableDebug {
+		protect(rootRouter, "/publisher/debug/{id:[a-f0-9]{64}}", handleDebug).Methods(http.MethodGet)
+		protect(v1Router, "/publisher/debug/{id:[a-f0-9]{64}}", handleDebug).Methods(http.MethodGet)
+	}
+	if *enableCacheAdmin {
+		registerCacheAdmin(rootRouter)
+	}
+	if *enableDocs {
+		rootRouter.HandleFunc("/docs", handleDocs).Methods(http.MethodGet)
+	}
+	if pprofOnMetrics && *metricsListen == "" {
+		panic(errors.New("-pprof-on-metrics requires -metrics-listen"))
+	}
+	if enablePprof && !pprofOnMetrics {
+		registerDiagnostics(rootRouter)
+	}
+
+	go verify.MaintainCaches(5 * time.Minute)
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		err := shutdownTracing(ctx)
+		if err != nil {
+			slog.Error("Unable to flush traces", "err", err)
+		}
+	}()
+
+	err = ServeMetrics(*metricsListen)
+	if err != nil {
+		slog.Error("Unable to start metrics listener", "err", err, "listen", *metricsListen)
+		os.Exit(1)
+	}
+
+	if *grpcListen != "" {
+		err = ServeGRPC(*grpcListen)
+		if err != nil {
+			slog.Error("Unable to start grpc listener", "err", err, "listen", *grpcListen)
+			os.Exit(1)
+		}
+	}
+
+	err = Serve(*listen)
+	if err != nil {
+		slog.Error("Http api stopped", "err", err, "listen", *listen)
+		os.Exit(1)
+	}
 }
 
-var verificationRegex = regexp.MustCompile(`@OpenIndexProto(?:col)?\p{Zs}verifying\p{Zs}[\p{Pi}"'](.+)[\p{Pf}"']\p{Zs}is\p{Zs}publishing\p{Zs}as:\p{Zs}\n?([0-9a-f]{64})`)
+// setFlagsFromEnv behaves like flagutil.SetFlagsFromEnv for the named flags,
+// but reads them from unprefixed environment variables (listen => LISTEN).
+func setFlagsFromEnv(fs *flag.FlagSet, names ...string) error {
+	alreadySet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		alreadySet[f.Name] = true
+	})
+	for _, name := range names {
+		if alreadySet[name] {
+			continue
+		}
+		key := strings.ToUpper(strings.Replace(name, "-", "_", -1))
+		val := os.Getenv(key)
+		if val == "" {
+			continue
+		}
+		if err := fs.Set(name, val); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", val, key, err)
+		}
+	}
+	return nil
+}
 
-type VerificationResponse struct {
-	Twitter    bool   `json:"twitter"`
-	TwitterMsg string `json:"twitter_msg,omitempty"`
-	Gab        bool   `json:"gab"`
-	GabMsg     string `json:"gab_msg,omitempty"`
-	Msg        string `json:"msg,omitempty"`
+type ErrorResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
 }
 
-var ErrBadFormat = errors.New("message contents did not match expected format")
+// httpStatus returns the HTTP status code a check response is sent with.
+func httpStatus(status *verifier.Result) int {
+	switch status.Code {
+	case verifier.CodeClaimNotFound, verifier.CodePublisherNotFound:
+		return http.StatusNotFound
+	case verifier.CodeUpstreamError:
+		return http.StatusBadGateway
+	case verifier.CodeRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"github.com/oipwg/verifier/verifier"
+)
+
+// The claims served by fakeUpstreams.
+const (
+	verifiedClaim    = "63715582e8b5549240b257727fe6ac17b99e4272e69b30887922398a1778c133"
+	badFormatClaim   = "1d6dcbb8158ced6e93123a7745a30a4e4853c6dc6589b41ea3a920071d3bfd2e"
+	noPublisherClaim = "aa4778bfd650ebf7318fc8d805dcfd0630d9d3781b3c4706d739a44d6ec37ac3"
+	fixturePublisher = "4f03a060b7eb032972dcb327f2c989b14d5e73c35b3d5928a6f875ca39c159ba"
+	missingPublisher = "2a53d651afdf99f8b5a9324f47f69f013f538236e386ddbcc7460e57df8c448d"
+	fixtureOipApi    = "https://api.oip.io/oip"
+	fixtureSigner    = "FJw3DFuLyrEbr8Ba7AutMt3DSGMRzBcyuM"
+)
+
+// fixtureRecords are the OIP records served by fakeUpstreams.
+var fixtureRecords = map[string]interface{}{
+	verifiedClaim:    map[string]interface{}{"tmpl_F471DFF9": map[string]string{"twitterId": "1724567800000000001", "gabId": "111412345678901234"}},
+	badFormatClaim:   map[string]interface{}{"tmpl_F471DFF9": map[string]string{"twitterId": "1724567800000000002"}},
+	noPublisherClaim: map[string]interface{}{"tmpl_F471DFF9": map[string]string{"twitterId": "1724567800000000003"}},
+	fixturePublisher: map[string]interface{}{"tmpl_433C2783": map[string]string{"name": "Example Publisher"}},
+}
+
+// fixtureTweets and fixturePosts are the tweets and Gab posts served by
+// fakeUpstreams.
+var (
+	fixtureTweets = map[string]string{
+		"1724567800000000001": `@OpenIndexProtocol verifying "Example Publisher" is publishing as: ` + fixturePublisher,
+		"1724567800000000002": "Publishing on OIP as Example Publisher, verification coming soon!",
+		"1724567800000000003": `@OpenIndexProtocol verifying "Missing Publisher" is publishing as: ` + missingPublisher,
+	}
+	fixturePosts = map[string]string{
+		"111412345678901234": `@OpenIndexProtocol verifying "Example Publisher" is publishing as: ` + fixturePublisher,
+	}
+)
+
+// serveFixture answers r from the fixture records, tweets and posts.
+func serveFixture(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Host == "api.oip.io" && strings.HasPrefix(r.URL.Path, "/oip/o5/record/get/"):
+		txid := strings.TrimPrefix(r.URL.Path, "/oip/o5/record/get/")
+		results := []interface{}{}
+		if details, ok := fixtureRecords[txid]; ok {
+			results = append(results, map[string]interface{}{
+				"record": map[string]interface{}{"details": details},
+				"meta":   verifier.RMeta{SignedBy: fixtureSigner, Time: 1700000000, Txid: txid},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"count": len(results), "total": len(results), "results": results})
+	case r.URL.Host == "api.twitter.com" && r.URL.Path == "/1.1/statuses/show.json" && fixtureTweets[r.URL.Query().Get("id")] != "":
+		id := r.URL.Query().Get("id")
+		_ = json.NewEncoder(w).Encode(twitter.Tweet{IDStr: id, FullText: fixtureTweets[id], User: &twitter.User{ScreenName: "examplepub"}})
+	case r.URL.Host == "api.twitter.com" && strings.HasPrefix(r.URL.Path, "/2/tweets/"):
+		// the v2 api answers missing tweets with a 200 and only errors
+		id := strings.TrimPrefix(r.URL.Path, "/2/tweets/")
+		if text, ok := fixtureTweets[id]; ok {
+			fmt.Fprintf(w, `{"data": {"id": %q, "text": %q, "author_id": "9"}, "includes": {"users": [{"id": "9", "username": "examplepub"}]}}`, id, text)
+			return
+		}
+		fmt.Fprintf(w, `{"errors": [{"type": "https://api.twitter.com/2/problems/resource-not-found", "detail": "Could not find tweet with id: [%s]."}]}`, id)
+	case r.URL.Host == "gab.com" && fixturePosts[strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/")] != "":
+		id := strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "content": fixturePosts[id], "account": map[string]string{"username": "examplepub"}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors": [{"code": 144, "message": "No status found with that ID."}]}`))
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// countingTransport counts the requests made through base by URL, without
+// the query, after holding each for delay.
+type countingTransport struct {
+	base  http.RoundTripper
+	delay time.Duration
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *req.URL
+	u.RawQuery = ""
+	t.mu.Lock()
+	t.requests[u.String()]++
+	t.mu.Unlock()
+	if t.delay > 0 {
+		select {
+		case <-time.After(t.delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *countingTransport) count(url string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requests[url]
+}
+
+// fakeUpstreams makes verify and the health checks look everything up in
+// the fixture records, tweets and posts for the rest of t.
+func fakeUpstreams(t *testing.T) *countingTransport {
+	t.Helper()
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		serveFixture(rec, req)
+		res := rec.Result()
+		res.Request = req
+		return res, nil
+	})
+	ct := &countingTransport{base: base, requests: make(map[string]int)}
+	oldVerify, oldTransport := verify, httpClient.Transport
+	t.Cleanup(func() { verify, httpClient.Transport = oldVerify, oldTransport })
+	httpClient.Transport = ct
+	verify = verifier.New(twitter.NewClient(httpClient), fixtureOipApi, httpClient)
+	return ct
+}
+
+// get serves a GET of path with router.
+func get(path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConcurrentChecksShared(t *testing.T) {
+	ct := fakeUpstreams(t)
+	ct.delay = 100 * time.Millisecond
+
+	var wg sync.WaitGroup
+	codes := make([]int, 50)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = get("/verified/publisher/check/" + noPublisherClaim).Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: got %d, want 200", i, code)
+		}
+	}
+	for _, u := range []string{
+		fixtureOipApi + "/o5/record/get/" + noPublisherClaim,
+		"https://api.twitter.com/1.1/statuses/show.json",
+		fixtureOipApi + "/o5/record/get/" + missingPublisher,
+	} {
+		if n := ct.count(u); n != 1 {
+			t.Errorf("%s: got %d requests, want 1", u, n)
+		}
+	}
+}
+
+func TestSharedCheckCloned(t *testing.T) {
+	ct := fakeUpstreams(t)
+	ct.delay = 50 * time.Millisecond
+
+	results := make([]*verifier.Result, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			results[i], err = sharedCheckClaim(context.Background(), verifiedClaim)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	if n := ct.count(fixtureOipApi + "/o5/record/get/" + verifiedClaim); n != 1 {
+		t.Errorf("got %d claim lookups, want 1", n)
+	}
+	results[0].Claim.SignedBy = "changed"
+	if results[1].Claim.SignedBy == "changed" {
+		t.Error("callers share the claim metadata of a check")
+	}
+}
+
+// setupFlags runs setup with the flags args, undoing it at the end of t.
+func setupFlags(t *testing.T, args ...string) error {
+	t.Helper()
+	_, _, err := setupFlagSet(t, args...)
+	return err
+}
+
+// setupFlagSet is setupFlags returning the flags and options it set up.
+func setupFlagSet(t *testing.T, args ...string) (*flag.FlagSet, *options, error) {
+	t.Helper()
+	oldVerify, oldTransport, oldUpstream := verify, httpClient.Transport, upstream.current.Load()
+	t.Cleanup(func() {
+		verify, httpClient.Transport = oldVerify, oldTransport
+		upstream.current.Store(oldUpstream)
+	})
+	flags, o := newFlagSet("test")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return flags, o, err
+	}
+	return flags, o, setup(o)
+}
+
+// hostTransport sends the requests to the hosts it has a transport for
+// there, and the others to base.
+type hostTransport struct {
+	hosts map[string]http.RoundTripper
+	base  http.RoundTripper
+}
+
+func (t hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt, ok := t.hosts[req.URL.Host]; ok {
+		return rt.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func TestOipApiFlag(t *testing.T) {
+	records := map[string]string{
+		verifiedClaim:    `{"tmpl_F471DFF9": {"twitterId": "1724567800000000001"}}`,
+		fixturePublisher: `{"tmpl_433C2783": {"name": "Example Publisher"}}`,
+	}
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.EscapedPath())
+		mu.Unlock()
+		txid := strings.TrimPrefix(r.URL.Path, "/staging/oip/o5/record/get/")
+		details, ok := records[txid]
+		if !ok {
+			fmt.Fprint(w, `{"count": 0, "total": 0, "results": []}`)
+			return
+		}
+		fmt.Fprintf(w, `{"count": 1, "total": 1, "results": [{"record": {"details": %s}, "meta": {"signed_by": "FJw3DFuLyrEbr8Ba7AutMt3DSGMRzBcyuM", "time": 1700000000, "txid": %q}}]}`, details, txid)
+	}))
+	defer ts.Close()
+
+	if err := setupFlags(t, "-oip-api", "ftp://oip.example.com"); err == nil {
+		t.Error("setup accepted an ftp OIP api")
+	}
+	fixtures := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		serveFixture(rec, req)
+		return rec.Result(), nil
+	})
+	httpClient.Transport = hostTransport{map[string]http.RoundTripper{"api.twitter.com": fixtures}, http.DefaultTransport}
+	err := setupFlags(t, "-oip-api", ts.URL+"/staging/oip/", "-bearer-token", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := get("/verified/publisher/check/" + verifiedClaim)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	var res verifier.VerificationResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Twitter {
+		t.Errorf("got %s", rec.Body)
+	}
+	want := []string{"/staging/oip/o5/record/get/" + verifiedClaim, "/staging/oip/o5/record/get/" + fixturePublisher}
+	if !slices.Equal(paths, want) {
+		t.Errorf("got requests for %v, want %v", paths, want)
+	}
+}
+
+func TestCheckRepresentations(t *testing.T) {
+	fakeUpstreams(t)
+	tests := []struct {
+		claim, query, accept string
+		code                 int
+		contentType, body    string
+	}{
+		{verifiedClaim, "", "", http.StatusOK, "application/json", `"verified":true`},
+		{verifiedClaim, "", "application/json", http.StatusOK, "application/json", `"verified":true`},
+		{verifiedClaim, "", "*/*", http.StatusOK, "application/json", `"verified":true`},
+		{verifiedClaim, "", "text/plain", http.StatusOK, "text/plain; charset=utf-8", "verified\n"},
+		{verifiedClaim, "", "text/*", http.StatusOK, "text/plain; charset=utf-8", "verified\n"},
+		{verifiedClaim, "", "application/json;q=0.5, text/plain", http.StatusOK, "text/plain; charset=utf-8", "verified\n"},
+		{verifiedClaim, "", "text/html, */*;q=0.1", http.StatusOK, "application/json", `"verified":true`},
+		{badFormatClaim, "", "text/plain", http.StatusOK, "text/plain; charset=utf-8", "unverified: "},
+		{verifiedClaim, "?format=short", "", http.StatusOK, "text/plain; charset=utf-8", "true\n"},
+		{badFormatClaim, "?format=short", "", http.StatusNotFound, "text/plain; charset=utf-8", "false\n"},
+		{verifiedClaim, "?format=short", "text/html", http.StatusOK, "text/plain; charset=utf-8", "true\n"},
+		{verifiedClaim, "", "text/html", http.StatusNotAcceptable, "application/json", verifier.CodeNotAcceptable},
+		{verifiedClaim, "", "application/json;q=0, text/plain;q=0", http.StatusNotAcceptable, "application/json", verifier.CodeNotAcceptable},
+		{verifiedClaim, "", "application/xml", http.StatusNotAcceptable, "application/json", verifier.CodeNotAcceptable},
+	}
+	for _, tt := range tests {
+		rec := get("/verified/publisher/check/"+tt.claim+tt.query, "Accept", tt.accept)
+		what := fmt.Sprintf("%s%s with Accept %q", tt.claim[:8], tt.query, tt.accept)
+		if rec.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", what, rec.Code, tt.code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: got Content-Type %q, want %q", what, ct, tt.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: got body %q, want it to contain %q", what, rec.Body, tt.body)
+		}
+		if !slices.Contains(rec.Header().Values("Vary"), "Accept") {
+			t.Errorf("%s: got Vary %q, want Accept", what, rec.Header().Values("Vary"))
+		}
+	}
+}
+
+func TestCheckETag(t *testing.T) {
+	ct := fakeUpstreams(t)
+	path := "/verified/publisher/check/" + verifiedClaim
+	claimURL := fixtureOipApi + "/o5/record/get/" + verifiedClaim
+
+	rec := get(path)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("got %d with ETag %q", rec.Code, etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=600" {
+		t.Errorf("got Cache-Control %q", cc)
+	}
+	if text := get(path, "Accept", "text/plain"); text.Header().Get("ETag") == etag {
+		t.Error("the text/plain representation has the ETag of the JSON")
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec = get(path, "If-None-Match", inm)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: got %d with %d bytes, want an empty 304", inm, rec.Code, rec.Body.Len())
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: got ETag %q on the 304", inm, rec.Header().Get("ETag"))
+		}
+	}
+	for _, inm := range []string{`"other"`, strings.TrimSuffix(etag, `"`) + `0"`} {
+		rec = get(path, "If-None-Match", inm)
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("If-None-Match %s: got %d with %d bytes, want the result", inm, rec.Code, rec.Body.Len())
+		}
+	}
+	if n := ct.count(claimURL); n != 1 {
+		t.Errorf("got %d claim lookups before refreshing, want 1", n)
+	}
+
+	rec = get(path+"?refresh=true", "If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("refresh: got %d with %d bytes, want the result", rec.Code, rec.Body.Len())
+	}
+	if rec.Header().Get("ETag") != etag {
+		t.Errorf("refresh: got ETag %q, want the unchanged %q", rec.Header().Get("ETag"), etag)
+	}
+	if n := ct.count(claimURL); n != 2 {
+		t.Errorf("got %d claim lookups after refreshing, want 2", n)
+	}
+}
+
+func TestAdhoc(t *testing.T) {
+	fakeUpstreams(t)
+	tests := []struct {
+		query    string
+		code     int
+		verified bool
+		resCode  string
+		platform map[string]string
+	}{
+		{"publisher=" + fixturePublisher + "&tweet=1724567800000000001", http.StatusOK, true, verifier.CodeOK, map[string]string{"twitter": verifier.CodeOK}},
+		{"publisher=" + fixturePublisher + "&tweet=https://twitter.com/examplepub/status/1724567800000000001", http.StatusOK, true, verifier.CodeOK, map[string]string{"twitter": verifier.CodeOK}},
+		{"publisher=" + fixturePublisher + "&twitter=1724567800000000001&gab=111412345678901234", http.StatusOK, true, verifier.CodeOK, map[string]string{"twitter": verifier.CodeOK, "gab": verifier.CodeOK}},
+		{"publisher=" + fixturePublisher + "&tweet=1724567800000000002", http.StatusOK, false, "", map[string]string{"twitter": verifier.CodeBadFormat}},
+		{"publisher=" + missingPublisher + "&tweet=1724567800000000001", http.StatusOK, false, "", map[string]string{"twitter": verifier.CodePublisherMismatch}},
+		{"publisher=" + fixturePublisher, http.StatusBadRequest, false, verifier.CodeBadRequest, nil},
+		{"publisher=" + fixturePublisher + "&unknown=1", http.StatusBadRequest, false, verifier.CodeBadRequest, nil},
+		{"publisher=xyz&tweet=1724567800000000001", http.StatusBadRequest, false, verifier.CodeInvalidId, nil},
+		{"tweet=1724567800000000001", http.StatusBadRequest, false, verifier.CodeInvalidId, nil},
+	}
+	for _, tt := range tests {
+		rec := get("/verified/adhoc?" + tt.query)
+		if rec.Code != tt.code {
+			t.Errorf("%s: got %d %s, want %d", tt.query, rec.Code, rec.Body, tt.code)
+			continue
+		}
+		// error responses have the code too
+		var res verifier.VerificationResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if res.Verified != tt.verified {
+			t.Errorf("%s: got verified %v, want %v", tt.query, res.Verified, tt.verified)
+		}
+		if tt.resCode != "" && res.Code != tt.resCode {
+			t.Errorf("%s: got %s", tt.query, rec.Body)
+		}
+		for name, code := range tt.platform {
+			if ps := res.Platforms[name]; ps == nil || ps.Code != code {
+				t.Errorf("%s: got %s status %+v, want %s", tt.query, name, ps, code)
+			}
+		}
+		if len(res.Platforms) != len(tt.platform) {
+			t.Errorf("%s: got statuses for %d platforms, want %d", tt.query, len(res.Platforms), len(tt.platform))
+		}
+	}
+}
+
+func TestOutboundProxy(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		// requests through an http proxy ask for the absolute URL
+		agents[r.URL.String()] = r.Header.Get("User-Agent")
+		mu.Unlock()
+		fmt.Fprint(w, `{"count": 0, "total": 0, "results": []}`)
+	}))
+	defer proxy.Close()
+
+	bad := [][]string{
+		{"-http-proxy", "ftp://proxy.example.com"},
+		{"-http-proxy", "http://"},
+		{"-platform-user-agents", "oip-verifier/2"},
+		{"-platform-user-agents", "myspace=oip-verifier/2"},
+	}
+	for _, args := range bad {
+		if err := setupFlags(t, args...); err == nil {
+			t.Errorf("setup accepted %q", args)
+		}
+	}
+
+	err := setupFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(verify.UserAgent, "oip-verifier/") {
+		t.Errorf("default User-Agent is %q", verify.UserAgent)
+	}
+
+	err = setupFlags(t, "-http-proxy", proxy.URL, "-oip-api", "http://oip.example.com/oip",
+		"-user-agent", "test-agent/1", "-platform-user-agents", "oip=oip-agent/2 (+https://example.com, contact)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := httpClient.Get("http://health.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	verify.CheckClaim(context.Background(), verifiedClaim)
+	want := map[string]string{
+		"http://health.example.com/":                                "test-agent/1",
+		"http://oip.example.com/oip/o5/record/get/" + verifiedClaim: "oip-agent/2 (+https://example.com, contact)",
+	}
+	for u, ua := range want {
+		got, ok := agents[u]
+		if !ok {
+			t.Errorf("proxy got no request for %s, got %v", u, agents)
+		} else if got != ua {
+			t.Errorf("request for %s had User-Agent %q, want %q", u, got, ua)
+		}
+	}
+
+	// without -http-proxy, requests go through the proxy of the environment,
+	// while those still under way on the transport with -http-proxy keep it
+	proxied := upstream.base()
+	err = setupFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://health.example.com/", nil)
+	u, err := upstream.base().Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, _ := http.ProxyFromEnvironment(req)
+	if fmt.Sprint(u) != fmt.Sprint(env) {
+		t.Errorf("proxy is %v, want %v from the environment", u, env)
+	}
+	if u, _ := proxied.Proxy(req); fmt.Sprint(u) != proxy.URL {
+		t.Errorf("setup changed the proxy of the transport it replaced to %v", u)
+	}
+	conn, err := proxied.DialContext(context.Background(), "tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("setup changed the dialer of the transport it replaced: %v", err)
+	}
+	conn.Close()
+}
+
+func TestSetupSwapsTransport(t *testing.T) {
+	err := setupFlags(t, "-oip-max-concurrent", "8", "-twitter-max-concurrent", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := upstream.base()
+	if first.MaxIdleConnsPerHost != 8 || first.MaxConnsPerHost != 8 {
+		t.Errorf("got %d idle and %d connections per host, want 8", first.MaxIdleConnsPerHost, first.MaxConnsPerHost)
+	}
+
+	// requests keep being sent while setup runs again
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/", nil)
+			if res, err := upstream.RoundTrip(req); err == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+	err = setupFlags(t, "-oip-max-concurrent", "2", "-twitter-max-concurrent", "0")
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := upstream.base()
+	if second == first {
+		t.Fatal("setup kept the transport")
+	}
+	if first.MaxIdleConnsPerHost != 8 || first.MaxConnsPerHost != 8 {
+		t.Errorf("setup changed the transport it replaced to %d idle and %d connections per host", first.MaxIdleConnsPerHost, first.MaxConnsPerHost)
+	}
+	if second.MaxIdleConnsPerHost != 2 || second.MaxConnsPerHost != 0 {
+		t.Errorf("got %d idle and %d connections per host, want 2 idle and no limit", second.MaxIdleConnsPerHost, second.MaxConnsPerHost)
+	}
+}
+
+func TestParseUserAgents(t *testing.T) {
+	got, err := parseUserAgents(" reddit=oip-verifier/2 (+https://example.com, contact),oip=oip-verifier/2, ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"reddit": "oip-verifier/2 (+https://example.com, contact)", "oip": "oip-verifier/2"}
+	if !maps.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	checkOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "platform_checks_total",
+		Help:      "Platform verifications performed, by platform and outcome.",
+	}, []string{"platform", "outcome"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "verifier",
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Duration of outbound requests, by upstream target.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"target"})
+
+	rateLimitRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "upstream_rate_limit_remaining",
+		Help:      "Requests remaining in the current upstream rate limit window, by upstream target.",
+	}, []string{"target"})
+
+	twitterLimiterTokens = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "twitter_limiter_tokens",
+		Help:      "Tweet lookups the local Twitter rate limiter currently allows.",
+	}, func() float64 {
+		if verify == nil || verify.TwitterLimiter == nil {
+			return 0
+		}
+		return verify.TwitterLimiter.Tokens()
+	})
+
+	throttledRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "throttled_requests_total",
+		Help:      "Requests rejected by the per-IP rate limit, by client network (/24 or /48).",
+	}, []string{"prefix"})
+
+	checksInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "checks_in_flight",
+		Help:      "Claim checks currently being evaluated.",
+	})
+
+	reverifyLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "reverify_last_run_timestamp_seconds",
+		Help:      "When the last pass of claim re-verification started.",
+	})
+
+	reverifyChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "reverify_checks_total",
+		Help:      "Claims re-verified, by check code.",
+	}, []string{"code"})
+
+	reverifyTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "reverify_transitions_total",
+		Help:      "Re-verified claims whose verification changed, by whether they now verify.",
+	}, []string{"verified"})
+
+	authFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "auth_failures_total",
+		Help:      "Requests to protected endpoints without a valid api key, by client network (/24 or /48).",
+	}, []string{"prefix"})
+
+	panics = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "panics_total",
+		Help:      "Requests whose handler panicked.",
+	})
+
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "upstream_breaker_transitions_total",
+		Help:      "Circuit breaker state changes of upstream hosts, by upstream target and new state.",
+	}, []string{"target", "state"})
+
+	openBreakers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "upstream_breakers_open",
+		Help:      "Upstream hosts whose circuit breaker is open or half-open, by upstream target.",
+	}, []string{"target"})
+
+	upstreamInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "upstream_requests_in_flight",
+		Help:      "Requests in flight to upstream targets with a concurrency limit, by target.",
+	}, []string{"target"})
+
+	upstreamWaiting = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "verifier",
+		Name:      "upstream_requests_waiting",
+		Help:      "Requests waiting for the concurrency limit of their upstream target, by target.",
+	}, []string{"target"})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "verifier",
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook deliveries, by outcome: delivered, failed or dropped.",
+	}, []string{"outcome"})
+)
+
+var metricsHooks = verifier.Hooks{
+	Outcome: func(platform, outcome string) {
+		checkOutcomes.WithLabelValues(platform, outcome).Inc()
+	},
+	Upstream: func(target string, duration time.Duration) {
+		upstreamDuration.WithLabelValues(target).Observe(duration.Seconds())
+	},
+	RateLimit: func(target string, remaining int) {
+		rateLimitRemaining.WithLabelValues(target).Set(float64(remaining))
+	},
+	Breaker: func(target, host, state string) {
+		breakerTransitions.WithLabelValues(target, state).Inc()
+		open := 0
+		for _, b := range verify.OpenBreakers() {
+			if b.Target == target {
+				open++
+			}
+		}
+		openBreakers.WithLabelValues(target).Set(float64(open))
+	},
+	Concurrency: func(target string, inUse, waiting int) {
+		upstreamInUse.WithLabelValues(target).Set(float64(inUse))
+		upstreamWaiting.WithLabelValues(target).Set(float64(waiting))
+	},
+}
+
+// ServeMetrics exposes /metrics on its own listener, or on the http api
+// router when listen is empty. With -pprof-on-metrics the diagnostics are
+// served on its listener too.
+func ServeMetrics(listen string) error {
+	if listen == "" {
+		router.Handle("/metrics", promhttp.Handler())
+		return nil
+	}
+
+	ln, err := Listen(listen)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if enablePprof && pprofOnMetrics {
+		mux.Handle("/verified/admin/", diagnosticsHandler())
+	}
+	go func() {
+		slog.Info("Serving metrics", "listen", ln.Addr().String())
+		err := http.Serve(ln, mux)
+		if err != nil {
+			slog.Error("Error serving metrics", "err", err, "listen", listen)
+		}
+	}()
+	return nil
+}
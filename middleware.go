@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+type requestIDKey struct{}
+
+// withRequestID gives each request an id, the client's X-Request-ID if it
+// sent one, and echoes it in the response's X-Request-ID. The request's
+// logger, from requestLog, carries the id.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			b := make([]byte, 8)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = verifier.WithLogger(ctx, slog.Default().With("request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestID returns the id withRequestID gave the request of ctx.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// recoverPanics responds 500 to requests whose handler panics, instead of
+// dropping the connection, and logs the stack. http.ErrAbortHandler is
+// re-raised so that net/http still aborts the response.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			panics.Inc()
+			requestLog(r).Error("Panic serving request", "err", err, "path", r.URL.Path, "stack", string(debug.Stack()))
+			RespondJSON(w, http.StatusInternalServerError, ErrorResponse{Code: verifier.CodeInternal, Msg: "internal server error"})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accessLog enables logAccess.
+var accessLog bool
+
+type accessInfoKey struct{}
+
+// accessInfo is what handlers add to a request's access log line.
+type accessInfo struct {
+	keyLabel string
+}
+
+// setKeyLabel records the label of the api key a request was made with.
+func setKeyLabel(ctx context.Context, label string) {
+	if info, ok := ctx.Value(accessInfoKey{}).(*accessInfo); ok {
+		info.keyLabel = label
+	}
+}
+
+// statusWriter records the status of a response. It still flushes, for
+// /verified/stream.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logAccess logs each request once it has been served, when accessLog is
+// set.
+func logAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !accessLog {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		info := &accessInfo{}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), accessInfoKey{}, info)))
+
+		attrs := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"duration", time.Since(start).String(),
+		}
+		if ip := clientIP(r); ip != nil {
+			attrs = append(attrs, "ip", ip.String())
+		}
+		if info.keyLabel != "" {
+			attrs = append(attrs, "key", info.keyLabel)
+		}
+		requestLog(r).Info("Request", attrs...)
+	})
+}
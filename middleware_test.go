@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/oipwg/verifier/verifier"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	r.HandleFunc("/abort", func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })
+	r.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { RespondText(w, http.StatusOK, "ok") })
+	ts := httptest.NewServer(withRequestID(recoverPanics(r)))
+	defer ts.Close()
+
+	for i := 0; i < 3; i++ {
+		res, err := http.Get(ts.URL + "/panic")
+		if err != nil {
+			t.Fatalf("panic %d: %v", i, err)
+		}
+		var e ErrorResponse
+		err = json.NewDecoder(res.Body).Decode(&e)
+		res.Body.Close()
+		if res.StatusCode != http.StatusInternalServerError || err != nil || e.Code != verifier.CodeInternal {
+			t.Errorf("panic %d: got %d %+v, %v, want a JSON 500", i, res.StatusCode, e, err)
+		}
+		if res.Header.Get("X-Request-ID") == "" {
+			t.Errorf("panic %d: no X-Request-ID", i)
+		}
+
+		// the connection that panicked is reused, and the server still serves
+		res, err = http.Get(ts.URL + "/ok")
+		if err != nil {
+			t.Fatalf("after panic %d: %v", i, err)
+		}
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || string(b) != "ok\n" {
+			t.Errorf("after panic %d: got %d %q", i, res.StatusCode, b)
+		}
+	}
+
+	if res, err := http.Get(ts.URL + "/abort"); err == nil {
+		res.Body.Close()
+		t.Errorf("ErrAbortHandler: got %d, want the response aborted", res.StatusCode)
+	}
+	res, err := http.Get(ts.URL + "/ok")
+	if err != nil {
+		t.Fatalf("after ErrAbortHandler: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("after ErrAbortHandler: got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate picks the media type of offers, in order of preference, that
+// the request's Accept header ranks highest. A request without an Accept
+// header gets the first offer. It returns false when none is acceptable.
+func negotiate(r *http.Request, offers ...string) (string, bool) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := acceptQuality(accept, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// acceptQuality returns the q value the Accept header gives offer, using
+// the most specific matching media range.
+func acceptQuality(accept, offer string) float64 {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		s := matchMediaRange(mediaType, offer)
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				q = 0
+			}
+		}
+	}
+	return q
+}
+
+// matchMediaRange returns how specifically the media range matches offer:
+// 2 for an exact match, 1 for type/*, 0 for */*, and -1 for no match.
+func matchMediaRange(mediaRange, offer string) int {
+	switch {
+	case mediaRange == offer:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
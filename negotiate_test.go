@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/plain"}
+	tests := []struct {
+		accept, want string
+		ok           bool
+	}{
+		{"", "application/json", true},
+		{"*/*", "application/json", true},
+		{"application/json", "application/json", true},
+		{"text/plain", "text/plain", true},
+		{"text/*", "text/plain", true},
+		{"application/*", "application/json", true},
+		{"text/plain, application/json", "application/json", true},
+		{"application/json;q=0.4, text/plain;q=0.8", "text/plain", true},
+		{"text/plain;q=0, */*", "application/json", true},
+		{"*/*;q=0.1, text/plain;q=0", "application/json", true},
+		{"text/html, application/xhtml+xml", "", false},
+		{"text/plain;q=0", "", false},
+		{"*/*;q=0", "", false},
+		{"not a media type", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		got, ok := negotiate(r, offers...)
+		if got != tt.want && tt.ok || ok != tt.ok {
+			t.Errorf("Accept %q: got %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+func init() {
+	rootRouter.HandleFunc("/openapi.json", handleOpenAPI).Methods(http.MethodGet)
+}
+
+type object = map[string]interface{}
+
+// openAPISchemas builds JSON schemas for Go types from their json struct
+// tags, so that the published document can't drift from the responses.
+type openAPISchemas struct {
+	components object
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref returns the schema of v's type, adding named structs to the
+// components.
+func (s *openAPISchemas) ref(v interface{}) object {
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *openAPISchemas) schema(t reflect.Type) object {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return object{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return object{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return object{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return object{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return object{"type": "array", "items": s.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return object{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		if _, ok := s.components[t.Name()]; !ok {
+			// placeholder, for types that refer to themselves
+			s.components[t.Name()] = object{}
+			s.components[t.Name()] = s.structSchema(t)
+		}
+		return object{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return object{}
+}
+
+func (s *openAPISchemas) structSchema(t reflect.Type) object {
+	props := object{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := object{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func jsonContent(schema object) object {
+	return object{"application/json": object{"schema": schema}}
+}
+
+func txidParam(name, description string) object {
+	return object{"name": name, "in": "path", "required": true, "description": description,
+		"schema": object{"type": "string", "pattern": "^[a-f0-9]{64}$"}}
+}
+
+func queryParam(name, description string, schema object) object {
+	return object{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+// openAPIDocument describes the http api.
+func openAPIDocument() object {
+	s := &openAPISchemas{components: object{}}
+	errorResponse := object{"description": "Error", "content": jsonContent(s.ref(ErrorResponse{}))}
+	refresh := queryParam("refresh", "Bypass cached lookups", object{"type": "boolean"})
+	claimID := txidParam("id", "Verification claim txid")
+
+	check := func(result object) object {
+		return object{"get": object{
+			"summary": "Check a verification claim",
+			"parameters": []object{claimID, refresh,
+				queryParam("format", "short responds just true or false, with a 404 for false", object{"type": "string", "enum": []string{"short"}})},
+			"responses": object{
+				"200": object{"description": "The check; see code and verified", "content": object{
+					"application/json": object{"schema": result},
+					"text/plain":       object{"schema": object{"type": "string"}},
+				}},
+				"304":     object{"description": "Not modified since the If-None-Match ETag"},
+				"404":     object{"description": "Claim not found", "content": jsonContent(result)},
+				"406":     errorResponse,
+				"429":     object{"description": "Rate limited upstream; see Retry-After", "content": jsonContent(result)},
+				"502":     object{"description": "Upstream error", "content": jsonContent(result)},
+				"default": errorResponse,
+			},
+		}}
+	}
+	batch := func(result object) object {
+		return object{"post": object{
+			"summary":     "Check several verification claims",
+			"parameters":  []object{refresh},
+			"requestBody": object{"required": true, "content": jsonContent(object{"type": "array", "items": object{"type": "string"}, "maxItems": batchMaxIds})},
+			"responses": object{
+				"200": object{"description": "The checks, by claim txid", "content": jsonContent(object{"type": "object", "additionalProperties": result})},
+				"400": errorResponse,
+				"413": errorResponse,
+			},
+		}}
+	}
+	debug := func(result object) object {
+		return object{"get": object{
+			"summary":     "Check a verification claim, showing the fetched posts",
+			"description": "Only served with serve -enable-debug. Always goes to the network.",
+			"parameters":  []object{claimID},
+			"responses":   object{"200": object{"description": "The check, with debug for each platform", "content": jsonContent(result)}},
+		}}
+	}
+	legacy, v1 := s.ref(verifier.VerificationResponse{}), s.ref(verifier.Result{})
+
+	paths := object{
+		"/verified/publisher/check/{id}":    check(legacy),
+		"/verified/v1/publisher/check/{id}": check(v1),
+		"/verified/publisher/check":         batch(legacy),
+		"/verified/v1/publisher/check":      batch(v1),
+		"/verified/publisher/debug/{id}":    debug(legacy),
+		"/verified/v1/publisher/debug/{id}": debug(v1),
+		"/verified/publisher/badge/{id}.svg": object{"get": object{
+			"summary": "Render a verification badge",
+			"parameters": []object{claimID,
+				queryParam("label", "Left hand text, OIP by default", object{"type": "string"}),
+				queryParam("style", "Badge style", object{"type": "string", "enum": []string{"flat", "plastic"}})},
+			"responses": object{
+				"200": object{"description": "The badge", "content": object{"image/svg+xml": object{"schema": object{"type": "string"}}}},
+				"400": errorResponse,
+			},
+		}},
+		"/verified/platforms": object{"get": object{
+			"summary":   "List the platforms claims are verified on",
+			"responses": object{"200": object{"description": "Enabled and disabled platforms", "content": jsonContent(s.ref(PlatformsResponse{}))}},
+		}},
+		"/verified/health": object{"get": object{
+			"summary": "Report the reachability of upstreams",
+			"responses": object{
+				"200": object{"description": "ok or degraded", "content": jsonContent(s.ref(HealthResponse{}))},
+				"503": object{"description": "A required upstream is failing", "content": jsonContent(s.ref(HealthResponse{}))},
+			},
+		}},
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "OIP publisher verifier",
+			"description": "Checks that OIP publishers control the social media accounts their verification claims name.",
+			"version":     "1",
+		},
+		"paths":      paths,
+		"components": object{"schemas": s.components},
+	}
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, http.StatusOK, openAPIDocument())
+}
+
+// swaggerUI is a page that renders /verified/openapi.json with Swagger UI.
+const swaggerUI = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>OIP publisher verifier api</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/verified/openapi.json", dom_id: "#swagger-ui"})</script>
+</body>
+</html>
+`
+
+func handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(swaggerUI))
+}
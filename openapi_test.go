@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// validate checks v, decoded JSON, against the subset of JSON schema that
+// openAPISchemas produces, returning the first mismatch.
+func validate(doc, schema object, v interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		s, ok := doc["components"].(object)["schemas"].(object)[name].(object)
+		if !ok {
+			return fmt.Errorf("%s: dangling $ref %s", at, ref)
+		}
+		return validate(doc, s, v, at)
+	}
+	switch schema["type"] {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: got %T, want a string", at, v)
+		}
+		if p, ok := schema["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(s) {
+			return fmt.Errorf("%s: %q doesn't match %s", at, s, p)
+		}
+		if enum, ok := schema["enum"].([]string); ok && !slices.Contains(enum, s) {
+			return fmt.Errorf("%s: %q isn't one of %v", at, s, enum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: got %T, want a boolean", at, v)
+		}
+	case "integer":
+		f, ok := v.(float64)
+		if !ok || f != float64(int64(f)) {
+			return fmt.Errorf("%s: got %v, want an integer", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: got %T, want a number", at, v)
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an array", at, v)
+		}
+		for i, e := range a {
+			if err := validate(doc, schema["items"].(object), e, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an object", at, v)
+		}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := o[name]; !ok {
+				return fmt.Errorf("%s: missing required %s", at, name)
+			}
+		}
+		props, _ := schema["properties"].(object)
+		extra, _ := schema["additionalProperties"].(object)
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s, ok := props[k].(object)
+			if !ok {
+				s = extra
+			}
+			if s == nil {
+				return fmt.Errorf("%s: unexpected property %s", at, k)
+			}
+			if err := validate(doc, s, o[k], at+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// responseSchema returns the JSON schema of the response to a GET of path
+// with code.
+func responseSchema(t *testing.T, doc object, path, code string) object {
+	t.Helper()
+	op := doc["paths"].(object)[path].(object)["get"].(object)
+	res, ok := op["responses"].(object)[code].(object)
+	if !ok {
+		t.Fatalf("%s has no %s response", path, code)
+	}
+	return res["content"].(object)["application/json"].(object)["schema"].(object)
+}
+
+func TestOpenAPIExamples(t *testing.T) {
+	doc := openAPIDocument()
+	// the document must survive a round trip through JSON as served
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob("testdata/golden/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples in testdata/golden: %v", err)
+	}
+	codes := map[string]string{"claim-not-found": "404", "rate-limited": "429"}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		example, version := name[:strings.LastIndex(name, ".")], name[strings.LastIndex(name, ".")+1:]
+		path := "/verified/publisher/check/{id}"
+		if version == "v1" {
+			path = "/verified/v1/publisher/check/{id}"
+		}
+		code := codes[example]
+		if code == "" {
+			code = "200"
+		}
+
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v interface{}
+		err = json.Unmarshal(b, &v)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if err := validate(doc, responseSchema(t, doc, path, code), v, name); err != nil {
+			t.Errorf("%s against %s %s: %v", file, path, code, err)
+		}
+	}
+
+	fakeUpstreams(t)
+	var platforms interface{}
+	err = json.Unmarshal(get("/verified/platforms").Body.Bytes(), &platforms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validate(doc, responseSchema(t, doc, "/verified/platforms", "200"), platforms, "platforms"); err != nil {
+		t.Errorf("/verified/platforms: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := openAPIDocument()
+	schema := responseSchema(t, doc, "/verified/v1/publisher/check/{id}", "200")
+	for _, bad := range []string{
+		`{"verified": true}`,
+		`{"code": "OK", "verified": "yes"}`,
+		`{"code": "OK", "verified": true, "surprise": 1}`,
+		`{"code": "OK", "verified": true, "platforms": {"twitter": {"verified": 1}}}`,
+	} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(bad), &v); err != nil {
+			t.Fatal(err)
+		}
+		if err := validate(doc, schema, v, "bad"); err == nil {
+			t.Errorf("%s validated", bad)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+	"golang.org/x/time/rate"
+)
+
+// ipLimiter limits the request rate of each client with its own token
+// bucket, keying IPv4 clients by address and IPv6 clients by /64, which a
+// single host is commonly given whole. Buckets idle for longer than idle are
+// evicted.
+type ipLimiter struct {
+	rate  rate.Limit
+	burst int
+	idle  time.Duration
+
+	// requests from exempt are never limited
+	exempt []*net.IPNet
+
+	mu      sync.Mutex
+	clients map[string]*ipClient
+}
+
+type ipClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPLimiter(r rate.Limit, burst int, idle time.Duration, exempt []*net.IPNet) *ipLimiter {
+	return &ipLimiter{
+		rate:    r,
+		burst:   burst,
+		idle:    idle,
+		exempt:  exempt,
+		clients: make(map[string]*ipClient),
+	}
+}
+
+// Middleware rejects requests from clients over their rate with a 429.
+func (l *ipLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if ip == nil || containsIP(l.exempt, ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res := l.limiter(clientKey(ip)).Reserve()
+		if d := res.Delay(); d > 0 {
+			res.Cancel()
+			throttledRequests.WithLabelValues(ipPrefix(ip)).Inc()
+			retryAfter := int(math.Ceil(d.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			RespondJSON(w, http.StatusTooManyRequests, ErrorResponse{
+				Code: verifier.CodeRateLimited,
+				Msg:  "Too many requests, retry after " + strconv.Itoa(retryAfter) + " seconds",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ipLimiter) limiter(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.clients[key]
+	if !ok {
+		c = &ipClient{limiter: rate.NewLimiter(l.rate, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = time.Now()
+	return c.limiter
+}
+
+// set changes the rate and burst of every client, for reloaded config.
+func (l *ipLimiter) set(r rate.Limit, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst = r, burst
+	for _, c := range l.clients {
+		c.limiter.SetLimit(r)
+		c.limiter.SetBurst(burst)
+	}
+}
+
+// evictIdle periodically drops the buckets of clients not seen for idle. It
+// never returns.
+func (l *ipLimiter) evictIdle() {
+	for range time.Tick(l.idle) {
+		cutoff := time.Now().Add(-l.idle)
+		l.mu.Lock()
+		for key, c := range l.clients {
+			if c.lastSeen.Before(cutoff) {
+				delete(l.clients, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// trustedProxies are the proxies whose X-Forwarded-For and X-Real-IP
+// headers are believed.
+var trustedProxies []*net.IPNet
+
+// clientIP returns the address of the client that made r. When r came from
+// a trusted proxy, the entries of every X-Forwarded-For header are walked
+// back from the last, which the nearest proxy added, to the first untrusted
+// address.
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(trustedProxies, ip) {
+		return ip
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		var hops []string
+		for _, h := range xff {
+			hops = append(hops, strings.Split(h, ",")...)
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			ip = hop
+			if !containsIP(trustedProxies, hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+		return real
+	}
+	return ip
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientKey returns the key of the rate limit bucket of ip: the address of
+// IPv4 clients, the /64 network of IPv6 ones.
+func clientKey(ip net.IP) string {
+	if ip.To4() != nil {
+		return ip.String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
+// ipPrefix returns the /24 (IPv4) or /48 (IPv6) network of ip, which keeps
+// the throttled requests metric's cardinality manageable.
+func ipPrefix(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// parseCIDRs parses a comma separated list of CIDRs or bare IPs.
+func parseCIDRs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: s}
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestClientIP(t *testing.T) {
+	old := trustedProxies
+	t.Cleanup(func() { trustedProxies = old })
+	var err error
+	trustedProxies, err = parseCIDRs("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted proxy", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"one header", "10.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"spoofed first entry", "10.0.0.1:1234", []string{"192.0.2.9, 198.51.100.1"}, "198.51.100.1"},
+		{"several headers", "10.0.0.1:1234", []string{"192.0.2.9", "198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"last header trusted", "10.0.0.1:1234", []string{"198.51.100.1", "10.0.0.2"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, h := range tt.xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		if ip := clientIP(r); ip.String() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, ip, tt.want)
+		}
+	}
+}
+
+func TestIPLimiterIPv6Prefix(t *testing.T) {
+	l := newIPLimiter(rate.Every(time.Hour), 1, 0, nil)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(remote string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = net.JoinHostPort(remote, "1234")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	for _, remote := range []string{"2001:db8:1:2::1", "2001:db8:1:3::1", "192.0.2.1", "192.0.2.2"} {
+		if code := serve(remote); code != http.StatusOK {
+			t.Errorf("first request from %s: got %d", remote, code)
+		}
+	}
+	// another address of the same /64 shares its bucket
+	if code := serve("2001:db8:1:2:ffff::1"); code != http.StatusTooManyRequests {
+		t.Errorf("second request from 2001:db8:1:2::/64: got %d, want 429", code)
+	}
+}
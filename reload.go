@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+	"github.com/rs/cors"
+	"golang.org/x/time/rate"
+)
+
+// reloadable are the flags that serve applies from its -config file on
+// SIGHUP. Changes to the others are logged as needing a restart.
+var reloadable = map[string]bool{
+	"cache-ttl":           true,
+	"cache-negative-ttl":  true,
+	"templates":           true,
+	"twitter-rate-limit":  true,
+	"twitter-rate-window": true,
+	"twitter-rate-burst":  true,
+	"ip-rate":             true,
+	"ip-burst":            true,
+	"webhook-url":         true,
+	"cors-origins":        true,
+	"cors-headers":        true,
+	"cors-expose":         true,
+	"cors-max-age":        true,
+	"allow-all-origins":   true,
+	"log-level":           true,
+}
+
+// configExplicit are the flags given on the command line or in the
+// environment, which the config file never overrides.
+var configExplicit map[string]bool
+
+// reloader reloads the config file flags were read from into the settings
+// a server runs with.
+type reloader struct {
+	flags *flag.FlagSet
+	// file is the config file, explicit the flags it doesn't override.
+	file     string
+	explicit map[string]bool
+	verify   *verifier.Verifier
+	// limiter is nil without a per-client rate limit, and webhooks without
+	// -db to compare checks against.
+	limiter  *ipLimiter
+	webhooks *atomic.Value // []string
+	level    *slog.LevelVar
+	cors     *atomic.Value // *cors.Cors
+}
+
+// newReloader returns the reloader of the -config file flags were read from
+// into the settings of the running server.
+func newReloader(flags *flag.FlagSet, limiter *ipLimiter) *reloader {
+	r := &reloader{flags: flags, file: configFile, explicit: configExplicit, verify: verify, limiter: limiter, level: logLevel, cors: &corsPolicy}
+	if history != nil {
+		r.webhooks = &webhookTargets
+	}
+	return r
+}
+
+// canReload reports whether a change to the named flag can be applied
+// without a restart. Limits that were disabled at startup, and webhooks
+// without -db, have nothing running to apply them to.
+func (r *reloader) canReload(name string) bool {
+	switch name {
+	case "twitter-rate-limit", "twitter-rate-window", "twitter-rate-burst":
+		return r.verify.TwitterLimiter != nil
+	case "ip-rate", "ip-burst":
+		return r.limiter != nil
+	case "webhook-url":
+		return r.webhooks != nil
+	}
+	return reloadable[name]
+}
+
+// items returns the values f is Set with to take on value, one per item for
+// repeatable flags.
+func items(f *flag.Flag, value string) []string {
+	if _, ok := f.Value.(*stringsFlag); ok {
+		return splitList(value)
+	}
+	return []string{value}
+}
+
+// normalize returns what f would print after being Set with items, without
+// changing f, so that e.g. 5m and 5m0s compare equal.
+func normalize(f *flag.Flag, path string, items []string) (string, error) {
+	v := reflect.New(reflect.TypeOf(f.Value).Elem()).Interface().(flag.Value)
+	for _, item := range items {
+		err := v.Set(item)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %q in config file %s: %v", f.Name, path, err)
+		}
+	}
+	return v.String(), nil
+}
+
+// setItems sets f to items, replacing the values of repeatable flags.
+func setItems(f *flag.Flag, items []string) error {
+	if list, ok := f.Value.(*stringsFlag); ok {
+		*list = nil
+	}
+	for _, item := range items {
+		err := f.Value.Set(item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reload rereads the config file and applies the reloadable flags that
+// changed, keys removed from the file going back to their defaults. Either
+// every change is applied or, on error, none.
+func (r *reloader) reload() error {
+	values, err := readConfigFile(r.file)
+	if err != nil {
+		return err
+	}
+	set, err := configValues(r.flags, r.file, values)
+	if err != nil {
+		return err
+	}
+
+	var changed, restart []string
+	previous := make(map[string][]string)
+	r.flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || r.explicit[f.Name] || f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+		if file := r.flags.Lookup(f.Name + "-file"); file != nil && file.Value.String() != "" {
+			// read from its secret file, which reloads on its own
+			return
+		}
+		want, ok := set[f.Name]
+		if !ok {
+			want = items(f, f.DefValue)
+		}
+		var value string
+		value, err = normalize(f, r.file, want)
+		if err != nil || value == f.Value.String() {
+			return
+		}
+		if !r.canReload(f.Name) {
+			restart = append(restart, f.Name)
+			return
+		}
+		previous[f.Name] = items(f, f.Value.String())
+		err = setItems(f, want)
+		changed = append(changed, f.Name)
+	})
+	if err == nil && len(changed) > 0 {
+		err = r.apply()
+	}
+	if err != nil {
+		for name, was := range previous {
+			_ = setItems(r.flags.Lookup(name), was)
+		}
+		return err
+	}
+
+	sort.Strings(changed)
+	sort.Strings(restart)
+	slog.Info("Reloaded config", "file", r.file, "changed", strings.Join(changed, ","), "restart_required", strings.Join(restart, ","))
+	return nil
+}
+
+// apply applies the current values of the reloadable flags. Each setting is
+// swapped in whole, so a request sees either the old or the new one; the
+// verifier pins its settings for the length of a check.
+func (r *reloader) apply() error {
+	get := func(name string) interface{} {
+		return r.flags.Lookup(name).Value.(flag.Getter).Get()
+	}
+	templates := verifier.BuiltinTemplates
+	if path := get("templates").(string); path != "" {
+		var err error
+		templates, err = verifier.LoadTemplates(path)
+		if err != nil {
+			return fmt.Errorf("invalid templates: %v", err)
+		}
+	}
+	level, err := parseLevel(get("log-level").(string))
+	if err != nil {
+		return err
+	}
+	policy := cors.New(newCorsOptions(get("cors-origins").(string), get("cors-headers").(string), get("cors-expose").(string), get("cors-max-age").(time.Duration), get("allow-all-origins").(bool)))
+
+	r.verify.Reconfigure(verifier.Settings{
+		CacheTTL:         get("cache-ttl").(time.Duration),
+		CacheNegativeTTL: get("cache-negative-ttl").(time.Duration),
+		Templates:        templates,
+	})
+	if lim := r.verify.TwitterLimiter; lim != nil {
+		lim.SetLimit(twitterRate(get("twitter-rate-limit").(int), get("twitter-rate-window").(time.Duration)))
+		lim.SetBurst(get("twitter-rate-burst").(int))
+	}
+	if r.limiter != nil {
+		r.limiter.set(rate.Limit(get("ip-rate").(float64)), get("ip-burst").(int))
+	}
+	if r.webhooks != nil {
+		r.webhooks.Store(append([]string(nil), *r.flags.Lookup("webhook-url").Value.(*stringsFlag)...))
+	}
+	r.cors.Store(policy)
+	r.level.Set(level)
+	return nil
+}
+
+// reloadOnHUP reloads the config file whenever the process gets SIGHUP,
+// keeping the current settings if that fails.
+func (r *reloader) reloadOnHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		err := r.reload()
+		if err != nil {
+			slog.Error("Unable to reload config, keeping the current settings", "err", err, "file", r.file)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+// addServeReloadable adds the reloadable flags that runServe adds to flags,
+// with the same defaults.
+func addServeReloadable(flags *flag.FlagSet) {
+	flags.Float64("ip-rate", 1, "")
+	flags.Int("ip-burst", 20, "")
+	flags.Var(new(stringsFlag), "webhook-url", "")
+	flags.String("cors-origins", "", "")
+	flags.String("cors-headers", "Accept,Content-Type,If-None-Match,X-Request-ID", "")
+	flags.String("cors-expose", "ETag,Retry-After,X-Request-ID", "")
+	flags.Duration("cors-max-age", 10*time.Minute, "")
+	flags.Bool("allow-all-origins", false, "")
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verifier.yaml")
+	write := func(config string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(config), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	flags, _ := newFlagSet("test")
+	addServeReloadable(flags)
+	// as parseFlags adds it, without setting up the logger
+	flags.String("log-level", "info", "")
+	err := flags.Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// its own settings, so that nothing else sees them change
+	r := &reloader{
+		flags:    flags,
+		file:     path,
+		verify:   verifier.New(nil, fixtureOipApi, httpClient),
+		webhooks: new(atomic.Value),
+		level:    new(slog.LevelVar),
+		cors:     new(atomic.Value),
+	}
+
+	write("log-level: info\ncache-ttl: 10m\nwebhook-url: [https://hooks.example.com/a]\n")
+	err = r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.level.Level() != slog.LevelInfo {
+		t.Fatalf("got log level %v", r.level.Level())
+	}
+	if urls := r.webhooks.Load().([]string); len(urls) != 1 || urls[0] != "https://hooks.example.com/a" {
+		t.Errorf("got webhooks %q", urls)
+	}
+	if r.cors.Load() == nil {
+		t.Error("no CORS policy")
+	}
+
+	write("log-level: debug\ncache-ttl: 1m\n")
+	err = r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.level.Level() != slog.LevelDebug {
+		t.Errorf("after reloading: got log level %v, want debug", r.level.Level())
+	}
+	if ttl := r.verify.CurrentSettings().CacheTTL; ttl != time.Minute {
+		t.Errorf("after reloading: got cache ttl %v, want 1m", ttl)
+	}
+
+	// one bad value, whether it is invalid or can't be applied, undoes the
+	// changes made before it
+	for _, bad := range []string{
+		"log-level: warn\ncache-ttl: 2m\ncache-negative-ttl: 5s\ntwitter-rate-burst: lots\n",
+		"log-level: warn\ncache-ttl: 2m\ncache-negative-ttl: 5s\ntemplates: " + filepath.Join(t.TempDir(), "missing.json") + "\n",
+		"log-level: loud\ncache-ttl: 2m\n",
+	} {
+		write(bad)
+		err = r.reload()
+		if err == nil {
+			t.Errorf("%q: reloaded", bad)
+		}
+		for name, want := range map[string]string{"log-level": "debug", "cache-ttl": "1m0s", "cache-negative-ttl": "30s", "templates": "", "twitter-rate-burst": flags.Lookup("twitter-rate-burst").DefValue} {
+			if got := flags.Lookup(name).Value.String(); got != want {
+				t.Errorf("%q: left -%s %s, want %s", bad, name, got, want)
+			}
+		}
+		if r.level.Level() != slog.LevelDebug {
+			t.Errorf("%q: got log level %v, want debug", bad, r.level.Level())
+		}
+		s := r.verify.CurrentSettings()
+		if s.CacheTTL != time.Minute || s.CacheNegativeTTL != 30*time.Second {
+			t.Errorf("%q: got cache ttls %v and %v, want 1m and 30s", bad, s.CacheTTL, s.CacheNegativeTTL)
+		}
+	}
+
+	// keys removed from the file go back to their defaults
+	write("cache-ttl: 1m\n")
+	err = r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.level.Level() != slog.LevelInfo {
+		t.Errorf("log level removed: got %v, want info", r.level.Level())
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+var (
+	reverifyInterval time.Duration
+	reverifyWorkers  = 2
+	// reverifySeed are claim txids re-verified besides those in history.
+	reverifySeed []string
+
+	// reverifyFailures is how many checks in a row may fail upstream before
+	// the worker backs off, starting at reverifyMinBackoff and doubling up
+	// to reverifyInterval.
+	reverifyFailures   = 5
+	reverifyMinBackoff = time.Minute
+
+	reverify reverifyState
+)
+
+// reverifyState is the progress of the re-verification worker, reported by
+// /health.
+type reverifyState struct {
+	mu sync.Mutex
+	ReverifyStatus
+
+	failures     int
+	backoff      time.Duration
+	backoffUntil time.Time
+}
+
+type ReverifyStatus struct {
+	Interval    string    `json:"interval"`
+	LastRun     time.Time `json:"last_run,omitempty"`
+	Running     bool      `json:"running"`
+	Claims      int       `json:"claims"`
+	Processed   int       `json:"processed"`
+	Transitions int       `json:"transitions"`
+	// BackoffUntil is set while the worker is waiting out failing upstreams.
+	BackoffUntil *time.Time `json:"backoff_until,omitempty"`
+}
+
+func (s *reverifyState) status() *ReverifyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs := s.ReverifyStatus
+	if time.Now().Before(s.backoffUntil) {
+		until := s.backoffUntil
+		rs.BackoffUntil = &until
+	}
+	return &rs
+}
+
+// readSeed reads claim txids from path, one per line. Blank lines and lines
+// starting with # are ignored.
+func readSeed(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var txids []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !txidRegex.MatchString(line) {
+			return nil, fmt.Errorf("%s:%d: invalid claim txid %q", path, n, line)
+		}
+		txids = append(txids, line)
+	}
+	return txids, scanner.Err()
+}
+
+// runReverify re-verifies every claim in history and reverifySeed each
+// reverifyInterval, give or take a tenth, until ctx is done.
+func runReverify(ctx context.Context) {
+	reverify.mu.Lock()
+	reverify.Interval = reverifyInterval.String()
+	reverify.mu.Unlock()
+
+	for {
+		jitter := time.Duration(rand.Int63n(int64(reverifyInterval)/5+1)) - reverifyInterval/10
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reverifyInterval + jitter):
+		}
+		reverifyClaims(ctx)
+	}
+}
+
+// reverifyClaims runs one pass of re-verification.
+func reverifyClaims(ctx context.Context) {
+	txids, err := history.Claims(ctx)
+	if err != nil {
+		slog.Error("Unable to list claims to re-verify", "err", err)
+		return
+	}
+	seen := make(map[string]bool, len(txids))
+	for _, txid := range txids {
+		seen[txid] = true
+	}
+	for _, txid := range reverifySeed {
+		if !seen[txid] {
+			seen[txid] = true
+			txids = append(txids, txid)
+		}
+	}
+
+	start := time.Now()
+	reverify.mu.Lock()
+	reverify.LastRun, reverify.Running = start, true
+	reverify.Claims, reverify.Processed, reverify.Transitions = len(txids), 0, 0
+	reverify.mu.Unlock()
+	reverifyLastRun.Set(float64(start.Unix()))
+	slog.Info("Re-verifying claims", "claims", len(txids))
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < reverifyWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for txid := range jobs {
+				reverifyClaim(ctx, txid)
+			}
+		}()
+	}
+	for _, txid := range txids {
+		select {
+		case jobs <- txid:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	reverify.mu.Lock()
+	reverify.Running = false
+	attrs := []interface{}{"claims", reverify.Claims, "processed", reverify.Processed, "transitions", reverify.Transitions, "duration", time.Since(start).String()}
+	reverify.mu.Unlock()
+	slog.Info("Re-verified claims", attrs...)
+}
+
+// reverifyClaim checks claim txid, bypassing caches, once the Twitter rate
+// limiter and any backoff allow, and records the check in history.
+func reverifyClaim(ctx context.Context, txid string) {
+	if ctx.Err() != nil {
+		return
+	}
+	reverify.mu.Lock()
+	wait := time.Until(reverify.backoffUntil)
+	reverify.mu.Unlock()
+	if lim := verify.TwitterLimiter; lim != nil {
+		// wait for a token without taking it, so the check itself can
+		r := lim.Reserve()
+		if d := r.Delay(); d > wait {
+			wait = d
+		}
+		r.Cancel()
+	}
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+
+	status, err := verify.CheckClaim(verifier.WithRefresh(ctx), txid)
+	if err != nil {
+		return
+	}
+	publishCheck(txid, status)
+	transition, err := history.Record(ctx, txid, time.Now(), status)
+	if err != nil {
+		slog.Error("Unable to record check", "txid", txid, "err", err)
+	}
+
+	reverify.mu.Lock()
+	defer reverify.mu.Unlock()
+	reverify.Processed++
+	reverifyChecks.WithLabelValues(status.Code).Inc()
+	if transition != nil {
+		reverify.Transitions++
+		reverifyTransitions.WithLabelValues(fmt.Sprint(transition.Verified)).Inc()
+		slog.Info("Claim verification changed", "txid", txid, "verified", transition.Verified, "code", transition.Code)
+		notifyTransition(txid, status, transition)
+	}
+
+	switch status.Code {
+	case verifier.CodeUpstreamError, verifier.CodeRateLimited:
+		reverify.failures++
+	default:
+		reverify.failures, reverify.backoff = 0, 0
+	}
+	until := time.Now().Add(time.Duration(status.RetryAfter) * time.Second)
+	if reverify.failures >= reverifyFailures {
+		reverify.failures = 0
+		reverify.backoff *= 2
+		if reverify.backoff < reverifyMinBackoff {
+			reverify.backoff = reverifyMinBackoff
+		}
+		if reverify.backoff > reverifyInterval {
+			reverify.backoff = reverifyInterval
+		}
+		if t := time.Now().Add(reverify.backoff); t.After(until) {
+			until = t
+		}
+		slog.Error("Upstreams are failing, backing off re-verification", "backoff", reverify.backoff.String())
+	}
+	if until.After(reverify.backoffUntil) {
+		reverify.backoffUntil = until
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// secretFlags are the flags holding credentials. Each has a -file variant
+// that reads the value from a file instead, such as a Docker or Kubernetes
+// secret mount, so that it doesn't show up in ps or the environment. The file
+// takes precedence over the flag and its environment variable. serve rereads
+// the files on SIGHUP, applying new Twitter credentials and webhook secrets.
+// Api keys have -api-key-file, which is reloaded the same way.
+var secretFlags = []string{"consumer-key", "consumer-secret", "access-token", "access-secret", "bearer-token", "github-token", "youtube-api-key", "matrix-token", "instagram-session", "twitch-client-secret", "tumblr-api-key", "facebook-token", "vimeo-token", "gitlab-token", "discord-bot-token", "webhook-secret"}
+
+// addSecretFileFlags adds the -file variant of each secret flag in flags.
+func addSecretFileFlags(flags *flag.FlagSet) {
+	for _, name := range secretFlags {
+		if flags.Lookup(name) != nil {
+			flags.String(name+"-file", "", "File to read -"+name+" from, taking precedence over -"+name)
+		}
+	}
+}
+
+// secretFileFlags returns the names of the -file flags in flags.
+func secretFileFlags(flags *flag.FlagSet) []string {
+	var names []string
+	for _, name := range secretFlags {
+		if flags.Lookup(name+"-file") != nil {
+			names = append(names, name+"-file")
+		}
+	}
+	return names
+}
+
+// readSecretFiles sets each secret flag whose -file variant is set to the
+// contents of the file, without trailing whitespace. Either all of the files
+// are read or, on error, none of the flags are changed. Errors name the flag
+// but never include a value.
+func readSecretFiles(flags *flag.FlagSet) error {
+	values := make(map[string]string)
+	for _, name := range secretFlags {
+		f := flags.Lookup(name + "-file")
+		if f == nil || f.Value.String() == "" {
+			continue
+		}
+		path := f.Value.String()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read -%s-file: %v", name, err)
+		}
+		value := strings.TrimRight(string(b), " \t\r\n")
+		if value == "" {
+			return fmt.Errorf("-%s-file %s is empty", name, path)
+		}
+		values[name] = value
+	}
+	for name, value := range values {
+		err := flags.Set(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid value in -%s-file", name)
+		}
+	}
+	return nil
+}
+
+// twitterCredentials are the Twitter flags of o, to tell whether a reload
+// changed them.
+type twitterCredentials struct {
+	consumerKey, consumerSecret, accessToken, accessSecret, bearerToken string
+}
+
+func (o *options) twitterCredentials() twitterCredentials {
+	return twitterCredentials{*o.consumerKey, *o.consumerSecret, *o.accessToken, *o.accessSecret, *o.bearerToken}
+}
+
+// reloadSecretsOnHUP rereads the secret files whenever the process gets
+// SIGHUP.
+func reloadSecretsOnHUP(flags *flag.FlagSet, o *options) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		reloadSecrets(flags, o)
+	}
+}
+
+// reloadSecrets rereads the secret files and, if the Twitter credentials
+// changed, replaces the Twitter client. The current secrets are kept if
+// anything fails.
+func reloadSecrets(flags *flag.FlagSet, o *options) {
+	before := o.twitterCredentials()
+	err := readSecretFiles(flags)
+	if err != nil {
+		slog.Error("Unable to reload secrets, keeping the current ones", "err", err)
+		return
+	}
+	if f := flags.Lookup("webhook-secret"); f != nil {
+		webhookSecret.Store(f.Value.String())
+	}
+	if o.twitterCredentials() == before {
+		return
+	}
+	client, err := newTwitterClient(o)
+	if err != nil {
+		slog.Error("Unable to apply reloaded Twitter credentials, keeping the current ones", "err", err)
+		return
+	}
+	verify.SetTwitter(client, *o.bearerToken)
+	twitterCredentialsChanged()
+	slog.Info("Reloaded Twitter credentials")
+}
@@ -0,0 +1,348 @@
+// Package store keeps the history of claim checks in SQLite or Postgres.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/oipwg/verifier/verifier"
+	_ "modernc.org/sqlite"
+)
+
+// Store records claim checks in a database.
+type Store struct {
+	db       *sql.DB
+	postgres bool
+}
+
+// A Check is one recorded check of a claim.
+type Check struct {
+	ID        int64                     `json:"id"`
+	Time      int64                     `json:"time"`
+	Code      string                    `json:"code"`
+	Verified  bool                      `json:"verified"`
+	Publisher string                    `json:"publisher,omitempty"`
+	Platforms map[string]*PlatformCheck `json:"platforms"`
+}
+
+// A PlatformCheck is the recorded outcome of a check on one platform.
+type PlatformCheck struct {
+	Code     string `json:"code"`
+	Verified bool   `json:"verified"`
+	Post     string `json:"post,omitempty"`
+}
+
+// A Transition is a change in whether a claim verifies.
+type Transition struct {
+	Time     int64 `json:"time"`
+	Verified bool  `json:"verified"`
+	// Code is why the claim now verifies or not: OK, the code of the
+	// check, or the code of the first platform that failed.
+	Code string `json:"code"`
+}
+
+// Open connects to dsn, a postgres:// URL or key=value DSN for Postgres or
+// otherwise the path of a SQLite file, and migrates it to the current
+// schema.
+func Open(ctx context.Context, dsn string) (*Store, error) {
+	s := &Store{postgres: strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, "host=")}
+	driver := "sqlite"
+	if s.postgres {
+		driver = "postgres"
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if !s.postgres {
+		// sqlite allows one writer at a time
+		db.SetMaxOpenConns(1)
+	}
+	s.db = db
+
+	err = s.migrate(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// migrations are the schema changes in order. Each runs once, in a
+// transaction, and is recorded in schema_version.
+var migrations = []struct {
+	sqlite, postgres string
+}{
+	{
+		sqlite: `CREATE TABLE checks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			claim_txid TEXT NOT NULL,
+			checked_at INTEGER NOT NULL,
+			code TEXT NOT NULL,
+			verified BOOLEAN NOT NULL,
+			publisher_txid TEXT NOT NULL
+		);
+		CREATE INDEX checks_claim ON checks (claim_txid, id);
+		CREATE TABLE check_platforms (
+			check_id INTEGER NOT NULL REFERENCES checks (id) ON DELETE CASCADE,
+			platform TEXT NOT NULL,
+			code TEXT NOT NULL,
+			verified BOOLEAN NOT NULL,
+			post TEXT NOT NULL,
+			PRIMARY KEY (check_id, platform)
+		)`,
+		postgres: `CREATE TABLE checks (
+			id BIGSERIAL PRIMARY KEY,
+			claim_txid TEXT NOT NULL,
+			checked_at BIGINT NOT NULL,
+			code TEXT NOT NULL,
+			verified BOOLEAN NOT NULL,
+			publisher_txid TEXT NOT NULL
+		);
+		CREATE INDEX checks_claim ON checks (claim_txid, id);
+		CREATE TABLE check_platforms (
+			check_id BIGINT NOT NULL REFERENCES checks (id) ON DELETE CASCADE,
+			platform TEXT NOT NULL,
+			code TEXT NOT NULL,
+			verified BOOLEAN NOT NULL,
+			post TEXT NOT NULL,
+			PRIMARY KEY (check_id, platform)
+		)`,
+	},
+	{
+		sqlite: `CREATE TABLE transitions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			claim_txid TEXT NOT NULL,
+			changed_at INTEGER NOT NULL,
+			verified BOOLEAN NOT NULL,
+			code TEXT NOT NULL
+		);
+		CREATE INDEX transitions_claim ON transitions (claim_txid, id)`,
+		postgres: `CREATE TABLE transitions (
+			id BIGSERIAL PRIMARY KEY,
+			claim_txid TEXT NOT NULL,
+			changed_at BIGINT NOT NULL,
+			verified BOOLEAN NOT NULL,
+			code TEXT NOT NULL
+		);
+		CREATE INDEX transitions_claim ON transitions (claim_txid, id)`,
+	},
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	var version int
+	err = s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		stmt := migrations[i].sqlite
+		if s.postgres {
+			stmt = migrations[i].postgres
+		}
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, q := range strings.Split(stmt, ";") {
+			if strings.TrimSpace(q) == "" {
+				continue
+			}
+			_, err = tx.ExecContext(ctx, q)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO schema_version (version) VALUES (?)`), i+1)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebind replaces the ? placeholders in q with $1, $2, ... for Postgres.
+func (s *Store) rebind(q string) string {
+	if !s.postgres {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// transient are the check codes that say nothing about whether a claim
+// verifies, so never make a transition.
+const transient = `('` + verifier.CodeUpstreamError + `', '` + verifier.CodeRateLimited + `')`
+
+// Record stores the check of claim txid made at t. It returns the
+// transition the check makes, if its outcome differs from the last check of
+// the claim that didn't fail upstream.
+func (s *Store) Record(ctx context.Context, txid string, t time.Time, status *verifier.Result) (*Transition, error) {
+	var publisher string
+	if status.Publisher != nil {
+		publisher = status.Publisher.Txid
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var transition *Transition
+	if status.Code != verifier.CodeUpstreamError && status.Code != verifier.CodeRateLimited {
+		var last bool
+		q := `SELECT verified FROM checks WHERE claim_txid = ? AND code NOT IN ` + transient + ` ORDER BY id DESC LIMIT 1`
+		err = tx.QueryRowContext(ctx, s.rebind(q), txid).Scan(&last)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, err
+		case last != status.Verified:
+			transition = &Transition{Time: ms, Verified: status.Verified, Code: reason(status)}
+			q := `INSERT INTO transitions (claim_txid, changed_at, verified, code) VALUES (?, ?, ?, ?)`
+			_, err = tx.ExecContext(ctx, s.rebind(q), txid, ms, transition.Verified, transition.Code)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var id int64
+	q := `INSERT INTO checks (claim_txid, checked_at, code, verified, publisher_txid) VALUES (?, ?, ?, ?, ?) RETURNING id`
+	err = tx.QueryRowContext(ctx, s.rebind(q), txid, ms, status.Code, status.Verified, publisher).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	for name, ps := range status.Platforms {
+		q := `INSERT INTO check_platforms (check_id, platform, code, verified, post) VALUES (?, ?, ?, ?, ?)`
+		_, err = tx.ExecContext(ctx, s.rebind(q), id, name, ps.Code, ps.Verified, ps.Post)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transition, tx.Commit()
+}
+
+// reason returns the code a transition to status is recorded with.
+func reason(status *verifier.Result) string {
+	if status.Verified || status.Code != verifier.CodeOK {
+		return status.Code
+	}
+	names := make([]string, 0, len(status.Platforms))
+	for name := range status.Platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ps := status.Platforms[name]; !ps.Verified && ps.Code != "" {
+			return ps.Code
+		}
+	}
+	return status.Code
+}
+
+// Claims returns the txid of every claim that has been checked.
+func (s *Store) Claims(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT claim_txid FROM checks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var txids []string
+	for rows.Next() {
+		var txid string
+		err = rows.Scan(&txid)
+		if err != nil {
+			return nil, err
+		}
+		txids = append(txids, txid)
+	}
+	return txids, rows.Err()
+}
+
+// History returns up to limit checks of claim txid, newest first, starting
+// after the check with id before if it isn't 0.
+func (s *Store) History(ctx context.Context, txid string, limit int, before int64) ([]*Check, error) {
+	q := `SELECT id, checked_at, code, verified, publisher_txid FROM checks WHERE claim_txid = ?`
+	args := []interface{}{txid}
+	if before > 0 {
+		q += ` AND id < ?`
+		args = append(args, before)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(q), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var checks []*Check
+	byID := make(map[int64]*Check)
+	for rows.Next() {
+		c := &Check{Platforms: make(map[string]*PlatformCheck)}
+		err = rows.Scan(&c.ID, &c.Time, &c.Code, &c.Verified, &c.Publisher)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+		byID[c.ID] = c
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil || len(checks) == 0 {
+		return checks, err
+	}
+
+	q = `SELECT check_id, platform, code, verified, post FROM check_platforms WHERE check_id <= ? AND check_id >= ? AND check_id IN (SELECT id FROM checks WHERE claim_txid = ?)`
+	prows, err := s.db.QueryContext(ctx, s.rebind(q), checks[0].ID, checks[len(checks)-1].ID, txid)
+	if err != nil {
+		return nil, err
+	}
+	defer prows.Close()
+	for prows.Next() {
+		var id int64
+		var name string
+		pc := &PlatformCheck{}
+		err = prows.Scan(&id, &name, &pc.Code, &pc.Verified, &pc.Post)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := byID[id]; ok {
+			c.Platforms[name] = pc
+		}
+	}
+	return checks, prows.Err()
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+const testClaim = "63715582e8b5549240b257727fe6ac17b99e4272e69b30887922398a1778c133"
+
+func openMemory(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// result returns a check result with code, verified as given, and a twitter
+// status with the same outcome.
+func result(code string, verified bool) *verifier.Result {
+	ps := &verifier.PlatformStatus{Verified: verified, Code: code, Post: "1724567800000000001"}
+	if !verified && code == verifier.CodeOK {
+		ps.Code = verifier.CodeBadFormat
+	}
+	return &verifier.Result{
+		Code:      code,
+		Verified:  verified,
+		Publisher: &verifier.PublisherMeta{Txid: "4f03a060b7eb032972dcb327f2c989b14d5e73c35b3d5928a6f875ca39c159ba"},
+		Platforms: map[string]*verifier.PlatformStatus{"twitter": ps},
+	}
+}
+
+func TestMigrateTwice(t *testing.T) {
+	ctx := context.Background()
+	s := openMemory(t)
+	err := s.migrate(ctx)
+	if err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	var versions, latest int
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*), MAX(version) FROM schema_version`).Scan(&versions, &latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions != len(migrations) || latest != len(migrations) {
+		t.Errorf("got %d versions up to %d, want %d", versions, latest, len(migrations))
+	}
+
+	// reopening a database keeps what it has
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Record(ctx, testClaim, time.Unix(1700000000, 0), result(verifier.CodeOK, true))
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer s.Close()
+	checks, err := s.History(ctx, testClaim, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 {
+		t.Errorf("got %d checks after reopening, want 1", len(checks))
+	}
+}
+
+func TestRecordTransitions(t *testing.T) {
+	ctx := context.Background()
+	s := openMemory(t)
+	steps := []struct {
+		code       string
+		verified   bool
+		transition string
+	}{
+		// the first check has nothing to change from
+		{verifier.CodeOK, true, ""},
+		{verifier.CodeOK, true, ""},
+		{verifier.CodeUpstreamError, false, ""},
+		{verifier.CodeRateLimited, false, ""},
+		{verifier.CodeOK, true, ""},
+		{verifier.CodeOK, false, verifier.CodeBadFormat},
+		{verifier.CodeUpstreamError, false, ""},
+		{verifier.CodeOK, false, ""},
+		{verifier.CodeRateLimited, false, ""},
+		{verifier.CodeOK, true, verifier.CodeOK},
+		{verifier.CodeClaimNotFound, false, verifier.CodeClaimNotFound},
+	}
+	at := time.Unix(1700000000, 0)
+	for i, step := range steps {
+		at = at.Add(time.Minute)
+		tr, err := s.Record(ctx, testClaim, at, result(step.code, step.verified))
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+		switch {
+		case step.transition == "" && tr != nil:
+			t.Errorf("check %d (%s): got transition %+v, want none", i, step.code, tr)
+		case step.transition != "" && tr == nil:
+			t.Errorf("check %d (%s): got no transition, want one to %s", i, step.code, step.transition)
+		case tr != nil && (tr.Code != step.transition || tr.Verified != step.verified || tr.Time != at.UnixNano()/int64(time.Millisecond)):
+			t.Errorf("check %d (%s): got transition %+v, want to %v with %s", i, step.code, tr, step.verified, step.transition)
+		}
+	}
+
+	var transitions int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM transitions WHERE claim_txid = ?`, testClaim).Scan(&transitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transitions != 3 {
+		t.Errorf("got %d stored transitions, want 3", transitions)
+	}
+}
+
+func TestHistoryPaging(t *testing.T) {
+	ctx := context.Background()
+	s := openMemory(t)
+	const other = "1d6dcbb8158ced6e93123a7745a30a4e4853c6dc6589b41ea3a920071d3bfd2e"
+	at := time.Unix(1700000000, 0)
+	for i := 0; i < 7; i++ {
+		_, err := s.Record(ctx, testClaim, at.Add(time.Duration(i)*time.Minute), result(verifier.CodeOK, i%2 == 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// interleave another claim's checks, which mustn't show up
+		_, err = s.Record(ctx, other, at, result(verifier.CodeUpstreamError, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pages [][]*Check
+	var before int64
+	for {
+		checks, err := s.History(ctx, testClaim, 3, before)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(checks) == 0 {
+			break
+		}
+		pages = append(pages, checks)
+		before = checks[len(checks)-1].ID
+	}
+	if len(pages) != 3 || len(pages[0]) != 3 || len(pages[1]) != 3 || len(pages[2]) != 1 {
+		t.Fatalf("got pages of %v checks, want 3, 3 and 1", pageSizes(pages))
+	}
+
+	i := 6
+	var last int64
+	for _, page := range pages {
+		for _, c := range page {
+			if last != 0 && c.ID >= last {
+				t.Errorf("check %d after %d: not newest first", c.ID, last)
+			}
+			last = c.ID
+			want := at.Add(time.Duration(i)*time.Minute).UnixNano() / int64(time.Millisecond)
+			if c.Time != want || c.Verified != (i%2 == 0) {
+				t.Errorf("check %d: got time %d verified %v, want %d %v", c.ID, c.Time, c.Verified, want, i%2 == 0)
+			}
+			ps := c.Platforms["twitter"]
+			if len(c.Platforms) != 1 || ps == nil || ps.Verified != c.Verified || ps.Post != "1724567800000000001" {
+				t.Errorf("check %d: got platforms %+v", c.ID, c.Platforms)
+			}
+			i--
+		}
+	}
+
+	checks, err := s.History(ctx, testClaim, 10, pages[0][0].ID+1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 7 {
+		t.Errorf("before past the newest check: got %d checks, want 7", len(checks))
+	}
+	checks, err = s.History(ctx, "2a53d651afdf99f8b5a9324f47f69f013f538236e386ddbcc7460e57df8c448d", 10, 0)
+	if err != nil || len(checks) != 0 {
+		t.Errorf("unchecked claim: got %v, %v", checks, err)
+	}
+}
+
+func pageSizes(pages [][]*Check) []int {
+	sizes := make([]int, len(pages))
+	for i, page := range pages {
+		sizes[i] = len(page)
+	}
+	return sizes
+}
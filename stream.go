@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+func init() {
+	rootRouter.HandleFunc("/stream", handleStream).Methods(http.MethodGet)
+}
+
+var (
+	streamMaxSubscribers = 100
+	streamKeepAlive      = 15 * time.Second
+
+	streamsMu   sync.Mutex
+	streams     = make(map[*subscriber]struct{})
+	streamsDone = make(chan struct{})
+	closeOnce   sync.Once
+)
+
+// A subscriber is a client of /verified/stream, which gets the events for
+// claim, or publisher, or every event if neither is set.
+type subscriber struct {
+	claim, publisher string
+	events           chan sseEvent
+}
+
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+func (s *subscriber) wants(ev *TransitionEvent) bool {
+	return (s.claim == "" || s.claim == ev.Claim) && (s.publisher == "" || s.publisher == ev.Publisher)
+}
+
+// publishCheck sends a check event for the check of claim txid.
+func publishCheck(txid string, status *verifier.Result) {
+	publishEvent("check", newEvent(txid, status, status.Verified, time.Now(), status.Code))
+}
+
+// publishEvent sends ev to the subscribers that want it. Subscribers too slow
+// to keep up miss events rather than holding up checks.
+func publishEvent(name string, ev *TransitionEvent) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	if len(streams) == 0 {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("Unable to encode stream event", "txid", ev.Claim, "err", err)
+		return
+	}
+	for s := range streams {
+		if !s.wants(ev) {
+			continue
+		}
+		select {
+		case s.events <- sseEvent{name, data}:
+		default:
+		}
+	}
+}
+
+// closeStreams ends every stream, so that they don't hold up shutdown.
+func closeStreams() {
+	closeOnce.Do(func() { close(streamsDone) })
+}
+
+// handleStream sends check and transition events as Server-Sent Events,
+// optionally only those for ?claim= or ?publisher=.
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s := &subscriber{claim: q.Get("claim"), publisher: q.Get("publisher"), events: make(chan sseEvent, 16)}
+	if (s.claim != "" && !txidRegex.MatchString(s.claim)) || (s.publisher != "" && !txidRegex.MatchString(s.publisher)) {
+		RespondJSON(w, http.StatusBadRequest, ErrorResponse{Code: verifier.CodeInvalidId, Msg: "claim and publisher must be txids"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported on this connection", http.StatusInternalServerError)
+		return
+	}
+
+	streamsMu.Lock()
+	if len(streams) >= streamMaxSubscribers {
+		streamsMu.Unlock()
+		RespondJSON(w, http.StatusServiceUnavailable, ErrorResponse{Code: verifier.CodeBusy, Msg: "Too many stream subscribers, try again later"})
+		return
+	}
+	streams[s] = struct{}{}
+	streamsMu.Unlock()
+	defer func() {
+		streamsMu.Lock()
+		delete(streams, s)
+		streamsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-streamsDone:
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case ev := <-s.events:
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
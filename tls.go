@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var (
+	// tlsCert and tlsKey are the files of a static certificate.
+	tlsCert, tlsKey string
+	// acmeDomains, when set, are the names to get Let's Encrypt certificates
+	// for, cached in acmeCache. acmeHTTPListen serves the HTTP-01 challenges
+	// and redirects everything else to https.
+	acmeDomains    []string
+	acmeCache      = "acme-cache"
+	acmeHTTPListen = ":80"
+)
+
+// certReloader serves a static certificate, reloading it from disk on
+// SIGHUP so that it can be rotated without a restart.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	err := c.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			err := c.reload()
+			if err != nil {
+				slog.Error("Unable to reload TLS certificate, keeping the current one", "err", err, "cert", certFile)
+				continue
+			}
+			slog.Info("Reloaded TLS certificate", "cert", certFile)
+		}
+	}()
+	return c, nil
+}
+
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// configureTLS sets up srv to serve TLS when a certificate or ACME domains
+// are configured, reporting whether it did. With ACME it also starts the
+// plain http listener for challenges and redirects.
+func configureTLS(srv *http.Server) (bool, error) {
+	switch {
+	case len(acmeDomains) > 0 && tlsCert != "":
+		return false, errors.New("-acme-domain and -tls-cert are exclusive")
+	case tlsCert != "" || tlsKey != "":
+		if tlsCert == "" || tlsKey == "" {
+			return false, errors.New("-tls-cert and -tls-key must be given together")
+		}
+		c, err := newCertReloader(tlsCert, tlsKey)
+		if err != nil {
+			return false, err
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: c.getCertificate}
+		return true, nil
+	case len(acmeDomains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(acmeDomains...),
+			Cache:      autocert.DirCache(acmeCache),
+		}
+		srv.TLSConfig = m.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+
+		ln, err := Listen(acmeHTTPListen)
+		if err != nil {
+			return false, err
+		}
+		go func() {
+			slog.Info("Serving ACME challenges", "listen", ln.Addr().String())
+			// without a fallback handler, other requests are redirected to https
+			err := http.Serve(ln, m.HTTPHandler(nil))
+			if err != nil {
+				slog.Error("Error serving ACME challenges", "err", err, "listen", acmeHTTPListen)
+			}
+		}()
+		return true, nil
+	}
+	return false, nil
+}
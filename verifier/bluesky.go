@@ -0,0 +1,127 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type blueskyPosts struct {
+	Posts []struct {
+		URI    string `json:"uri"`
+		Author struct {
+			DID    string `json:"did"`
+			Handle string `json:"handle"`
+		} `json:"author"`
+		Record struct {
+			Text string `json:"text"`
+		} `json:"record"`
+	} `json:"posts"`
+}
+
+var (
+	blueskyATRegex  = regexp.MustCompile(`^at://([^/]+)/app\.bsky\.feed\.post/([A-Za-z0-9._~:-]+)$`)
+	blueskyWebRegex = regexp.MustCompile(`^https://bsky\.app/profile/([^/]+)/post/([A-Za-z0-9._~:-]+)/?$`)
+)
+
+// parseBlueskyURI returns the repo (a handle or DID) and record key of a post
+// given as an at:// URI or a bsky.app URL.
+func parseBlueskyURI(uri string) (repo, rkey string, err error) {
+	m := blueskyATRegex.FindStringSubmatch(uri)
+	if m == nil {
+		m = blueskyWebRegex.FindStringSubmatch(uri)
+	}
+	if m == nil {
+		return "", "", ErrInvalidID
+	}
+	return m[1], m[2], nil
+}
+
+// VerifyBluesky fetches the post uri from the BlueskyAppView and returns the
+// publisher name and txid from its verification statement along with the
+// author's handle.
+func (v *Verifier) VerifyBluesky(ctx context.Context, uri string) (name string, txid string, handle string, err error) {
+	repo, rkey, err := parseBlueskyURI(uri)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	did := repo
+	if !strings.HasPrefix(repo, "did:") {
+		did, err = v.resolveBlueskyHandle(ctx, repo)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+
+	atURI := "at://" + did + "/app.bsky.feed.post/" + rkey
+	body, err := v.httpGet(ctx, "bluesky", v.BlueskyAppView+"/xrpc/app.bsky.feed.getPosts?uris="+url.QueryEscape(atURI))
+	if err != nil {
+		return "", "", "", err
+	}
+	posts := &blueskyPosts{}
+	err = json.Unmarshal(body, posts)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "bluesky post " + uri, Err: err}
+	}
+	if len(posts.Posts) == 0 {
+		return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Bluesky post " + uri + " has been deleted or does not exist"}
+	}
+
+	post := posts.Posts[0]
+	name, txid, err = v.matchVerification(ctx, post.Record.Text)
+	return name, txid, post.Author.Handle, err
+}
+
+func (v *Verifier) resolveBlueskyHandle(ctx context.Context, handle string) (string, error) {
+	body, err := v.httpGet(ctx, "bluesky", v.BlueskyAppView+"/xrpc/com.atproto.identity.resolveHandle?handle="+url.QueryEscape(handle))
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusBadRequest {
+			return "", &PlatformError{Code: CodeHandleNotFound, Msg: "Unable to resolve Bluesky handle " + handle}
+		}
+		return "", err
+	}
+	var res struct {
+		DID string `json:"did"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return "", &DecodeError{URL: "bluesky handle " + handle, Err: err}
+	}
+	if res.DID == "" {
+		return "", &PlatformError{Code: CodeHandleNotFound, Msg: "Unable to resolve Bluesky handle " + handle}
+	}
+	return res.DID, nil
+}
+
+type blueskyPlatform struct {
+	v *Verifier
+}
+
+func (b blueskyPlatform) Name() string {
+	return "bluesky"
+}
+
+func (b blueskyPlatform) Verify(ctx context.Context, uri string) (string, string, error) {
+	name, txid, _, err := b.v.VerifyBluesky(ctx, uri)
+	return name, txid, err
+}
+
+func (b blueskyPlatform) VerifyAuthor(ctx context.Context, uri string) (string, string, string, error) {
+	return b.v.VerifyBluesky(ctx, uri)
+}
+
+// blueskyClaimHandle returns the handle named in the claim's post URI, or ""
+// when the post is referenced by DID.
+func blueskyClaimHandle(vc *VerificationClaim) string {
+	repo, _, err := parseBlueskyURI(vc.BlueskyUri)
+	if err != nil || strings.HasPrefix(repo, "did:") {
+		return ""
+	}
+	return repo
+}
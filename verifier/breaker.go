@@ -0,0 +1,204 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the cause of the UnavailableError requests to an
+// upstream fail with while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerSettings configure the circuit breaker of each upstream host. A
+// breaker opens when at least MinRequests requests were made in Window and
+// FailureRate of them failed with an error or a 5xx. While it is open,
+// requests fail fast. After Cooldown it half-opens and lets Probes requests
+// through: it closes once they all succeed and opens again if one fails.
+type BreakerSettings struct {
+	FailureRate float64
+	// MinRequests is 0 to disable the breakers.
+	MinRequests int
+	Window      time.Duration
+	Cooldown    time.Duration
+	Probes      int
+}
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerStatus is the state of the breaker of an upstream host that isn't
+// closed.
+type BreakerStatus struct {
+	Target string    `json:"target"`
+	Host   string    `json:"host"`
+	State  string    `json:"state"`
+	Since  time.Time `json:"since"`
+}
+
+type breaker struct {
+	target, host string
+	state        string
+	since        time.Time
+
+	// the window of a closed breaker
+	windowStart        time.Time
+	requests, failures int
+	// the probes of a half-open breaker
+	probing, probed int
+}
+
+// breakerSet holds the breakers of the upstream hosts requests were made
+// to, by target and host.
+type breakerSet struct {
+	mu sync.Mutex
+	m  map[[2]string]*breaker
+}
+
+// maxIdleBreakers is how many breakers are kept before closed ones that
+// haven't seen a request for a window are dropped.
+const maxIdleBreakers = 1024
+
+func (s *breakerSet) get(target, host string, now time.Time, window time.Duration) *breaker {
+	key := [2]string{target, host}
+	if b, ok := s.m[key]; ok {
+		return b
+	}
+	if s.m == nil {
+		s.m = make(map[[2]string]*breaker)
+	}
+	if len(s.m) >= maxIdleBreakers {
+		for k, b := range s.m {
+			if b.state == BreakerClosed && now.Sub(b.windowStart) >= window {
+				delete(s.m, k)
+			}
+		}
+	}
+	b := &breaker{target: target, host: host, state: BreakerClosed, since: now, windowStart: now}
+	s.m[key] = b
+	return b
+}
+
+// set moves b to state, returning a function that reports the change, to be
+// called once s.mu is released.
+func (b *breaker) set(state string, now time.Time, hook func(target, host, state string)) func() {
+	b.state, b.since = state, now
+	b.windowStart, b.requests, b.failures = now, 0, 0
+	b.probing, b.probed = 0, 0
+	target, host := b.target, b.host
+	return func() {
+		attrs := []interface{}{"target", target, "host", host}
+		if state == BreakerOpen {
+			slog.Warn("Opened circuit breaker", attrs...)
+		} else {
+			slog.Info("Circuit breaker is "+state, attrs...)
+		}
+		if hook != nil {
+			hook(target, host, state)
+		}
+	}
+}
+
+// allowUpstream returns an UnavailableError when the breaker of host is open,
+// or half-open with its probes already under way.
+func (v *Verifier) allowUpstream(target, host string) error {
+	s := v.Breakers
+	if s.MinRequests <= 0 {
+		return nil
+	}
+	now := time.Now()
+	report := func() {}
+	defer func() { report() }()
+
+	v.breakers.mu.Lock()
+	defer v.breakers.mu.Unlock()
+	b := v.breakers.get(target, host, now, s.Window)
+	if b.state == BreakerOpen {
+		if now.Sub(b.since) < s.Cooldown {
+			return &UnavailableError{What: target, Err: ErrCircuitOpen}
+		}
+		report = b.set(BreakerHalfOpen, now, v.Hooks.Breaker)
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probing+b.probed >= s.Probes {
+			return &UnavailableError{What: target, Err: ErrCircuitOpen}
+		}
+		b.probing++
+	}
+	return nil
+}
+
+// recordUpstream counts the outcome of a request to host that
+// allowUpstream let through. Requests that were cancelled are not counted.
+func (v *Verifier) recordUpstream(target, host string, res *http.Response, err error) {
+	s := v.Breakers
+	if s.MinRequests <= 0 {
+		return
+	}
+	cancelled := errors.Is(err, context.Canceled)
+	failed := err != nil || res != nil && res.StatusCode >= 500
+	now := time.Now()
+	report := func() {}
+	defer func() { report() }()
+
+	v.breakers.mu.Lock()
+	defer v.breakers.mu.Unlock()
+	b := v.breakers.get(target, host, now, s.Window)
+	switch b.state {
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= s.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		if cancelled {
+			return
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= s.MinRequests && float64(b.failures) >= s.FailureRate*float64(b.requests) {
+			report = b.set(BreakerOpen, now, v.Hooks.Breaker)
+		}
+	case BreakerHalfOpen:
+		if b.probing > 0 {
+			b.probing--
+		}
+		switch {
+		case cancelled:
+		case failed:
+			report = b.set(BreakerOpen, now, v.Hooks.Breaker)
+		default:
+			b.probed++
+			if b.probed >= s.Probes {
+				report = b.set(BreakerClosed, now, v.Hooks.Breaker)
+			}
+		}
+	}
+}
+
+// OpenBreakers returns the breakers that are open or half-open, by target
+// and host.
+func (v *Verifier) OpenBreakers() []BreakerStatus {
+	v.breakers.mu.Lock()
+	defer v.breakers.mu.Unlock()
+	var open []BreakerStatus
+	for _, b := range v.breakers.m {
+		if b.state != BreakerClosed {
+			open = append(open, BreakerStatus{Target: b.target, Host: b.host, State: b.state, Since: b.since})
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].Target != open[j].Target {
+			return open[i].Target < open[j].Target
+		}
+		return open[i].Host < open[j].Host
+	})
+	return open
+}
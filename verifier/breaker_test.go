@@ -0,0 +1,133 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	v := New(nil, testOipApi, nil)
+	v.Breakers = BreakerSettings{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, Cooldown: cooldown, Probes: 2}
+	var states []string
+	v.Hooks.Breaker = func(target, host, state string) {
+		states = append(states, state)
+	}
+
+	ok := &http.Response{StatusCode: http.StatusOK}
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	timeout := errors.New("timeout")
+	// each step asks allowUpstream to let a request through, or records the
+	// outcome of the request it let through, and checks the state after it
+	type step struct {
+		do    string
+		res   *http.Response
+		err   error
+		state string
+	}
+	allow := func(state string) step { return step{do: "allow", state: state} }
+	refuse := func(state string) step { return step{do: "refuse", state: state} }
+	record := func(res *http.Response, err error, state string) step {
+		return step{do: "record", res: res, err: err, state: state}
+	}
+	wait := step{do: "wait", state: BreakerOpen}
+	steps := []step{
+		record(ok, nil, BreakerClosed),
+		record(ok, nil, BreakerClosed),
+		record(nil, timeout, BreakerClosed),
+		// cancelled requests aren't counted at all
+		record(nil, context.Canceled, BreakerClosed),
+		record(unavailable, nil, BreakerOpen),
+		refuse(BreakerOpen),
+		wait,
+		allow(BreakerHalfOpen),
+		// a failed probe opens it again
+		record(unavailable, nil, BreakerOpen),
+		refuse(BreakerOpen),
+		wait,
+		allow(BreakerHalfOpen),
+		allow(BreakerHalfOpen),
+		// out of probes
+		refuse(BreakerHalfOpen),
+		// a cancelled probe gives its slot back
+		record(nil, context.Canceled, BreakerHalfOpen),
+		allow(BreakerHalfOpen),
+		record(ok, nil, BreakerHalfOpen),
+		// one probe passed and one is under way
+		refuse(BreakerHalfOpen),
+		record(ok, nil, BreakerClosed),
+		allow(BreakerClosed),
+		allow(BreakerClosed),
+	}
+	for i, s := range steps {
+		switch s.do {
+		case "allow", "refuse":
+			err := v.allowUpstream("example", "example.com")
+			if s.do == "allow" && err != nil {
+				t.Fatalf("step %d: allowUpstream: %v", i, err)
+			}
+			var ue *UnavailableError
+			if s.do == "refuse" && (!errors.As(err, &ue) || !errors.Is(err, ErrCircuitOpen)) {
+				t.Fatalf("step %d: allowUpstream: got %v, want ErrCircuitOpen", i, err)
+			}
+		case "record":
+			v.recordUpstream("example", "example.com", s.res, s.err)
+		case "wait":
+			time.Sleep(cooldown)
+		}
+		state := BreakerClosed
+		if open := v.OpenBreakers(); len(open) == 1 {
+			state = open[0].State
+		} else if len(open) > 1 {
+			t.Fatalf("step %d: OpenBreakers = %v", i, open)
+		}
+		if state != s.state {
+			t.Fatalf("step %d: %s: breaker is %s, want %s", i, s.do, state, s.state)
+		}
+	}
+	want := []string{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("Breaker hook got %v, want %v", states, want)
+	}
+	if got := v.allowUpstream("example", "other.example.com"); got != nil {
+		t.Errorf("allowUpstream of another host: %v", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBreakerRequests(t *testing.T) {
+	var statuses []int
+	var requests int
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		status := statuses[0]
+		statuses = statuses[1:]
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	})}
+	v := New(nil, testOipApi, client)
+	v.Breakers = BreakerSettings{FailureRate: 0.5, MinRequests: 2, Window: time.Minute, Cooldown: time.Hour, Probes: 1}
+	statuses = []int{http.StatusInternalServerError, http.StatusBadGateway}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		var se *StatusError
+		if _, err := v.httpGet(ctx, "example", "https://example.com/"); !errors.As(err, &se) {
+			t.Fatalf("request %d: got %v, want a StatusError", i, err)
+		}
+	}
+	_, err := v.httpGet(ctx, "example", "https://example.com/")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("request to an open breaker: got %v, want ErrCircuitOpen", err)
+	}
+	if requests != 2 {
+		t.Errorf("upstream got %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,328 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ctxKey int
+
+const (
+	refreshKey ctxKey = iota
+	templateKey
+	debugKey
+	settingsKey
+	loggerKey
+)
+
+// WithRefresh marks ctx so that cached lookups made with it go to the network
+// and replace whatever was cached.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey, true)
+}
+
+// RefreshRequested reports whether ctx was marked with WithRefresh.
+func RefreshRequested(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey).(bool)
+	return refresh
+}
+
+// WithDebug marks ctx so that claims checked with it report the fetched text
+// and how it was matched in each PlatformStatus. It implies WithRefresh, since
+// the text isn't kept in the cache.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(WithRefresh(ctx), debugKey, true)
+}
+
+// DebugRequested reports whether ctx was marked with WithDebug.
+func DebugRequested(ctx context.Context) bool {
+	debug, _ := ctx.Value(debugKey).(bool)
+	return debug
+}
+
+// WithLogger returns ctx with l as the logger for lookups made with it, e.g.
+// one carrying the id of the request they are made for.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// Logger returns the logger of ctx, or slog's default logger if it has none.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+type cacheEntry struct {
+	value   interface{}
+	err     error
+	expires time.Time
+}
+
+// ttlCache holds lookup results, successful or not, for a limited time.
+type ttlCache struct {
+	name      string
+	mu        sync.Mutex
+	entries   map[string]cacheEntry
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newTTLCache(name string) *ttlCache {
+	return &ttlCache{name: name, entries: make(map[string]cacheEntry)}
+}
+
+// cached returns the result cached in c for key, or calls fn and caches what
+// it returns. Successful results are kept for CacheTTL and definitive failures
+// (not found, bad format) for CacheNegativeTTL; upstream, rate limit, not
+// configured, and context errors are never cached.
+func (v *Verifier) cached(ctx context.Context, c *ttlCache, key string, fn func() (interface{}, error)) (interface{}, error) {
+	return v.cachedFor(ctx, c, key, v.settingsFor(ctx).CacheTTL, fn)
+}
+
+// cachedFor is cached with successful results kept for ttl.
+func (v *Verifier) cachedFor(ctx context.Context, c *ttlCache, key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	_, span := startSpan(ctx, "cacheLookup", attribute.String("cache", c.name), attribute.String("key", key))
+	if !RefreshRequested(ctx) {
+		c.mu.Lock()
+		e, ok := c.entries[key]
+		c.mu.Unlock()
+		if ok && time.Now().Before(e.expires) {
+			atomic.AddUint64(&c.hits, 1)
+			span.SetAttributes(attribute.Bool("hit", true))
+			span.End()
+			return e.value, e.err
+		}
+	}
+	atomic.AddUint64(&c.misses, 1)
+	span.SetAttributes(attribute.Bool("hit", false))
+	span.End()
+
+	value, err := fn()
+
+	if err != nil {
+		if _, upstream := UpstreamMsg(err); upstream || errors.Is(err, ErrRateLimited) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return value, err
+		}
+		// credentials may be configured at any time
+		var pe *PlatformError
+		if errors.As(err, &pe) && pe.Code == CodeNotConfigured {
+			return value, err
+		}
+		ttl = v.settingsFor(ctx).CacheNegativeTTL
+	}
+	if ttl > 0 {
+		c.store(key, cacheEntry{value: value, err: err, expires: time.Now().Add(ttl)}, v.CacheMaxEntries)
+	}
+	return value, err
+}
+
+// evictSample is how many entries store looks at to pick one to evict.
+const evictSample = 8
+
+// store caches e for key. A new key in a cache holding max entries, unless
+// max is 0, first evicts the entry expiring soonest of evictSample taken in
+// the map's random order, which approximates evicting the soonest overall.
+func (c *ttlCache) store(key string, e cacheEntry, max int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && max > 0 {
+		for len(c.entries) >= max {
+			var victim string
+			var soonest time.Time
+			n := 0
+			for k, ce := range c.entries {
+				if n == 0 || ce.expires.Before(soonest) {
+					victim, soonest = k, ce.expires
+				}
+				if n++; n == evictSample {
+					break
+				}
+			}
+			delete(c.entries, victim)
+			atomic.AddUint64(&c.evictions, 1)
+		}
+	}
+	c.entries[key] = e
+}
+
+// sweep removes expired entries and returns how many remain.
+func (c *ttlCache) sweep() int {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	return len(c.entries)
+}
+
+// MaintainCaches periodically drops expired cache entries and logs hit/miss
+// counts. It never returns.
+func (v *Verifier) MaintainCaches(interval time.Duration) {
+	for range time.Tick(interval) {
+		for _, c := range v.caches() {
+			slog.Info("Cache stats",
+				"cache", c.name,
+				"entries", c.sweep(),
+				"hits", atomic.LoadUint64(&c.hits),
+				"misses", atomic.LoadUint64(&c.misses),
+				"evictions", atomic.LoadUint64(&c.evictions),
+			)
+		}
+	}
+}
+
+func (v *Verifier) caches() []*ttlCache {
+	return []*ttlCache{v.claims, v.publishers, v.posts, v.dkimKeys, v.claimSearches}
+}
+
+// delete removes key from c, returning its value if it was cached.
+func (c *ttlCache) delete(key string) (value interface{}, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	delete(c.entries, key)
+	return e.value, ok
+}
+
+// flush removes every entry and returns how many there were.
+func (c *ttlCache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	c.entries = make(map[string]cacheEntry)
+	return n
+}
+
+// FlushCaches empties all of v's caches and returns how many entries were
+// evicted.
+func (v *Verifier) FlushCaches() int {
+	n := 0
+	for _, c := range v.caches() {
+		n += c.flush()
+	}
+	return n
+}
+
+// InvalidateClaim evicts the cached claim txid along with the cached posts it
+// references and the publisher records those and the claim point at, so that
+// the next check of the claim fetches all of them again. It returns how many
+// entries were evicted.
+func (v *Verifier) InvalidateClaim(txid string) int {
+	value, ok := v.claims.delete(txid)
+	if !ok {
+		return 0
+	}
+	n := 1
+	vc := value.(claimRecord).claim
+	if vc == nil {
+		return n
+	}
+	publishers := map[string]bool{vc.RegisteredPublisher: true}
+	for _, p := range v.platforms {
+		id := p.ClaimID(vc)
+		if id == "" {
+			continue
+		}
+		value, ok := v.posts.delete(p.Verifier.Name() + ":" + id)
+		if !ok {
+			continue
+		}
+		n++
+		if proof, _ := value.(*Proof); proof != nil && proof.Txid != "" {
+			publishers[proof.Txid] = true
+		}
+	}
+	for pub := range publishers {
+		if _, ok := v.publishers.delete(pub); ok {
+			n++
+		}
+		if _, ok := v.claimSearches.delete(pub); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// CacheStats describes one of a Verifier's caches.
+type CacheStats struct {
+	Name     string  `json:"name"`
+	Entries  int     `json:"entries"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+	// Bytes is a rough estimate of the memory the entries hold.
+	Bytes int `json:"bytes"`
+}
+
+// CacheStats reports the size and hit ratio of each of v's caches.
+func (v *Verifier) CacheStats() []CacheStats {
+	var stats []CacheStats
+	for _, c := range v.caches() {
+		s := CacheStats{Name: c.name, Hits: atomic.LoadUint64(&c.hits), Misses: atomic.LoadUint64(&c.misses)}
+		if total := s.Hits + s.Misses; total > 0 {
+			s.HitRatio = float64(s.Hits) / float64(total)
+		}
+		c.mu.Lock()
+		s.Entries = len(c.entries)
+		seen := make(map[uintptr]bool)
+		for k, e := range c.entries {
+			s.Bytes += len(k) + int(reflect.TypeOf(e).Size()) + sizeOf(reflect.ValueOf(e.value), seen)
+		}
+		c.mu.Unlock()
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// sizeOf estimates the memory v refers to beyond its own size, counting what
+// pointers in seen refer to only once.
+func sizeOf(v reflect.Value, seen map[uintptr]bool) int {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+		seen[v.Pointer()] = true
+		return int(v.Type().Elem().Size()) + sizeOf(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return int(v.Elem().Type().Size()) + sizeOf(v.Elem(), seen)
+	case reflect.String:
+		return v.Len()
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < v.NumField(); i++ {
+			n += sizeOf(v.Field(i), seen)
+		}
+		return n
+	case reflect.Slice:
+		n := v.Cap() * int(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOf(v.Index(i), seen)
+		}
+		return n
+	case reflect.Map:
+		n := 0
+		iter := v.MapRange()
+		for iter.Next() {
+			n += int(iter.Key().Type().Size()+iter.Value().Type().Size()) + sizeOf(iter.Key(), seen) + sizeOf(iter.Value(), seen)
+		}
+		return n
+	}
+	return 0
+}
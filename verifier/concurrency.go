@@ -0,0 +1,71 @@
+package verifier
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// upstreamLimit bounds the requests in flight to one upstream target.
+type upstreamLimit struct {
+	sem            *semaphore.Weighted
+	inUse, waiting int64
+}
+
+type upstreamLimits struct {
+	mu sync.Mutex
+	m  map[string]*upstreamLimit
+}
+
+// limitFor returns the limit of target, or nil if MaxConcurrent has none.
+func (v *Verifier) limitFor(target string) *upstreamLimit {
+	n := v.MaxConcurrent[target]
+	if n <= 0 {
+		return nil
+	}
+	v.limits.mu.Lock()
+	defer v.limits.mu.Unlock()
+	l, ok := v.limits.m[target]
+	if !ok {
+		if v.limits.m == nil {
+			v.limits.m = make(map[string]*upstreamLimit)
+		}
+		l = &upstreamLimit{sem: semaphore.NewWeighted(n)}
+		v.limits.m[target] = l
+	}
+	return l
+}
+
+// acquireUpstream waits until a request to target may be made, or ctx is
+// done. The returned function must be called once the request is over.
+func (v *Verifier) acquireUpstream(ctx context.Context, target string) (release func(), err error) {
+	l := v.limitFor(target)
+	if l == nil {
+		return func() {}, nil
+	}
+	v.observeConcurrency(target, atomic.LoadInt64(&l.inUse), atomic.AddInt64(&l.waiting, 1))
+	err = l.sem.Acquire(ctx, 1)
+	waiting := atomic.AddInt64(&l.waiting, -1)
+	if err != nil {
+		v.observeConcurrency(target, atomic.LoadInt64(&l.inUse), waiting)
+		return nil, err
+	}
+	v.observeConcurrency(target, atomic.AddInt64(&l.inUse, 1), waiting)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inUse := atomic.AddInt64(&l.inUse, -1)
+			l.sem.Release(1)
+			v.observeConcurrency(target, inUse, atomic.LoadInt64(&l.waiting))
+		})
+	}, nil
+}
+
+func (v *Verifier) observeConcurrency(target string, inUse, waiting int64) {
+	if v.Hooks.Concurrency != nil {
+		v.Hooks.Concurrency(target, int(inUse), int(waiting))
+	}
+}
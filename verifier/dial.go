@@ -0,0 +1,88 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"syscall"
+)
+
+// ErrNotPublic is the cause of the dial errors of requests to hosts taken
+// from claim records that resolved to loopback, private, link-local or
+// unspecified addresses.
+var ErrNotPublic = errors.New("address is not public")
+
+type claimHostKey struct{}
+
+// toClaimHost marks the requests of ctx as going to a host taken from a
+// claim or publisher record, which GuardDialer only connects to at public
+// addresses.
+func toClaimHost(ctx context.Context) context.Context {
+	return context.WithValue(ctx, claimHostKey{}, true)
+}
+
+// ToClaimHost reports whether the requests of ctx go to a host taken from a
+// claim or publisher record.
+func ToClaimHost(ctx context.Context) bool {
+	b, _ := ctx.Value(claimHostKey{}).(bool)
+	return b
+}
+
+// GuardDialer returns a DialContext for the transport of HTTPClient that
+// dials with d, but refuses to connect requests to hosts taken from claim
+// records to addresses that aren't public, so that claims can't make the
+// verifier probe internal services. The addresses are checked once the host
+// is resolved, so DNS can't get around it. Connections to proxies, given as
+// host:port, are always allowed, since the proxy makes those requests.
+func GuardDialer(d *net.Dialer, proxies ...string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	guarded := *d
+	control := d.Control
+	guarded.Control = func(network, address string, c syscall.RawConn) error {
+		err := checkPublic(address)
+		if err == nil && control != nil {
+			err = control(network, address, c)
+		}
+		return err
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if ToClaimHost(ctx) && !slices.Contains(proxies, addr) {
+			return guarded.DialContext(ctx, network, addr)
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// checkPublic returns an error unless address, an ip:port, is a public
+// address.
+func checkPublic(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return fmt.Errorf("%s: %w", host, ErrNotPublic)
+	}
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	// 0.0.0.0/8 reaches the local host on some systems
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return false
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// notPublicError returns the PlatformError of err when it was a request to
+// host that GuardDialer refused, and err unchanged otherwise.
+func notPublicError(err error, host string) error {
+	if errors.Is(err, ErrNotPublic) {
+		return &PlatformError{Code: CodeInvalidId, Msg: host + " does not resolve to a public address"}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:93.184.216.34", true},
+	}
+	for _, tt := range tests {
+		if got := isPublic(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
+
+func guardedClient(proxies ...string) *http.Client {
+	d := &net.Dialer{Timeout: time.Second}
+	return &http.Client{Transport: &http.Transport{DialContext: GuardDialer(d, proxies...)}}
+}
+
+func TestGuardDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	addr := ts.Listener.Addr().String()
+
+	get := func(client *http.Client, ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		res, err := client.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	ctx := context.Background()
+	if err := get(guardedClient(), toClaimHost(ctx)); !errors.Is(err, ErrNotPublic) {
+		t.Errorf("claim host on loopback: got %v, want ErrNotPublic", err)
+	}
+	if err := get(guardedClient(), ctx); err != nil {
+		t.Errorf("configured host on loopback: %v", err)
+	}
+	if err := get(guardedClient(addr), toClaimHost(ctx)); err != nil {
+		t.Errorf("claim host through proxy on loopback: %v", err)
+	}
+}
+
+func TestGuardedPlatforms(t *testing.T) {
+	var requests int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+	client := ts.Client()
+	client.Transport.(*http.Transport).DialContext = GuardDialer(&net.Dialer{Timeout: time.Second})
+	v := New(nil, "http://oip.invalid", client)
+	ctx := context.Background()
+
+	_, err := v.VerifyFLOProof(ctx, ts.URL+"/proof.txt")
+	var pe *PlatformError
+	if !errors.As(err, &pe) || pe.Code != CodeInvalidId {
+		t.Errorf("VerifyFLOProof of a loopback URL: got %v, want %s", err, CodeInvalidId)
+	}
+	u, _ := url.Parse(ts.URL)
+	_, _, _, err = v.VerifyWebsite(ctx, u.Host)
+	if !errors.As(err, &pe) || pe.Code != CodeInvalidId {
+		t.Errorf("VerifyWebsite of a loopback host: got %v, want %s", err, CodeInvalidId)
+	}
+	if requests != 0 {
+		t.Errorf("loopback server got %d requests, want 0", requests)
+	}
+
+	// the operator's own GitLab instance may be internal
+	v.GitLabURL = ts.URL
+	_, _, _, err = v.VerifyGitLabSnippet(ctx, "12345")
+	if !errors.As(err, &pe) || pe.Code != CodePostNotFound {
+		t.Errorf("VerifyGitLabSnippet on GitLabURL: got %v, want %s", err, CodePostNotFound)
+	}
+	if requests != 1 {
+		t.Errorf("GitLabURL got %d requests, want 1", requests)
+	}
+}
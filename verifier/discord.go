@@ -0,0 +1,132 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type discordMessage struct {
+	Content string `json:"content"`
+	Author  struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+type discordError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Discord JSON error codes
+const (
+	discordUnknownChannel = 10003
+	discordUnknownMessage = 10008
+	discordMissingAccess  = 50001
+	discordMissingPerms   = 50013
+)
+
+var discordMessageRegex = regexp.MustCompile(`^([0-9]{15,21})/([0-9]{15,21})$`)
+
+// VerifyDiscord fetches the message channelId/messageId with DiscordBotToken
+// and returns the verification statement in its content along with its
+// author's username and the name of the server it was posted in.
+func (v *Verifier) VerifyDiscord(ctx context.Context, ref string) (*Proof, error) {
+	m := discordMessageRegex.FindStringSubmatch(ref)
+	if m == nil {
+		return nil, ErrInvalidID
+	}
+	if v.DiscordBotToken == "" {
+		return nil, &PlatformError{Code: CodeNotConfigured, Msg: "Discord verification is not configured on this server"}
+	}
+
+	msg := &discordMessage{}
+	err := v.discordGet(ctx, "/channels/"+m[1]+"/messages/"+m[2], msg)
+	if err != nil {
+		return nil, err
+	}
+	proof := &Proof{Author: msg.Author.Username}
+
+	// the guild is only for display, so failing to find it isn't fatal
+	var channel struct {
+		GuildID string `json:"guild_id"`
+	}
+	var guild struct {
+		Name string `json:"name"`
+	}
+	if v.discordGet(ctx, "/channels/"+m[1], &channel) == nil && channel.GuildID != "" && v.discordGet(ctx, "/guilds/"+channel.GuildID, &guild) == nil {
+		proof.Details = map[string]string{"guild": guild.Name, "guild_id": channel.GuildID}
+	}
+
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, msg.Content)
+	return proof, err
+}
+
+func (v *Verifier) discordGet(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://discord.com/api/v10"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bot "+v.DiscordBotToken)
+	body, err := v.doRequest("discord", req, v.MaxBodySize)
+	var se *StatusError
+	if errors.As(err, &se) {
+		de := &discordError{}
+		_ = json.Unmarshal(se.Body, de)
+		switch {
+		case se.StatusCode == http.StatusTooManyRequests:
+			return &RateLimitError{Target: "discord", Reset: discordReset(se.Header)}
+		case se.StatusCode == http.StatusUnauthorized:
+			return &PlatformError{Code: CodeNotConfigured, Msg: "Discord rejected this server's bot token"}
+		case de.Code == discordMissingAccess || de.Code == discordMissingPerms || se.StatusCode == http.StatusForbidden:
+			return &PlatformError{Code: CodeNotPublic, Msg: "This server's Discord bot can't read the channel; add it to the server or allow it to read the channel"}
+		case de.Code == discordUnknownMessage || de.Code == discordUnknownChannel || se.StatusCode == http.StatusNotFound:
+			return &PlatformError{Code: CodePostNotFound, Msg: "Discord message does not exist or has been deleted"}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		return &DecodeError{URL: "discord " + path, Err: err}
+	}
+	return nil
+}
+
+// discordReset returns when a rate limited Discord request may be retried,
+// from the fractional seconds in Retry-After or X-RateLimit-Reset-After.
+func discordReset(h http.Header) time.Time {
+	for _, name := range []string{"Retry-After", "X-RateLimit-Reset-After"} {
+		if s, err := strconv.ParseFloat(h.Get(name), 64); err == nil {
+			return time.Now().Add(time.Duration(math.Ceil(s*1000)) * time.Millisecond)
+		}
+	}
+	return time.Now().Add(time.Minute)
+}
+
+type discordPlatform struct {
+	v *Verifier
+}
+
+func (d discordPlatform) Name() string {
+	return "discord"
+}
+
+func (d discordPlatform) Verify(ctx context.Context, ref string) (string, string, error) {
+	proof, err := d.v.VerifyDiscord(ctx, ref)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (d discordPlatform) VerifyProof(ctx context.Context, ref string) (*Proof, error) {
+	return d.v.VerifyDiscord(ctx, ref)
+}
@@ -0,0 +1,84 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const dnsVerificationPrefix = "_oip-verification."
+
+var (
+	domainRegex    = regexp.MustCompile(`^(?i:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}\.?$`)
+	dnsRecordRegex = regexp.MustCompile(`^oip-verify=([0-9a-f]{64})(?:;name=(.+))?$`)
+)
+
+// resolver returns the resolver for DNS lookups and a description of the
+// nameserver it queries.
+func (v *Verifier) resolver() (*net.Resolver, string) {
+	if v.DNSResolver == "" {
+		return net.DefaultResolver, "system resolver"
+	}
+	var d net.Dialer
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, v.DNSResolver)
+		},
+	}, v.DNSResolver
+}
+
+// VerifyDNS looks up the TXT records of _oip-verification.<domain> and returns
+// the publisher txid, and name if given, from the first record of the form
+// oip-verify=<txid>[;name=<name>], along with the nameserver that answered.
+// The resolver follows CNAMEs.
+func (v *Verifier) VerifyDNS(ctx context.Context, domain string) (name string, txid string, nameserver string, err error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if !domainRegex.MatchString(domain) {
+		return "", "", "", ErrInvalidID
+	}
+	if v.DNSTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.DNSTimeout)
+		defer cancel()
+	}
+
+	r, nameserver := v.resolver()
+	host := dnsVerificationPrefix + domain
+	records, err := r.LookupTXT(ctx, host)
+	if err != nil {
+		var de *net.DNSError
+		if errors.As(err, &de) && de.IsNotFound {
+			return "", "", nameserver, &PlatformError{Code: CodeDomainNotFound, Msg: host + " does not exist (NXDOMAIN from " + nameserver + ")"}
+		}
+		return "", "", nameserver, &UnavailableError{What: "nameserver " + nameserver, Err: err}
+	}
+
+	for _, record := range records {
+		m := dnsRecordRegex.FindStringSubmatch(strings.TrimSpace(record))
+		if m != nil {
+			return m[2], m[1], nameserver, nil
+		}
+	}
+	return "", "", nameserver, &PlatformError{Code: CodeBadFormat, Msg: "No oip-verify TXT record found at " + host + " (answered by " + nameserver + ")"}
+}
+
+type dnsPlatform struct {
+	v *Verifier
+}
+
+func (d dnsPlatform) Name() string {
+	return "dns"
+}
+
+func (d dnsPlatform) Verify(ctx context.Context, domain string) (string, string, error) {
+	name, txid, _, err := d.v.VerifyDNS(ctx, domain)
+	return name, txid, err
+}
+
+func (d dnsPlatform) VerifyProof(ctx context.Context, domain string) (*Proof, error) {
+	name, txid, nameserver, err := d.v.VerifyDNS(ctx, domain)
+	return &Proof{Name: name, Txid: txid, Source: nameserver}, err
+}
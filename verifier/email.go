@@ -0,0 +1,250 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// emailMaxBodySize caps downloaded messages.
+const emailMaxBodySize = 256 << 10
+
+// VerifyEmail downloads the raw RFC 5322 message at messageURL, checks that
+// it has a valid DKIM signature from its From domain, and returns the
+// verification statement in its text body. The From domain must be the
+// domain of the publisher the statement names, or a subdomain of it.
+func (v *Verifier) VerifyEmail(ctx context.Context, messageURL string) (*Proof, error) {
+	u, err := url.Parse(messageURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidID
+	}
+
+	req, err := http.NewRequestWithContext(toClaimHost(ctx), http.MethodGet, messageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "message/rfc822, text/plain")
+	raw, err := v.doRequest("email", req, emailMaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, &PlatformError{Code: CodePostNotFound, Msg: "No message at " + messageURL}
+		}
+		if errors.Is(err, ErrBodyTooLarge) {
+			return nil, &PlatformError{Code: CodeBadFormat, Msg: "Message at " + messageURL + " is larger than 256KB"}
+		}
+		return nil, err
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Message at " + messageURL + " is not an RFC 5322 message"}
+	}
+	// a second From could go unsigned while the first is shown to people
+	// (RFC 7489 section 6.6.1)
+	if len(msg.Header["From"]) > 1 {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Message at " + messageURL + " has more than one From header"}
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil || !strings.Contains(from.Address, "@") {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Message at " + messageURL + " has no valid From address"}
+	}
+	fromDomain := strings.ToLower(from.Address[strings.LastIndex(from.Address, "@")+1:])
+	proof := &Proof{Author: from.Address, Details: map[string]string{"from_domain": fromDomain}}
+
+	signer, err := v.dkimSigner(ctx, raw, msg.Header, fromDomain)
+	if err != nil {
+		var ue *UnavailableError
+		if errors.As(err, &ue) {
+			return proof, err
+		}
+		return proof, &PlatformError{Code: CodeDKIMFail, Msg: "Message has no valid DKIM signature from " + fromDomain + ": " + err.Error()}
+	}
+	proof.Details["dkim_domain"] = signer
+
+	text, err := mailText(msg)
+	if err != nil {
+		return proof, &PlatformError{Code: CodeBadFormat, Msg: "Unable to read the text of the message at " + messageURL + ": " + err.Error()}
+	}
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, text)
+	if err != nil {
+		return proof, err
+	}
+
+	pub, _, err := v.getPublisher(ctx, proof.Txid)
+	if err != nil {
+		if _, upstream := UpstreamMsg(err); upstream {
+			return proof, err
+		}
+		return proof, &PlatformError{Code: CodePublisherNotFound, Msg: "Unable to locate publisher with ID " + proof.Txid}
+	}
+	domain := strings.TrimSuffix(strings.ToLower(pub.Domain), ".")
+	if domain == "" {
+		return proof, &PlatformError{Code: CodeDomainMismatch, Msg: "Publisher " + proof.Txid + " has no domain"}
+	}
+	if !inDomain(fromDomain, domain) {
+		return proof, &PlatformError{Code: CodeDomainMismatch, Msg: "Message is from " + fromDomain + " but publisher's domain is " + domain}
+	}
+	return proof, nil
+}
+
+// dkimSigner returns the domain of the first valid DKIM signature of raw,
+// whose header is header, that is aligned with fromDomain, i.e. the same
+// domain or a parent of it. Signatures with a body length tag aren't valid,
+// since text could be appended after the signed part of the body. Selector
+// keys are looked up with the configured resolver and cached.
+func (v *Verifier) dkimSigner(ctx context.Context, raw []byte, header mail.Header, fromDomain string) (string, error) {
+	var lookupErr error
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(raw), &dkim.VerifyOptions{
+		MaxVerifications: 5,
+		LookupTXT: func(name string) ([]string, error) {
+			records, err := v.lookupDKIMKey(ctx, name)
+			if err != nil && lookupErr == nil {
+				lookupErr = err
+			}
+			return records, err
+		},
+	})
+	if err != nil && !errors.Is(err, dkim.ErrTooManySignatures) {
+		return "", err
+	}
+
+	// the verifications are in the order of the signatures
+	signatures := header["Dkim-Signature"]
+	var failure error = errors.New("message is not DKIM signed")
+	for i, ver := range verifications {
+		if ver.Err != nil {
+			failure = ver.Err
+			continue
+		}
+		d := strings.ToLower(ver.Domain)
+		if i < len(signatures) && hasBodyLength(signatures[i]) {
+			failure = errors.New("signature from " + d + " has a body length tag")
+			continue
+		}
+		if inDomain(fromDomain, d) {
+			return d, nil
+		}
+		failure = errors.New("signature is from " + d)
+	}
+	var ue *UnavailableError
+	if errors.As(lookupErr, &ue) {
+		return "", lookupErr
+	}
+	return "", failure
+}
+
+// lookupDKIMKey looks up the TXT records of a DKIM selector through the
+// DKIM key cache.
+func (v *Verifier) lookupDKIMKey(ctx context.Context, name string) ([]string, error) {
+	r, err := v.cached(ctx, v.dkimKeys, name, func() (interface{}, error) {
+		if v.DNSTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, v.DNSTimeout)
+			defer cancel()
+		}
+		resolver, nameserver := v.resolver()
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			var de *net.DNSError
+			if !errors.As(err, &de) || !de.IsNotFound {
+				return []string(nil), &UnavailableError{What: "nameserver " + nameserver, Err: err}
+			}
+		}
+		return records, err
+	})
+	return r.([]string), err
+}
+
+// hasBodyLength reports whether the DKIM-Signature header value signature
+// has an l= tag.
+func hasBodyLength(signature string) bool {
+	for _, tag := range strings.Split(signature, ";") {
+		if name, _, ok := strings.Cut(tag, "="); ok && strings.TrimSpace(name) == "l" {
+			return true
+		}
+	}
+	return false
+}
+
+// inDomain reports whether host is domain or a subdomain of it.
+func inDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// mailText returns the text/plain body of msg, or of the first text/plain
+// part of a multipart message, decoded.
+func mailText(msg *mail.Message) (string, error) {
+	return partText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+}
+
+func partText(contentType, encoding string, r io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		mediaType, err = "text/plain", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				return "", errors.New("no text/plain part")
+			}
+			text, err := partText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err == nil {
+				return text, nil
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", errors.New("no text/plain part")
+	}
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(string(text), "\r\n", "\n", -1), nil
+}
+
+type emailPlatform struct {
+	v *Verifier
+}
+
+func (e emailPlatform) Name() string {
+	return "email"
+}
+
+func (e emailPlatform) Verify(ctx context.Context, messageURL string) (string, string, error) {
+	proof, err := e.v.VerifyEmail(ctx, messageURL)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (e emailPlatform) VerifyProof(ctx context.Context, messageURL string) (*Proof, error) {
+	return e.v.VerifyEmail(ctx, messageURL)
+}
@@ -0,0 +1,100 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// signedMessage returns a message from publisher@example.com with headers
+// prepended to its own, signed by example.com with the key of the selector
+// "test", which v is given.
+func signedMessage(t *testing.T, v *Verifier, headers string) string {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.dkimKeys.entries["test._domainkey.example.com"] = cacheEntry{
+		value:   []string{"v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub)},
+		expires: time.Now().Add(time.Hour),
+	}
+	raw := headers + "From: Example Publisher <publisher@example.com>\r\n" +
+		"Subject: Verification\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		strings.Replace(statement(testPubName, testPubTxid), "\n", "\r\n", -1) + "\r\n"
+	var signed bytes.Buffer
+	err = dkim.Sign(&signed, strings.NewReader(raw), &dkim.SignOptions{
+		Domain:     "example.com",
+		Selector:   "test",
+		Signer:     priv,
+		HeaderKeys: []string{"From", "Subject", "Content-Type"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed.String()
+}
+
+func TestVerifyEmail(t *testing.T) {
+	u := newUpstream()
+	u.records[testPubTxid] = map[string]interface{}{"tmpl_433C2783": map[string]string{"name": testPubName, "domain": "example.com"}}
+	v := newTestVerifier(u)
+	valid := signedMessage(t, v, "")
+	tests := []struct {
+		name    string
+		message string
+		code    string
+	}{
+		{"valid", valid, ""},
+		{"second From", strings.Replace(valid, "\r\nSubject:", "\r\nFrom: attacker@evil.example\r\nSubject:", 1), CodeBadFormat},
+		{"unsigned From", "From: attacker@evil.example\r\n" + valid, CodeBadFormat},
+		{"body length", strings.Replace(valid, "DKIM-Signature: ", "DKIM-Signature: l=10; ", 1), CodeDKIMFail},
+		{"appended body", valid + "\r\nMore text\r\n", CodeDKIMFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u.handle("mail.example.com", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "message/rfc822")
+				w.Write([]byte(tt.message))
+			})
+			proof, err := v.VerifyEmail(context.Background(), "https://mail.example.com/verification.eml")
+			if tt.code != "" {
+				checkCode(t, tt.name, err, tt.code)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.Txid != testPubTxid || proof.Details["dkim_domain"] != "example.com" {
+				t.Errorf("got %+v", proof)
+			}
+		})
+	}
+}
+
+func TestHasBodyLength(t *testing.T) {
+	tests := []struct {
+		signature string
+		want      bool
+	}{
+		{"v=1; a=ed25519-sha256; d=example.com; s=test; l=120; bh=abc; b=def", true},
+		{"v=1; a=ed25519-sha256;\r\n l = 0; b=def", true},
+		{"v=1; a=ed25519-sha256; d=example.com; s=test; bh=abc; b=def", false},
+		{"v=1; d=l.example.com; s=l; b=def", false},
+	}
+	for _, tt := range tests {
+		if got := hasBodyLength(tt.signature); got != tt.want {
+			t.Errorf("hasBodyLength(%q) = %v, want %v", tt.signature, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+type facebookPost struct {
+	Message string `json:"message"`
+	From    struct {
+		Name string `json:"name"`
+	} `json:"from"`
+}
+
+type facebookError struct {
+	Error struct {
+		Message      string `json:"message"`
+		Code         int    `json:"code"`
+		ErrorSubcode int    `json:"error_subcode"`
+	} `json:"error"`
+}
+
+// facebookBackoff is when Graph API requests may resume after Facebook
+// reported the app's rate limit as used up.
+type facebookBackoff struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+const facebookGraphAPI = "https://graph.facebook.com/v19.0/"
+
+var facebookPostIDRegex = regexp.MustCompile(`^[0-9]+(?:_[0-9]+)?$`)
+
+// VerifyFacebook fetches the post pageId_postId with the Graph API and
+// returns the publisher name and txid from the verification statement in
+// its message along with the name of the page or user that posted it.
+func (v *Verifier) VerifyFacebook(ctx context.Context, id string) (name string, txid string, from string, err error) {
+	if !facebookPostIDRegex.MatchString(id) {
+		return "", "", "", ErrInvalidID
+	}
+	if v.FacebookToken == "" {
+		return "", "", "", &PlatformError{Code: CodeNotConfigured, Msg: "Facebook verification is not configured on this server"}
+	}
+
+	v.facebookBackoff.mu.Lock()
+	until := v.facebookBackoff.until
+	v.facebookBackoff.mu.Unlock()
+	if time.Now().Before(until) {
+		return "", "", "", &RateLimitError{Target: "facebook", Reset: until}
+	}
+
+	q := url.Values{"fields": {"message,from{name}"}, "access_token": {v.FacebookToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, facebookGraphAPI+id+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	body, err := v.doRequest("facebook", req, v.MaxBodySize)
+	var se *StatusError
+	if errors.As(err, &se) {
+		// the URL has the token in it
+		se.URL = "facebook post " + id
+		if e := v.facebookError(id, se); e != nil {
+			return "", "", "", e
+		}
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+
+	post := &facebookPost{}
+	err = json.Unmarshal(body, post)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "facebook post " + id, Err: err}
+	}
+	name, txid, err = v.matchVerification(ctx, post.Message)
+	return name, txid, post.From.Name, err
+}
+
+// facebookError maps the Graph API error codes in se's body to a
+// PlatformError or RateLimitError, or returns nil for other errors.
+func (v *Verifier) facebookError(id string, se *StatusError) error {
+	fe := &facebookError{}
+	if json.Unmarshal(se.Body, fe) != nil {
+		return nil
+	}
+	switch fe.Error.Code {
+	case 4, 17, 32, 613:
+		return v.facebookRateLimited(se.Header)
+	case 10, 200:
+		return &PlatformError{Code: CodeNotPublic, Msg: "Facebook post " + id + " is not public or this server's token lacks permission to read it"}
+	case 100:
+		if fe.Error.ErrorSubcode == 33 {
+			return &PlatformError{Code: CodeNotPublic, Msg: "Facebook post " + id + " does not exist or is not public"}
+		}
+		return &PlatformError{Code: CodePostNotFound, Msg: "Facebook post " + id + " does not exist"}
+	case 190:
+		return &PlatformError{Code: CodeTokenExpired, Msg: "Facebook rejected this server's app token"}
+	}
+	return nil
+}
+
+// facebookRateLimited backs off Graph API requests for as long as Facebook
+// asks in the X-Business-Use-Case-Usage header, or an hour, which is the
+// window app limits are computed over.
+func (v *Verifier) facebookRateLimited(h http.Header) *RateLimitError {
+	wait := time.Hour
+	var usage map[string][]struct {
+		EstimatedTimeToRegainAccess int `json:"estimated_time_to_regain_access"`
+	}
+	if json.Unmarshal([]byte(h.Get("X-Business-Use-Case-Usage")), &usage) == nil {
+		for _, entries := range usage {
+			for _, e := range entries {
+				if e.EstimatedTimeToRegainAccess > 0 {
+					wait = time.Duration(e.EstimatedTimeToRegainAccess) * time.Minute
+				}
+			}
+		}
+	}
+
+	rl := &RateLimitError{Target: "facebook", Reset: time.Now().Add(wait)}
+	v.facebookBackoff.mu.Lock()
+	v.facebookBackoff.until = rl.Reset
+	v.facebookBackoff.mu.Unlock()
+	if v.Hooks.RateLimit != nil {
+		v.Hooks.RateLimit("facebook", 0)
+	}
+	return rl
+}
+
+type facebookPlatform struct {
+	v *Verifier
+}
+
+func (f facebookPlatform) Name() string {
+	return "facebook"
+}
+
+func (f facebookPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := f.v.VerifyFacebook(ctx, id)
+	return name, txid, err
+}
+
+func (f facebookPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return f.v.VerifyFacebook(ctx, id)
+}
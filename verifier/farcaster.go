@@ -0,0 +1,118 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type farcasterMessage struct {
+	Hash string `json:"hash"`
+	Data struct {
+		Fid         uint64 `json:"fid"`
+		CastAddBody struct {
+			Text string `json:"text"`
+		} `json:"castAddBody"`
+		UserDataBody struct {
+			Value string `json:"value"`
+		} `json:"userDataBody"`
+	} `json:"data"`
+}
+
+var (
+	farcasterCastRegex = regexp.MustCompile(`^([0-9]+)/(0x[0-9a-f]{40})$`)
+	warpcastURLRegex   = regexp.MustCompile(`^https://warpcast\.com/([a-z0-9][a-z0-9.-]{0,19})/(0x[0-9a-f]{8,40})$`)
+)
+
+// VerifyFarcaster fetches the cast, given as fid/0xhash or a Warpcast URL,
+// from FarcasterHub and returns the publisher name and txid from its
+// verification statement along with its author's fname.
+func (v *Verifier) VerifyFarcaster(ctx context.Context, ref string) (name string, txid string, fname string, err error) {
+	ref = strings.ToLower(ref)
+	var cast *farcasterMessage
+	if m := farcasterCastRegex.FindStringSubmatch(ref); m != nil {
+		cast = &farcasterMessage{}
+		err = v.farcasterGet(ctx, "/v1/castById", url.Values{"fid": {m[1]}, "hash": {m[2]}}, cast)
+	} else if m := warpcastURLRegex.FindStringSubmatch(ref); m != nil {
+		cast, err = v.farcasterFindCast(ctx, m[1], m[2])
+	} else {
+		return "", "", "", ErrInvalidID
+	}
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusBadRequest) {
+			return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Farcaster cast " + ref + " does not exist or has been pruned from the hub"}
+		}
+		return "", "", "", err
+	}
+
+	var user farcasterMessage
+	err = v.farcasterGet(ctx, "/v1/userDataByFid", url.Values{"fid": {strconv.FormatUint(cast.Data.Fid, 10)}, "user_data_type": {"6"}}, &user)
+	if err == nil {
+		fname = user.Data.UserDataBody.Value
+	}
+
+	name, txid, err = v.matchVerification(ctx, cast.Data.CastAddBody.Text)
+	return name, txid, fname, err
+}
+
+// farcasterFindCast finds the cast by fname whose hash starts with prefix,
+// as in Warpcast URLs, among the fid's most recent casts.
+func (v *Verifier) farcasterFindCast(ctx context.Context, fname, prefix string) (*farcasterMessage, error) {
+	var proof struct {
+		Fid uint64 `json:"fid"`
+	}
+	err := v.farcasterGet(ctx, "/v1/userNameProofByName", url.Values{"name": {fname}}, &proof)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusBadRequest) {
+			return nil, &PlatformError{Code: CodeHandleNotFound, Msg: "Unable to resolve Farcaster name " + fname}
+		}
+		return nil, err
+	}
+
+	var casts struct {
+		Messages []farcasterMessage `json:"messages"`
+	}
+	err = v.farcasterGet(ctx, "/v1/castsByFid", url.Values{"fid": {strconv.FormatUint(proof.Fid, 10)}, "reverse": {"true"}, "pageSize": {"1000"}}, &casts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range casts.Messages {
+		if strings.HasPrefix(casts.Messages[i].Hash, prefix) {
+			return &casts.Messages[i], nil
+		}
+	}
+	return nil, &StatusError{URL: "farcaster cast " + prefix, StatusCode: http.StatusNotFound}
+}
+
+func (v *Verifier) farcasterGet(ctx context.Context, path string, q url.Values, out interface{}) error {
+	body, err := v.httpGet(ctx, "farcaster", v.FarcasterHub+path+"?"+q.Encode())
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		return &DecodeError{URL: "farcaster " + path, Err: err}
+	}
+	return nil
+}
+
+type farcasterPlatform struct {
+	v *Verifier
+}
+
+func (f farcasterPlatform) Name() string {
+	return "farcaster"
+}
+
+func (f farcasterPlatform) Verify(ctx context.Context, ref string) (string, string, error) {
+	name, txid, _, err := f.v.VerifyFarcaster(ctx, ref)
+	return name, txid, err
+}
+
+func (f farcasterPlatform) VerifyAuthor(ctx context.Context, ref string) (string, string, string, error) {
+	return f.v.VerifyFarcaster(ctx, ref)
+}
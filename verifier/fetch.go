@@ -0,0 +1,667 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type claimRecord struct {
+	claim *VerificationClaim
+	meta  *RMeta
+}
+
+func (v *Verifier) getVerificationClaim(ctx context.Context, txid string) (_ *VerificationClaim, _ *RMeta, err error) {
+	ctx, span := startSpan(ctx, "getVerificationClaim", attribute.String("txid", txid))
+	defer func() { endSpan(span, err) }()
+	r, err := v.cached(ctx, v.claims, txid, func() (interface{}, error) {
+		claim, meta, err := v.fetchVerificationClaim(ctx, txid)
+		return claimRecord{claim, meta}, err
+	})
+	cr := r.(claimRecord)
+	return cr.claim, cr.meta, err
+}
+
+func (v *Verifier) fetchVerificationClaim(ctx context.Context, txid string) (*VerificationClaim, *RMeta, error) {
+	body, err := v.oipGet(ctx, "o5", "record", "get", txid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := &oipApiResult{}
+	err = json.Unmarshal(body, results)
+	if err != nil {
+		return nil, nil, &DecodeError{URL: "verification claim " + txid, Err: err}
+	}
+
+	if len(results.Results) == 1 {
+		return &results.Results[0].Record.Details.VerificationClaim, &results.Results[0].Meta, nil
+	}
+
+	return nil, nil, ErrClaimNotFound
+}
+
+var (
+	tweetIDRegex  = regexp.MustCompile(`^[0-9]{1,20}$`)
+	tweetURLRegex = regexp.MustCompile(`^https?://(?:www\.|mobile\.)?(?:twitter|x)\.com/([A-Za-z0-9_]{1,15})/status(?:es)?/([0-9]{1,20})/?(?:\?.*)?$`)
+	gabIDRegex    = regexp.MustCompile(`^[0-9]{1,20}$`)
+	gabURLRegex   = regexp.MustCompile(`^https?://(?:www\.)?gab\.com/([A-Za-z0-9_]{1,30})/posts/([0-9]{1,20})/?(?:\?.*)?$`)
+)
+
+// parseTweetRef returns the status id, and the handle when ref is a URL, of
+// a bare tweet id or a twitter.com, mobile.twitter.com or x.com status URL.
+func parseTweetRef(ref string) (id int64, handle string, err error) {
+	s := ref
+	if m := tweetURLRegex.FindStringSubmatch(ref); m != nil {
+		handle, s = m[1], m[2]
+	} else if !tweetIDRegex.MatchString(ref) {
+		return 0, "", &PlatformError{Code: CodeInvalidId, Msg: "twitterId is not a tweet id or URL"}
+	}
+	id, err = strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, "", &PlatformError{Code: CodeInvalidId, Msg: "twitterId is not a tweet id or URL"}
+	}
+	return id, handle, nil
+}
+
+// twitterClaimHandle returns the claim's twitterHandle, or else the handle in
+// its tweet URL.
+func twitterClaimHandle(vc *VerificationClaim) string {
+	if vc.TwitterHandle != "" {
+		return vc.TwitterHandle
+	}
+	_, handle, _ := parseTweetRef(vc.TwitterId)
+	return handle
+}
+
+// parseGabRef returns the post id, and the username when ref is a URL, of a
+// bare Gab post id or a gab.com/username/posts/id URL.
+func parseGabRef(ref string) (id string, username string, err error) {
+	if m := gabURLRegex.FindStringSubmatch(ref); m != nil {
+		return m[2], m[1], nil
+	}
+	if !gabIDRegex.MatchString(ref) {
+		return "", "", &PlatformError{Code: CodeInvalidId, Msg: "gabId is not a Gab post id or URL"}
+	}
+	return ref, "", nil
+}
+
+// TwitterConfigured reports whether v has a Twitter client or bearer token
+// to look tweets up with.
+func (v *Verifier) TwitterConfigured() bool {
+	client, bearerToken := v.TwitterCredentials()
+	return client != nil || bearerToken != ""
+}
+
+// VerifyTwitter fetches the tweet id, a status id or URL, and returns the
+// publisher name and txid from its verification statement along with the
+// author's screen name.
+// The handle is returned even when the tweet is ErrBadFormat.
+func (v *Verifier) VerifyTwitter(ctx context.Context, id string) (name string, txid string, handle string, err error) {
+	intId, _, err := parseTweetRef(id)
+	if err != nil {
+		return "", "", "", err
+	}
+	if !v.TwitterConfigured() {
+		return "", "", "", &PlatformError{Code: CodeNotConfigured, Msg: "Twitter verification is not configured on this server"}
+	}
+
+	if v.TwitterLimiter != nil {
+		r := v.TwitterLimiter.Reserve()
+		if d := r.Delay(); d > 0 {
+			r.Cancel()
+			return "", "", "", &RateLimitError{Target: "twitter", Reset: time.Now().Add(d)}
+		}
+	}
+
+	// wait out the rate limit once if it resets within TwitterRateLimitWait
+	var tweet *twitter.Tweet
+	for waited := false; ; waited = true {
+		var res *http.Response
+		tweet, res, err = v.showTweet(ctx, intId)
+		if ctx.Err() != nil {
+			return "", "", "", ctx.Err()
+		}
+		if res != nil {
+			v.observeRateLimit("twitter", res.Header)
+		}
+		rl := twitterRateLimitError(res, err)
+		if rl != nil {
+			wait := time.Until(rl.Reset)
+			if wait < 0 {
+				// the reset has passed, or the clocks disagree
+				wait = 0
+			}
+			if waited || wait > v.TwitterRateLimitWait {
+				return "", "", "", rl
+			}
+			Logger(ctx).Warn("Waiting for twitter rate limit to reset", "wait", wait.String())
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return "", "", "", ctx.Err()
+			case <-t.C:
+			}
+			continue
+		}
+		if err != nil {
+			if res != nil && res.StatusCode >= 500 {
+				return "", "", "", &StatusError{URL: "twitter status " + id, StatusCode: res.StatusCode}
+			}
+			if perr := twitterLookupError(err); perr != nil {
+				return "", "", "", perr
+			}
+			return "", "", "", err
+		}
+		break
+	}
+
+	if tweet.User != nil {
+		handle = tweet.User.ScreenName
+	}
+	text := tweet.FullText
+	if len(text) == 0 {
+		text = tweet.Text
+	}
+	text = tcoSuffixRegex.ReplaceAllString(text, "")
+	name, txid, err = v.matchVerification(ctx, text)
+	return name, txid, handle, err
+}
+
+// oipGet fetches the OIP api path made of elems, each of which is escaped.
+// Each attempt is bounded by OipTimeout when it is set, and transient
+// failures are retried up to OipAttempts times in total.
+func (v *Verifier) oipGet(ctx context.Context, elems ...string) ([]byte, error) {
+	return v.oipGetURL(ctx, v.OipURL(elems...))
+}
+
+// oipGetURL is oipGet with the URL already built, e.g. with a query.
+func (v *Verifier) oipGetURL(ctx context.Context, u string) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		body, err := v.oipGetOnce(ctx, u)
+		if err == nil || attempt >= v.OipAttempts || ctx.Err() != nil || !retryable(err) {
+			return body, err
+		}
+
+		delay := backoffDelay(v.OipRetryDelay, attempt)
+		Logger(ctx).Warn("Retrying OIP api request", "url", u, "attempt", attempt, "err", err, "delay", delay.String())
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+	}
+}
+
+func (v *Verifier) oipGetOnce(ctx context.Context, u string) ([]byte, error) {
+	if v.OipTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.OipTimeout)
+		defer cancel()
+	}
+	return v.httpGet(ctx, "oip", u)
+}
+
+// retryable reports whether err is a transient upstream failure: a 5xx, a
+// timeout, or a dropped connection.
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= 500
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoffDelay returns a random delay of up to base doubled for each
+// previous attempt.
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt-1)
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d))) + 1
+}
+
+// OipURL returns the OIP api URL for the path made of elems, each of which is
+// escaped.
+func (v *Verifier) OipURL(elems ...string) string {
+	u := v.OipApi
+	for _, e := range elems {
+		u += "/" + url.PathEscape(e)
+	}
+	return u
+}
+
+// DefaultUserAgent is the UserAgent of a new Verifier.
+const DefaultUserAgent = "oip-verifier"
+
+// setUserAgent sets the User-Agent of a request to target in h, unless it
+// has one.
+func (v *Verifier) setUserAgent(h http.Header, target string) {
+	ua, ok := v.UserAgents[target]
+	if !ok {
+		ua = v.UserAgent
+	}
+	if ua != "" && h.Get("User-Agent") == "" {
+		h.Set("User-Agent", ua)
+	}
+}
+
+// twitterAPIHost is the host of both Twitter apis, for its breaker.
+const twitterAPIHost = "api.twitter.com"
+
+// showTweet calls Statuses.Show, or the v2 api when there is no Twitter
+// client. go-twitter has no context support, so it gives up on the call
+// (which is still bounded by the client timeout) once ctx is done.
+func (v *Verifier) showTweet(ctx context.Context, id int64) (*twitter.Tweet, *http.Response, error) {
+	client, bearerToken := v.TwitterCredentials()
+	if client == nil {
+		return v.showTweetV2(ctx, id, bearerToken)
+	}
+	type showResult struct {
+		tweet *twitter.Tweet
+		res   *http.Response
+		err   error
+	}
+	release, err := v.acquireUpstream(ctx, "twitter")
+	if err != nil {
+		return nil, nil, err
+	}
+	err = v.allowUpstream("twitter", twitterAPIHost)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	done := make(chan showResult, 1)
+	go func() {
+		defer release()
+		start := time.Now()
+		tweet, res, err := client.Statuses.Show(id, &twitter.StatusShowParams{TweetMode: "extended"})
+		v.observeUpstream(ctx, "twitter", twitterAPIHost, start, res, err)
+		done <- showResult{tweet, res, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case sr := <-done:
+		return sr.tweet, sr.res, sr.err
+	}
+}
+
+type twitterV2Tweet struct {
+	Data *struct {
+		Text      string `json:"text"`
+		AuthorID  string `json:"author_id"`
+		NoteTweet *struct {
+			Text string `json:"text"`
+		} `json:"note_tweet"`
+	} `json:"data"`
+	Includes struct {
+		Users []struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"users"`
+	} `json:"includes"`
+	Errors []struct {
+		Type   string `json:"type"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// tweetLookup is the outcome of the latest v2 api tweet lookup, which
+// LastTweetLookup reports for health checks.
+type tweetLookup struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+// LastTweetLookup returns when the latest v2 api tweet lookup finished and
+// its error if the api was unreachable, failing, or refused the bearer
+// token. The time is zero before the first lookup.
+func (v *Verifier) LastTweetLookup() (time.Time, error) {
+	v.lastTweet.mu.Lock()
+	defer v.lastTweet.mu.Unlock()
+	return v.lastTweet.at, v.lastTweet.err
+}
+
+func (v *Verifier) recordTweetLookup(ctx context.Context, res *http.Response, err error) {
+	// the caller giving up says nothing about the api
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil && (res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden || res.StatusCode >= 500) {
+		err = &StatusError{URL: "twitter tweet lookup", StatusCode: res.StatusCode}
+	}
+	v.lastTweet.mu.Lock()
+	defer v.lastTweet.mu.Unlock()
+	v.lastTweet.at, v.lastTweet.err = time.Now(), err
+}
+
+// showTweetV2 fetches tweet id from the v2 api with bearerToken,
+// returning it in the v1.1 shape VerifyTwitter expects. The response is
+// returned for its rate limit headers.
+func (v *Verifier) showTweetV2(ctx context.Context, id int64, bearerToken string) (*twitter.Tweet, *http.Response, error) {
+	q := url.Values{"tweet.fields": {"text,author_id,note_tweet"}, "expansions": {"author_id"}, "user.fields": {"username"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+twitterAPIHost+"/2/tweets/"+strconv.FormatInt(id, 10)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	v.setUserAgent(req.Header, "twitter")
+
+	release, err := v.acquireUpstream(ctx, "twitter")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	err = v.allowUpstream("twitter", twitterAPIHost)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := time.Now()
+	res, err := v.HTTPClient.Do(req)
+	v.observeUpstream(ctx, "twitter", twitterAPIHost, start, res, err)
+	v.recordTweetLookup(ctx, res, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, v.MaxBodySize+1))
+	if err != nil {
+		return nil, res, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, res, &StatusError{URL: "twitter tweet " + strconv.FormatInt(id, 10), StatusCode: res.StatusCode, Header: res.Header}
+	}
+
+	tv2 := &twitterV2Tweet{}
+	err = json.Unmarshal(body, tv2)
+	if err != nil {
+		return nil, res, &DecodeError{URL: "twitter tweet " + strconv.FormatInt(id, 10), Err: err}
+	}
+	// missing, deleted and protected tweets are a 200 with only errors
+	if tv2.Data == nil {
+		for _, e := range tv2.Errors {
+			switch {
+			case strings.HasSuffix(e.Type, "/not-authorized-for-resource"):
+				return nil, res, errTweetProtected
+			case strings.Contains(e.Detail, "suspended"):
+				return nil, res, errTweetSuspended
+			}
+		}
+		return nil, res, ErrPostNotFound
+	}
+
+	tweet := &twitter.Tweet{FullText: tv2.Data.Text}
+	if tv2.Data.NoteTweet != nil {
+		tweet.FullText = tv2.Data.NoteTweet.Text
+	}
+	for _, u := range tv2.Includes.Users {
+		if u.ID == tv2.Data.AuthorID {
+			tweet.User = &twitter.User{ScreenName: u.Username}
+		}
+	}
+	return tweet, res, nil
+}
+
+var (
+	errTweetDeleted   = &PlatformError{Code: CodePostRemoved, Msg: "Tweet has been deleted"}
+	errTweetSuspended = &PlatformError{Code: CodeSuspended, Msg: "Tweet's author has been suspended"}
+	errTweetProtected = &PlatformError{Code: CodeNotPublic, Msg: "Tweet's author has protected their tweets"}
+	errTweetMissing   = &PlatformError{Code: CodePostNotFound, Msg: "Tweet does not exist"}
+)
+
+// twitterLookupError returns the PlatformError for a v1.1 api error that
+// says why the tweet can't be shown, or nil for any other error.
+func twitterLookupError(err error) *PlatformError {
+	var apiErr twitter.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	for _, e := range apiErr.Errors {
+		switch e.Code {
+		case 144:
+			return errTweetDeleted
+		case 63:
+			return errTweetSuspended
+		case 179:
+			return errTweetProtected
+		case 34:
+			return errTweetMissing
+		}
+	}
+	return nil
+}
+
+// twitterRateLimitError returns a RateLimitError if the Twitter api rejected
+// a request for exceeding the rate limit (HTTP 429 or error code 88).
+func twitterRateLimitError(res *http.Response, err error) *RateLimitError {
+	limited := res != nil && res.StatusCode == http.StatusTooManyRequests
+	var apiErr twitter.APIError
+	if errors.As(err, &apiErr) {
+		for _, e := range apiErr.Errors {
+			if e.Code == 88 {
+				limited = true
+			}
+		}
+	}
+	if !limited {
+		return nil
+	}
+
+	rl := &RateLimitError{Target: "twitter", Reset: time.Now().Add(time.Minute)}
+	if res != nil {
+		if reset, err := strconv.ParseInt(res.Header.Get("X-Rate-Limit-Reset"), 10, 64); err == nil {
+			rl.Reset = time.Unix(reset, 0)
+		}
+	}
+	return rl
+}
+
+func (v *Verifier) httpGet(ctx context.Context, target string, rawurl string) ([]byte, error) {
+	return v.httpGetLimit(ctx, target, rawurl, v.MaxBodySize)
+}
+
+// httpGetLimit is httpGet with a body size limit other than MaxBodySize.
+func (v *Verifier) httpGetLimit(ctx context.Context, target string, rawurl string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return v.doRequest(target, req, limit)
+}
+
+// httpGetPage fetches the web page at rawurl, which may be at most limit
+// bytes.
+func (v *Verifier) httpGetPage(ctx context.Context, target string, rawurl string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	return v.doRequest(target, req, limit)
+}
+
+// guardedClient returns a copy of HTTPClient that fails with err rather than
+// follow a redirect for which refuse returns true, e.g. to a login page.
+func (v *Verifier) guardedClient(refuse func(*url.URL) bool, err error) *http.Client {
+	client := *v.HTTPClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if refuse(req.URL) {
+			return err
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client
+}
+
+// doRequest sends req and returns the body of its 2xx response, which may be
+// at most limit bytes.
+func (v *Verifier) doRequest(target string, req *http.Request, limit int64) ([]byte, error) {
+	return v.doRequestWith(v.HTTPClient, target, req, limit)
+}
+
+// doRequestWith is doRequest with a client other than HTTPClient, e.g. one
+// with its own redirect policy.
+func (v *Verifier) doRequestWith(client *http.Client, target string, req *http.Request, limit int64) ([]byte, error) {
+	v.setUserAgent(req.Header, target)
+	release, err := v.acquireUpstream(req.Context(), target)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	err = v.allowUpstream(target, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	v.observeUpstream(req.Context(), target, req.URL.Host, start, res, err)
+	if err != nil {
+		return nil, notPublicError(err, req.URL.Host)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// keep a little of the body for the error, which also lets the
+		// connection be reused
+		snippet, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, &StatusError{URL: req.URL.String(), StatusCode: res.StatusCode, Header: res.Header, Body: snippet}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+// jsonRPC makes a JSON-RPC call to endpoint, decoding the result into out.
+// An error the server returns is a DecodeError.
+func (v *Verifier) jsonRPC(ctx context.Context, target, endpoint, method string, params interface{}, out interface{}) error {
+	reqBody, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params, "id": 1})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	body, err := v.doRequest(target, req, v.MaxBodySize)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err == nil && res.Error != nil {
+		err = errors.New(res.Error.Message)
+	}
+	if err == nil {
+		err = json.Unmarshal(res.Result, out)
+	}
+	if err != nil {
+		return &DecodeError{URL: target + " " + method, Err: err}
+	}
+	return nil
+}
+
+// VerifyGab fetches the Gab post postId, an id or URL, from Gab's Mastodon
+// compatible api and returns the publisher name and txid from its
+// verification statement along with its author's username.
+func (v *Verifier) VerifyGab(ctx context.Context, postId string) (name string, txid string, username string, err error) {
+	postId, _, err = parseGabRef(postId)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	status, err := v.mastodonStatus(ctx, "gab", "gab.com", postId)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Gab post " + postId + " has been deleted or does not exist"}
+		}
+		return "", "", "", err
+	}
+	name, txid, err = v.matchVerification(ctx, htmlToText(status.Content))
+	return name, txid, status.Account.Username, err
+}
+
+// gabClaimUsername returns the username in the claim's Gab post URL, if it is
+// one.
+func gabClaimUsername(vc *VerificationClaim) string {
+	_, username, _ := parseGabRef(vc.GabId)
+	return username
+}
+
+type publisherRecord struct {
+	publisher *Publisher
+	meta      *RMeta
+}
+
+func (v *Verifier) getPublisher(ctx context.Context, txid string) (_ *Publisher, _ *RMeta, err error) {
+	ctx, span := startSpan(ctx, "getPublisher", attribute.String("txid", txid))
+	defer func() { endSpan(span, err) }()
+	r, err := v.cached(ctx, v.publishers, txid, func() (interface{}, error) {
+		pub, meta, err := v.fetchPublisher(ctx, txid)
+		return publisherRecord{pub, meta}, err
+	})
+	pr := r.(publisherRecord)
+	return pr.publisher, pr.meta, err
+}
+
+func (v *Verifier) fetchPublisher(ctx context.Context, txid string) (*Publisher, *RMeta, error) {
+	body, err := v.oipGet(ctx, "o5", "record", "get", txid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := &oipApiResult{}
+	err = json.Unmarshal(body, results)
+	if err != nil {
+		return nil, nil, &DecodeError{URL: "publisher " + txid, Err: err}
+	}
+
+	if len(results.Results) == 1 {
+		return &results.Results[0].Record.Details.Publisher, &results.Results[0].Meta, nil
+	}
+
+	return nil, nil, ErrPublisherNotFound
+}
@@ -0,0 +1,609 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+)
+
+func TestHTTPGetStatusError(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.oip.io", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "<html>Internal Server Error</html>")
+	})
+	v := newTestVerifier(u)
+
+	_, err := v.httpGet(context.Background(), "oip", testOipApi+"/daemon/version")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got %v, want a StatusError 500", err)
+	}
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != CodeUpstreamError || !strings.HasSuffix(res.Msg, "upstream returned 500") {
+		t.Errorf("got %s %q, want %s from upstream returned 500", res.Code, res.Msg, CodeUpstreamError)
+	}
+}
+
+func TestHTTPGetTooLarge(t *testing.T) {
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"twitterId": "1"})
+	v := newTestVerifier(u)
+	v.MaxBodySize = 64
+
+	_, err := v.httpGet(context.Background(), "oip", testOipApi+"/o5/record/get/"+testClaimTxid)
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("got %v, want ErrBodyTooLarge", err)
+	}
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != CodeUpstreamError || !strings.HasSuffix(res.Msg, "upstream returned an invalid response") {
+		t.Errorf("got %s %q, want %s from an invalid response", res.Code, res.Msg, CodeUpstreamError)
+	}
+}
+
+func TestHTTPGetTruncated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n{\"count\": 1,")
+		buf.Flush()
+	}))
+	defer ts.Close()
+	v := New(nil, ts.URL, ts.Client())
+
+	_, err := v.httpGet(context.Background(), "oip", ts.URL+"/o5/record/get/"+testClaimTxid)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	if msg, ok := UpstreamMsg(err); !ok || msg != "upstream returned an invalid response" {
+		t.Errorf("got %q, want upstream returned an invalid response", msg)
+	}
+}
+
+func TestRecordDecodeError(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.oip.io", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"count": 1, "results": [`)
+	})
+	v := newTestVerifier(u)
+
+	_, _, err := v.getVerificationClaim(context.Background(), testClaimTxid)
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("got %v, want a DecodeError", err)
+	}
+	_, _, err = v.getPublisher(context.Background(), testPubTxid)
+	if !errors.As(err, &de) {
+		t.Fatalf("got %v, want a DecodeError", err)
+	}
+}
+
+func TestRecordNotFound(t *testing.T) {
+	v := newTestVerifier(newUpstream())
+
+	_, _, err := v.getVerificationClaim(context.Background(), testClaimTxid)
+	if err != ErrClaimNotFound {
+		t.Fatalf("got %v, want ErrClaimNotFound", err)
+	}
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != CodeClaimNotFound || res.Msg != "Unable to locate verification claim with ID "+testClaimTxid {
+		t.Errorf("got %s %q, want %s", res.Code, res.Msg, CodeClaimNotFound)
+	}
+}
+
+func TestVerifyTwitterExtended(t *testing.T) {
+	const name = "The Example Publishing Collective"
+	full := statement(name, testPubTxid)
+	if len(full) <= 140 {
+		t.Fatalf("statement is %d characters, want one that only fits in extended mode", len(full))
+	}
+	u := newUpstream()
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1.1/statuses/show.json" || r.URL.Query().Get("id") != "1724567800000000001" {
+			http.NotFound(w, r)
+			return
+		}
+		tweet := map[string]interface{}{
+			"id_str":    "1724567800000000001",
+			"truncated": true,
+			"text":      full[:139] + "…",
+			"user":      map[string]string{"screen_name": "examplepub"},
+		}
+		if r.URL.Query().Get("tweet_mode") == "extended" {
+			delete(tweet, "text")
+			tweet["full_text"] = full + " https://t.co/Ab12Cd34 https://t.co/Ef56Gh78"
+		}
+		writeJSON(w, tweet)
+	})
+	v := newTestVerifier(u)
+
+	gotName, txid, handle, err := v.VerifyTwitter(context.Background(), "1724567800000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotName != name || txid != testPubTxid || handle != "examplepub" {
+		t.Errorf("got %q, %s, %s, want %q, %s, examplepub", gotName, txid, handle, name, testPubTxid)
+	}
+}
+
+func TestTcoSuffix(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"txid https://t.co/Ab12Cd34", "txid"},
+		{"txid\nhttps://t.co/Ab12Cd34 https://t.co/Ef56Gh78 ", "txid"},
+		{"see https://t.co/Ab12Cd34 for txid", "see https://t.co/Ab12Cd34 for txid"},
+		{"txid", "txid"},
+	}
+	for _, tt := range tests {
+		if got := tcoSuffixRegex.ReplaceAllString(tt.text, ""); got != tt.want {
+			t.Errorf("stripping %q: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHangingUpstream(t *testing.T) {
+	done := make(chan struct{})
+	hang := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(hang))
+	defer ts.Close()
+	defer close(done)
+	v := New(nil, ts.URL, &http.Client{Timeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check took %v, want it to time out after 100ms", elapsed)
+	}
+	if res.Code != CodeUpstreamError || !strings.HasSuffix(res.Msg, "upstream unreachable") {
+		t.Errorf("got %s %q, want %s from upstream unreachable", res.Code, res.Msg, CodeUpstreamError)
+	}
+
+	// go-twitter can't be cancelled, but the lookup gives up with ctx
+	u := newUpstream()
+	u.handle("api.twitter.com", hang)
+	v = newTestVerifier(u)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, _, _, err = v.VerifyTwitter(ctx, "1724567800000000001")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled lookup took %v, want it to give up after 50ms", elapsed)
+	}
+}
+
+func TestOipRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		code     string
+	}{
+		{"5xx", http.StatusServiceUnavailable, 3, CodeNoPlatforms},
+		{"4xx", http.StatusForbidden, 1, CodeClaimNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream()
+			u.claim(testClaimTxid, map[string]string{})
+			var failures int
+			u.handle("api.oip.io", func(w http.ResponseWriter, r *http.Request) {
+				if failures < 2 {
+					failures++
+					w.WriteHeader(tt.status)
+					return
+				}
+				u.serveRecord(w, r)
+			})
+			v := newTestVerifier(u)
+
+			res, err := v.CheckClaim(context.Background(), testClaimTxid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := u.count(testOipApi + "/o5/record/get/" + testClaimTxid); n != tt.attempts {
+				t.Errorf("got %d attempts, want %d", n, tt.attempts)
+			}
+			if res.Code != tt.code {
+				t.Errorf("got %s %q, want %s", res.Code, res.Msg, tt.code)
+			}
+		})
+	}
+}
+
+func TestOipRetriesExhausted(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.oip.io", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	v := newTestVerifier(u)
+	v.OipAttempts = 2
+
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := u.count(testOipApi + "/o5/record/get/" + testClaimTxid); n != 2 {
+		t.Errorf("got %d attempts, want 2", n)
+	}
+	if res.Code != CodeUpstreamError {
+		t.Errorf("got %s %q, want %s", res.Code, res.Msg, CodeUpstreamError)
+	}
+}
+
+func TestOipNotFoundNotRetried(t *testing.T) {
+	u := newUpstream()
+	v := newTestVerifier(u)
+
+	_, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := u.count(testOipApi + "/o5/record/get/" + testClaimTxid); n != 1 {
+		t.Errorf("got %d attempts for a missing claim, want 1", n)
+	}
+}
+
+func TestShowTweetV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		text   string
+		err    error
+	}{
+		{"tweet", http.StatusOK, `{"data": {"id": "1", "text": "hello", "author_id": "9"}, "includes": {"users": [{"id": "9", "username": "examplepub"}]}}`, "hello", nil},
+		{"note tweet", http.StatusOK, `{"data": {"id": "1", "text": "hello…", "author_id": "9", "note_tweet": {"text": "hello world"}}, "includes": {"users": [{"id": "9", "username": "examplepub"}]}}`, "hello world", nil},
+		{"missing", http.StatusOK, `{"errors": [{"type": "https://api.twitter.com/2/problems/resource-not-found", "detail": "Could not find tweet with id: [1]."}]}`, "", ErrPostNotFound},
+		{"bad token", http.StatusUnauthorized, `{"title": "Unauthorized", "status": 401}`, "", &StatusError{}},
+		{"bad json", http.StatusOK, `{"data": `, "", &DecodeError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream()
+			u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" || r.URL.Path != "/2/tweets/1" {
+					t.Errorf("got request %s with %q", r.URL, r.Header.Get("Authorization"))
+				}
+				if r.URL.Query().Get("expansions") != "author_id" {
+					t.Errorf("got query %s without the author expansion", r.URL.RawQuery)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			v := newTestVerifier(u)
+
+			tweet, res, err := v.showTweetV2(context.Background(), 1, "token")
+			if res == nil || res.StatusCode != tt.status {
+				t.Errorf("got response %v, want one for its rate limit headers", res)
+			}
+			switch want := tt.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tweet.FullText != tt.text || tweet.User == nil || tweet.User.ScreenName != "examplepub" {
+					t.Errorf("got %+v", tweet)
+				}
+			case *StatusError:
+				if !errors.As(err, &want) || want.StatusCode != tt.status {
+					t.Errorf("got %v, want a StatusError %d", err, tt.status)
+				}
+			case *DecodeError:
+				if !errors.As(err, &want) {
+					t.Errorf("got %v, want a DecodeError", err)
+				}
+			default:
+				if err != tt.err {
+					t.Errorf("got %v, want %v", err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestShowTweet(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/statuses/show.json" {
+			writeJSON(w, map[string]interface{}{"id_str": r.URL.Query().Get("id"), "full_text": "from v1.1", "user": map[string]string{"screen_name": "examplepub"}})
+			return
+		}
+		io.WriteString(w, `{"data": {"id": "1", "text": "from v2", "author_id": "9"}, "includes": {"users": [{"id": "9", "username": "examplepub"}]}}`)
+	})
+	v := newTestVerifier(u)
+	v.SetTwitter(nil, "token")
+
+	tweet, _, err := v.showTweet(context.Background(), 1)
+	if err != nil || tweet.FullText != "from v2" {
+		t.Errorf("without a client: got %+v, %v, want the v2 tweet", tweet, err)
+	}
+	if at, err := v.LastTweetLookup(); at.IsZero() || err != nil {
+		t.Errorf("got last lookup at %v, %v", at, err)
+	}
+	v.SetTwitter(twitter.NewClient(&http.Client{Transport: u}), "")
+	tweet, _, err = v.showTweet(context.Background(), 1)
+	if err != nil || tweet.FullText != "from v1.1" || tweet.User.ScreenName != "examplepub" {
+		t.Errorf("with a client: got %+v, %v, want the v1.1 tweet", tweet, err)
+	}
+}
+
+func TestLastTweetLookup(t *testing.T) {
+	u := newUpstream()
+	v := newTestVerifier(u)
+	if at, _ := v.LastTweetLookup(); !at.IsZero() {
+		t.Errorf("got a lookup at %v before any", at)
+	}
+
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	v.showTweetV2(context.Background(), 1, "revoked")
+	if _, err := v.LastTweetLookup(); err == nil {
+		t.Error("got no error after a 401")
+	}
+
+	// a missing tweet is the api working
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"errors": [{"type": "https://api.twitter.com/2/problems/resource-not-found", "detail": "Could not find tweet with id: [1]."}]}`)
+	})
+	v.showTweetV2(context.Background(), 1, "token")
+	if _, err := v.LastTweetLookup(); err != nil {
+		t.Errorf("got %v after a missing tweet", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before, _ := v.LastTweetLookup()
+	v.showTweetV2(ctx, 1, "token")
+	if at, _ := v.LastTweetLookup(); at != before {
+		t.Error("a cancelled lookup was recorded")
+	}
+}
+
+// gabFixture is a status of Gab's statuses api, trimmed.
+const gabFixture = `{
+  "id": "111412345678901234",
+  "created_at": "2023-11-14T22:15:00.000Z",
+  "visibility": "public",
+  "url": "https://gab.com/examplepub/posts/111412345678901234",
+  "content": "<p>Hello Gab!</p><p>@OpenIndexProtocol verifying &quot;Example Publisher&quot; is publishing as: <br />4f03a060b7eb032972dcb327f2c989b14d5e73c35b3d5928a6f875ca39c159ba</p>",
+  "account": {"id": "1234", "username": "examplepub", "acct": "examplepub", "display_name": "Example Publisher"},
+  "media_attachments": [],
+  "emojis": []
+}`
+
+func TestVerifyGabStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+	}{
+		{"status", http.StatusOK, gabFixture, ""},
+		{"deleted", http.StatusNotFound, `{"error": "Record not found"}`, CodePostNotFound},
+		{"unauthorized", http.StatusUnauthorized, `{"error": "This method requires an authenticated user"}`, CodeAuthRequired},
+		{"forbidden", http.StatusForbidden, `{"error": "This action is not allowed"}`, CodeAuthRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream()
+			u.handle("gab.com", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/statuses/111412345678901234" {
+					t.Errorf("got request for %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			v := newTestVerifier(u)
+
+			name, txid, username, err := v.VerifyGab(context.Background(), "111412345678901234")
+			if tt.code != "" {
+				checkCode(t, tt.name, err, tt.code)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != testPubName || txid != testPubTxid || username != "examplepub" {
+				t.Errorf("got %q, %s, %s", name, txid, username)
+			}
+		})
+	}
+}
+
+func TestVerifyGabOldShape(t *testing.T) {
+	u := newUpstream()
+	u.handle("gab.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<!DOCTYPE html><html><body>Gab Social</body></html>")
+	})
+	v := newTestVerifier(u)
+
+	_, _, _, err := v.VerifyGab(context.Background(), "111412345678901234")
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("got %v, want a DecodeError", err)
+	}
+}
+
+func TestGabAuthor(t *testing.T) {
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"gabId": "https://gab.com/someoneelse/posts/111412345678901234"})
+	u.publisher(testPubTxid, testPubName)
+	u.handle("gab.com", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, gabFixture)
+	})
+	v := newTestVerifier(u)
+
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps := res.Platforms["gab"]; ps == nil || ps.Verified || ps.Code != CodeAuthorMismatch {
+		t.Errorf("got %+v, want %s", ps, CodeAuthorMismatch)
+	}
+}
+
+func TestTwitterErrorCodes(t *testing.T) {
+	tests := []struct {
+		status  int
+		code    int
+		message string
+		want    *PlatformError
+	}{
+		{http.StatusNotFound, 144, "No status found with that ID.", errTweetDeleted},
+		{http.StatusForbidden, 63, "User has been suspended.", errTweetSuspended},
+		{http.StatusForbidden, 179, "Sorry, you are not authorized to see this status.", errTweetProtected},
+		{http.StatusNotFound, 34, "Sorry, that page does not exist.", errTweetMissing},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			u := newUpstream()
+			u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001"})
+			u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"errors": [{"code": %d, "message": %q}]}`, tt.code, tt.message)
+			})
+			v := newTestVerifier(u)
+			v.Twitter = twitter.NewClient(&http.Client{Transport: u})
+
+			_, _, _, err := v.VerifyTwitter(context.Background(), "1724567800000000001")
+			if err != tt.want {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			res, err := v.CheckClaim(context.Background(), testClaimTxid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ps := res.Platforms["twitter"]; ps.Code != tt.want.Code || ps.Msg != tt.want.Msg {
+				t.Errorf("got %s %q, want %s %q", ps.Code, ps.Msg, tt.want.Code, tt.want.Msg)
+			}
+		})
+	}
+}
+
+func TestTwitterTransientError(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, `{"errors": [{"code": 130, "message": "Over capacity"}]}`)
+	})
+	v := newTestVerifier(u)
+	v.Twitter = twitter.NewClient(&http.Client{Transport: u})
+
+	_, _, _, err := v.VerifyTwitter(context.Background(), "1724567800000000001")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want a StatusError 503", err)
+	}
+}
+
+func TestTwitterRateLimitResetPassed(t *testing.T) {
+	u := newUpstream()
+	u.tweet("1724567800000000001", statement(testPubName, testPubTxid))
+	limited := false
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		if !limited {
+			limited = true
+			w.Header().Set("X-Rate-Limit-Reset", "1700000000")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"errors": [{"code": 88, "message": "Rate limit exceeded"}]}`)
+			return
+		}
+		u.serveTweet(w, r)
+	})
+	v := newTestVerifier(u)
+	v.TwitterRateLimitWait = 0
+
+	_, _, handle, err := v.VerifyTwitter(context.Background(), "1724567800000000001")
+	if err != nil || handle != "examplepub" {
+		t.Errorf("got %q, %v, want the tweet once the reset that passed is waited out", handle, err)
+	}
+}
+
+func TestUserAgents(t *testing.T) {
+	u := newUpstream()
+	u.handle("api.twitter.com", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data": {"id": "1", "text": "from v2", "author_id": "9"}, "includes": {"users": [{"id": "9", "username": "examplepub"}]}}`)
+	})
+	u.post("111412345678901234", statement(testPubName, testPubTxid))
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	client := &http.Client{Timeout: 5 * time.Second, Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		agents[req.URL.Host] = req.Header.Get("User-Agent")
+		mu.Unlock()
+		return u.RoundTrip(req)
+	})}
+	v := New(nil, testOipApi, client)
+	v.UserAgent = "test-agent/1"
+	v.UserAgents["gab"] = "gab-agent/2 (+https://example.com, contact)"
+	ctx := context.Background()
+
+	if _, _, err := v.showTweetV2(ctx, 1, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := v.VerifyGab(ctx, "111412345678901234"); err != nil {
+		t.Fatal(err)
+	}
+	v.httpGet(ctx, "reddit", "https://www.reddit.com/r/oip/comments/abc.json")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/", nil)
+	req.Header.Set("User-Agent", "own-agent/3")
+	v.doRequest("website", req, v.MaxBodySize)
+	want := map[string]string{
+		"api.twitter.com": "test-agent/1",
+		"gab.com":         "gab-agent/2 (+https://example.com, contact)",
+		"www.reddit.com":  redditUserAgent,
+		"example.com":     "own-agent/3",
+	}
+	for host, ua := range want {
+		if agents[host] != ua {
+			t.Errorf("request to %s had User-Agent %q, want %q", host, agents[host], ua)
+		}
+	}
+
+	v.UserAgent = ""
+	if _, _, err := v.showTweetV2(ctx, 1, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if ua := agents["api.twitter.com"]; ua != "" {
+		t.Errorf("empty UserAgent sent User-Agent %q", ua)
+	}
+}
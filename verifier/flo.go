@@ -0,0 +1,171 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// floMessagePrefix is prepended to messages signed with a FLO key, as in
+// Bitcoin's "Bitcoin Signed Message:\n".
+const floMessagePrefix = "Florincoin Signed Message:\n"
+
+// floPubKeyHashAddrID is the version byte of FLO mainnet P2PKH addresses.
+const floPubKeyHashAddrID = 0x23
+
+var errNoXPub = errors.New("publisher has no floBip44XPub")
+
+// VerifyFLOProof downloads the proof at proofURL, a text document whose last
+// non-empty line is the base64 signature of everything before it, and checks
+// that it is signed by the address at index FLOAddressIndex of the external
+// chain of the floBip44XPub of the publisher its verification statement
+// names. The signing address is returned in the proof's details.
+func (v *Verifier) VerifyFLOProof(ctx context.Context, proofURL string) (*Proof, error) {
+	u, err := url.Parse(proofURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidID
+	}
+
+	doc, err := v.httpGetText(toClaimHost(ctx), "proof", proofURL)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return nil, &PlatformError{Code: CodePostNotFound, Msg: "No proof at " + proofURL}
+		}
+		return nil, err
+	}
+	doc = bytes.TrimSpace(doc)
+	i := bytes.LastIndexByte(doc, '\n')
+	if i < 0 {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Proof at " + proofURL + " has no signature line"}
+	}
+	message := strings.TrimSpace(string(doc[:i]))
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(doc[i+1:])))
+	if err != nil {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Proof signature line at " + proofURL + " is not base64"}
+	}
+
+	proof := &Proof{}
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, message)
+	if err != nil {
+		return proof, err
+	}
+
+	pub, _, err := v.getPublisher(ctx, proof.Txid)
+	if err != nil {
+		if _, upstream := UpstreamMsg(err); upstream {
+			return proof, err
+		}
+		return proof, &PlatformError{Code: CodePublisherNotFound, Msg: "Unable to locate publisher with ID " + proof.Txid}
+	}
+	expected, err := floXPubAddress(pub.FloBip44XPub, v.FLOAddressIndex)
+	if err != nil {
+		return proof, &PlatformError{Code: CodeAddressMismatch, Msg: "Unable to derive an address from publisher's floBip44XPub: " + err.Error()}
+	}
+
+	signer, err := floMessageSigner(message, sig)
+	if err != nil {
+		return proof, &PlatformError{Code: CodeSignatureInvalid, Msg: "Proof signature is invalid: " + err.Error()}
+	}
+	proof.Details = map[string]string{"address": signer}
+	if signer != expected {
+		return proof, &PlatformError{Code: CodeAddressMismatch, Msg: "Proof is signed by " + signer + " but publisher's address " + strconv.FormatUint(uint64(v.FLOAddressIndex), 10) + " is " + expected}
+	}
+	return proof, nil
+}
+
+// floXPubAddress derives the FLO address at index of the external chain of
+// the BIP44 account xpub.
+func floXPubAddress(xpub string, index uint32) (string, error) {
+	if xpub == "" {
+		return "", errNoXPub
+	}
+	account, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return "", err
+	}
+	external, err := account.Derive(0)
+	if err != nil {
+		return "", err
+	}
+	key, err := external.Derive(index)
+	if err != nil {
+		return "", err
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return "", err
+	}
+	return base58.CheckEncode(btcutil.Hash160(pubKey.SerializeCompressed()), floPubKeyHashAddrID), nil
+}
+
+// floMessageSigner recovers the address that made the compact signature sig
+// of message.
+func floMessageSigner(message string, sig []byte) (string, error) {
+	var buf bytes.Buffer
+	writeVarString(&buf, floMessagePrefix)
+	writeVarString(&buf, message)
+	pubKey, compressed, err := ecdsa.RecoverCompact(sig, chainhash.DoubleHashB(buf.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+	return base58.CheckEncode(btcutil.Hash160(serialized), floPubKeyHashAddrID), nil
+}
+
+// writeVarString writes s prefixed with its length as a Bitcoin varint.
+func writeVarString(buf *bytes.Buffer, s string) {
+	n := uint64(len(s))
+	var b [9]byte
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		b[0] = 0xfd
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	case n <= 0xffffffff:
+		b[0] = 0xfe
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	default:
+		b[0] = 0xff
+		binary.LittleEndian.PutUint64(b[1:], n)
+		buf.Write(b[:])
+	}
+	buf.WriteString(s)
+}
+
+type floProofPlatform struct {
+	v *Verifier
+}
+
+func (f floProofPlatform) Name() string {
+	return "proof"
+}
+
+func (f floProofPlatform) Verify(ctx context.Context, proofURL string) (string, string, error) {
+	proof, err := f.v.VerifyFLOProof(ctx, proofURL)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (f floProofPlatform) VerifyProof(ctx context.Context, proofURL string) (*Proof, error) {
+	return f.v.VerifyFLOProof(ctx, proofURL)
+}
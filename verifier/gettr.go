@@ -0,0 +1,62 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+)
+
+type gettrPost struct {
+	RC     string `json:"rc"`
+	Result struct {
+		Data struct {
+			Text string `json:"txt"`
+			UID  string `json:"uid"`
+		} `json:"data"`
+	} `json:"result"`
+}
+
+var gettrIDRegex = regexp.MustCompile(`^p[0-9a-z]{4,20}$`)
+
+// VerifyGettr fetches the Gettr post id from Gettr's public api and returns
+// the publisher name and txid from its verification statement along with its
+// author's username.
+func (v *Verifier) VerifyGettr(ctx context.Context, id string) (name string, txid string, username string, err error) {
+	if !gettrIDRegex.MatchString(id) {
+		return "", "", "", ErrInvalidID
+	}
+
+	body, err := v.httpGet(ctx, "gettr", "https://api.gettr.com/u/post/"+id)
+	if err != nil {
+		return "", "", "", err
+	}
+	post := &gettrPost{}
+	err = json.Unmarshal(body, post)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "gettr post " + id, Err: err}
+	}
+	// missing and deleted posts are a 200 with an error rc
+	if post.RC != "OK" {
+		return "", "", "", ErrPostNotFound
+	}
+
+	name, txid, err = v.matchVerification(ctx, post.Result.Data.Text)
+	return name, txid, post.Result.Data.UID, err
+}
+
+type gettrPlatform struct {
+	v *Verifier
+}
+
+func (g gettrPlatform) Name() string {
+	return "gettr"
+}
+
+func (g gettrPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := g.v.VerifyGettr(ctx, id)
+	return name, txid, err
+}
+
+func (g gettrPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return g.v.VerifyGettr(ctx, id)
+}
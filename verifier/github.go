@@ -0,0 +1,108 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type githubGist struct {
+	Owner *struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Files map[string]struct {
+		Content   string `json:"content"`
+		Truncated bool   `json:"truncated"`
+	} `json:"files"`
+}
+
+var gistIDRegex = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// VerifyGist fetches the gist id and returns the publisher name and txid from
+// the verification statement in its files along with the owner's login.
+func (v *Verifier) VerifyGist(ctx context.Context, id string) (name string, txid string, owner string, err error) {
+	if !gistIDRegex.MatchString(id) {
+		return "", "", "", ErrInvalidID
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/gists/"+id, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if v.GitHubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+v.GitHubToken)
+	}
+	body, err := v.doRequest("github", req, v.MaxBodySize)
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) {
+			if (se.StatusCode == http.StatusForbidden || se.StatusCode == http.StatusTooManyRequests) && se.Header.Get("X-RateLimit-Remaining") == "0" {
+				return "", "", "", &RateLimitError{Target: "github", Reset: githubReset(se.Header)}
+			}
+			if se.StatusCode == http.StatusNotFound {
+				return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Gist " + id + " does not exist or is not public"}
+			}
+		}
+		return "", "", "", err
+	}
+
+	gist := &githubGist{}
+	err = json.Unmarshal(body, gist)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "gist " + id, Err: err}
+	}
+	if gist.Owner != nil {
+		owner = gist.Owner.Login
+	}
+
+	var names []string
+	for name := range gist.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var content []string
+	for _, name := range names {
+		f := gist.Files[name]
+		if f.Truncated {
+			return "", "", owner, &PlatformError{Code: CodeTruncated, Msg: "Gist file " + name + " is too large to verify"}
+		}
+		content = append(content, f.Content)
+	}
+
+	name, txid, err = v.matchVerification(ctx, strings.Join(content, "\n"))
+	return name, txid, owner, err
+}
+
+// githubReset returns when GitHub's rate limit resets, from the unix time in
+// the X-RateLimit-Reset header.
+func githubReset(h http.Header) time.Time {
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Now().Add(time.Minute)
+	}
+	return time.Unix(reset, 0)
+}
+
+type githubPlatform struct {
+	v *Verifier
+}
+
+func (g githubPlatform) Name() string {
+	return "github"
+}
+
+func (g githubPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := g.v.VerifyGist(ctx, id)
+	return name, txid, err
+}
+
+func (g githubPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return g.v.VerifyGist(ctx, id)
+}
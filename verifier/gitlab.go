@@ -0,0 +1,101 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type gitlabSnippet struct {
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+var (
+	gitlabSnippetIDRegex  = regexp.MustCompile(`^[0-9]{1,12}$`)
+	gitlabSnippetURLRegex = regexp.MustCompile(`^(https://[A-Za-z0-9.-]+(?::[0-9]+)?)/-/snippets/([0-9]{1,12})/?$`)
+)
+
+// VerifyGitLabSnippet fetches the snippet, given as an id on GitLabURL or a
+// snippet URL on any GitLab instance, and returns the publisher name and txid
+// from the verification statement in its content along with its author's
+// username. GitLabToken is only sent to GitLabURL.
+func (v *Verifier) VerifyGitLabSnippet(ctx context.Context, ref string) (name string, txid string, author string, err error) {
+	base, id := v.GitLabURL, ref
+	if m := gitlabSnippetURLRegex.FindStringSubmatch(ref); m != nil {
+		base, id = m[1], m[2]
+	} else if !gitlabSnippetIDRegex.MatchString(ref) {
+		return "", "", "", ErrInvalidID
+	}
+
+	snippet := &gitlabSnippet{}
+	body, err := v.gitlabGet(ctx, base, "/api/v4/snippets/"+id)
+	if err == nil {
+		err = json.Unmarshal(body, snippet)
+		if err != nil {
+			return "", "", "", &DecodeError{URL: "gitlab snippet " + id, Err: err}
+		}
+		body, err = v.gitlabGet(ctx, base, "/api/v4/snippets/"+id+"/raw")
+	}
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return "", "", snippet.Author.Username, &PlatformError{Code: CodePostNotFound, Msg: "GitLab snippet " + ref + " does not exist or is not public"}
+		}
+		return "", "", snippet.Author.Username, err
+	}
+
+	name, txid, err = v.matchVerification(ctx, string(body))
+	return name, txid, snippet.Author.Username, err
+}
+
+func (v *Verifier) gitlabGet(ctx context.Context, base, path string) ([]byte, error) {
+	if base != v.GitLabURL {
+		ctx = toClaimHost(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.GitLabToken != "" && base == v.GitLabURL {
+		req.Header.Set("PRIVATE-TOKEN", v.GitLabToken)
+	}
+	body, err := v.doRequest("gitlab", req, v.MaxBodySize)
+	var se *StatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests {
+		rl := &RateLimitError{Target: "gitlab", Reset: time.Now().Add(time.Minute)}
+		if reset, err := strconv.ParseInt(se.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+			rl.Reset = time.Unix(reset, 0)
+		}
+		return nil, rl
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		u, _ := url.Parse(base)
+		return nil, &UnavailableError{What: "GitLab instance " + u.Host, Err: err}
+	}
+	return body, err
+}
+
+type gitlabPlatform struct {
+	v *Verifier
+}
+
+func (g gitlabPlatform) Name() string {
+	return "gitlab"
+}
+
+func (g gitlabPlatform) Verify(ctx context.Context, ref string) (string, string, error) {
+	name, txid, _, err := g.v.VerifyGitLabSnippet(ctx, ref)
+	return name, txid, err
+}
+
+func (g gitlabPlatform) VerifyAuthor(ctx context.Context, ref string) (string, string, string, error) {
+	return g.v.VerifyGitLabSnippet(ctx, ref)
+}
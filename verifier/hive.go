@@ -0,0 +1,83 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type hiveContent struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
+
+var hivePermlinkRegex = regexp.MustCompile(`^@?([a-z0-9][a-z0-9.-]{2,15})/([a-z0-9-]+)$`)
+
+// VerifyHive fetches the post author/permlink from HiveNode and returns the
+// publisher name and txid from its verification statement along with its
+// author.
+func (v *Verifier) VerifyHive(ctx context.Context, permlink string) (name string, txid string, author string, err error) {
+	m := hivePermlinkRegex.FindStringSubmatch(strings.ToLower(permlink))
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+
+	content := &hiveContent{}
+	err = v.hiveCall(ctx, "condenser_api.get_content", []interface{}{m[1], m[2]}, content)
+	if err != nil {
+		return "", "", "", err
+	}
+	if content.Author == "" {
+		var accounts []json.RawMessage
+		err = v.hiveCall(ctx, "condenser_api.get_accounts", []interface{}{[]string{m[1]}}, &accounts)
+		if err != nil {
+			return "", "", "", err
+		}
+		if len(accounts) == 0 {
+			return "", "", "", &PlatformError{Code: CodeHandleNotFound, Msg: "Hive account " + m[1] + " does not exist"}
+		}
+		return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Hive account " + m[1] + " has no post " + m[2]}
+	}
+
+	name, txid, err = v.matchVerification(ctx, content.Body)
+	return name, txid, content.Author, err
+}
+
+// hiveCall makes a JSON-RPC call to HiveNode, decoding the result into out.
+func (v *Verifier) hiveCall(ctx context.Context, method string, params interface{}, out interface{}) error {
+	err := v.jsonRPC(ctx, "hive", v.HiveNode, method, params, out)
+	if _, upstream := UpstreamMsg(err); upstream {
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			return &UnavailableError{What: "hive node " + v.HiveNode, Err: err}
+		}
+	}
+	return err
+}
+
+// hiveCheckPublisher requires the on-chain author to be the publisher.
+func hiveCheckPublisher(author string, pub *Publisher) error {
+	if !strings.EqualFold(author, pub.Name) {
+		return &PlatformError{Code: CodeAuthorMismatch, Msg: "Post was published by Hive account " + author + " but publisher is " + pub.Name}
+	}
+	return nil
+}
+
+type hivePlatform struct {
+	v *Verifier
+}
+
+func (h hivePlatform) Name() string {
+	return "hive"
+}
+
+func (h hivePlatform) Verify(ctx context.Context, permlink string) (string, string, error) {
+	name, txid, _, err := h.v.VerifyHive(ctx, permlink)
+	return name, txid, err
+}
+
+func (h hivePlatform) VerifyAuthor(ctx context.Context, permlink string) (string, string, string, error) {
+	return h.v.VerifyHive(ctx, permlink)
+}
@@ -0,0 +1,169 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// instagramPost is the part of the ?__a=1 JSON that is needed, in both the
+// current and the older graphql shapes.
+type instagramPost struct {
+	Items []struct {
+		Caption *struct {
+			Text string `json:"text"`
+		} `json:"caption"`
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	} `json:"items"`
+	Graphql struct {
+		ShortcodeMedia *struct {
+			EdgeMediaToCaption struct {
+				Edges []struct {
+					Node struct {
+						Text string `json:"text"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"edge_media_to_caption"`
+			Owner struct {
+				Username string `json:"username"`
+			} `json:"owner"`
+		} `json:"shortcode_media"`
+	} `json:"graphql"`
+}
+
+// instagramAppID is the app id the Instagram web client sends, without which
+// the JSON endpoint refuses requests.
+const instagramAppID = "936619743392459"
+
+var (
+	instagramShortcodeRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{5,40}$`)
+	instagramMetaRegex      = regexp.MustCompile(`<meta[^>]+property="og:description"[^>]+content="([^"]*)"`)
+	// instagramMetaCaptionRegex matches the og:description of a post, e.g.
+	// `12 likes, 3 comments - user on May 1, 2024: "caption"`.
+	instagramMetaCaptionRegex = regexp.MustCompile(`(?s) - ([A-Za-z0-9._]+) on [^:]+: "(.*)"\.?$`)
+)
+
+var errInstagramBlocked = errors.New("instagram blocked the request")
+
+// VerifyInstagram fetches the post shortcode and returns the publisher name
+// and txid from the verification statement in its caption along with the
+// posting username. The JSON endpoint is tried first, then the post page;
+// InstagramSession, when set, is sent with both.
+func (v *Verifier) VerifyInstagram(ctx context.Context, shortcode string) (name string, txid string, username string, err error) {
+	if !instagramShortcodeRegex.MatchString(shortcode) {
+		return "", "", "", ErrInvalidID
+	}
+
+	caption, username, err := v.instagramJSON(ctx, shortcode)
+	if errors.Is(err, errInstagramBlocked) {
+		caption, username, err = v.instagramPage(ctx, shortcode)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errInstagramBlocked):
+			msg := "Instagram blocked the request for post " + shortcode
+			if v.InstagramSession == "" {
+				msg += "; this server has no Instagram session configured"
+			}
+			return "", "", "", &PlatformError{Code: CodeBlocked, Msg: msg}
+		case isStatus(err, http.StatusNotFound):
+			return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Instagram post " + shortcode + " has been deleted or does not exist"}
+		}
+		return "", "", "", err
+	}
+
+	name, txid, err = v.matchVerification(ctx, caption)
+	return name, txid, username, err
+}
+
+func (v *Verifier) instagramJSON(ctx context.Context, shortcode string) (caption, username string, err error) {
+	body, err := v.instagramGet(ctx, "https://www.instagram.com/p/"+shortcode+"/?__a=1&__d=dis", "application/json")
+	if err != nil {
+		return "", "", err
+	}
+
+	post := &instagramPost{}
+	if json.Unmarshal(body, post) != nil {
+		// a login or consent page served in place of the JSON
+		return "", "", errInstagramBlocked
+	}
+	if len(post.Items) != 0 {
+		item := post.Items[0]
+		if item.Caption != nil {
+			caption = item.Caption.Text
+		}
+		return caption, item.User.Username, nil
+	}
+	if media := post.Graphql.ShortcodeMedia; media != nil {
+		if edges := media.EdgeMediaToCaption.Edges; len(edges) != 0 {
+			caption = edges[0].Node.Text
+		}
+		return caption, media.Owner.Username, nil
+	}
+	return "", "", errInstagramBlocked
+}
+
+func (v *Verifier) instagramPage(ctx context.Context, shortcode string) (caption, username string, err error) {
+	body, err := v.instagramGet(ctx, "https://www.instagram.com/p/"+shortcode+"/", "text/html")
+	if err != nil {
+		return "", "", err
+	}
+
+	meta := instagramMetaRegex.FindSubmatch(body)
+	if meta == nil {
+		return "", "", errInstagramBlocked
+	}
+	m := instagramMetaCaptionRegex.FindStringSubmatch(html.UnescapeString(string(meta[1])))
+	if m == nil {
+		return "", "", errInstagramBlocked
+	}
+	return m[2], m[1], nil
+}
+
+// instagramGet fetches rawurl, reporting the ways Instagram turns away
+// anonymous and datacenter clients (a redirect to the login page, 401, 403,
+// or 429) as errInstagramBlocked.
+func (v *Verifier) instagramGet(ctx context.Context, rawurl, accept string) ([]byte, error) {
+	client := v.guardedClient(func(u *url.URL) bool {
+		return strings.HasPrefix(u.Path, "/accounts/login") || strings.HasPrefix(u.Path, "/challenge")
+	}, errInstagramBlocked)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-IG-App-ID", instagramAppID)
+	if v.InstagramSession != "" {
+		req.AddCookie(&http.Cookie{Name: "sessionid", Value: v.InstagramSession})
+	}
+	body, err := v.doRequestWith(client, "instagram", req, v.MaxBodySize)
+	if isStatus(err, http.StatusUnauthorized) || isStatus(err, http.StatusForbidden) || isStatus(err, http.StatusTooManyRequests) {
+		return nil, errInstagramBlocked
+	}
+	return body, err
+}
+
+type instagramPlatform struct {
+	v *Verifier
+}
+
+func (i instagramPlatform) Name() string {
+	return "instagram"
+}
+
+func (i instagramPlatform) Verify(ctx context.Context, shortcode string) (string, string, error) {
+	name, txid, _, err := i.v.VerifyInstagram(ctx, shortcode)
+	return name, txid, err
+}
+
+func (i instagramPlatform) VerifyAuthor(ctx context.Context, shortcode string) (string, string, string, error) {
+	return i.v.VerifyInstagram(ctx, shortcode)
+}
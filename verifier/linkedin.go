@@ -0,0 +1,74 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkedinUpdateRegex = regexp.MustCompile(`^https://(?:www\.)?linkedin\.com/feed/update/(urn:li:(?:activity|share|ugcPost):[0-9]+)/?$`)
+	linkedinPostsRegex  = regexp.MustCompile(`^https://(?:www\.)?linkedin\.com/posts/[^/?#]+-(activity|share|ugcPost)-([0-9]+)-[A-Za-z0-9_-]+/?$`)
+)
+
+var errLinkedInAuthwall = errors.New("linkedin redirected to its login wall")
+
+// VerifyLinkedIn fetches the embed page of the public post at postURL and
+// returns the verification statement in its text along with the author name
+// shown on it. LinkedIn's api can't be used without a partner app.
+func (v *Verifier) VerifyLinkedIn(ctx context.Context, postURL string) (name string, txid string, author string, err error) {
+	var urn string
+	if m := linkedinUpdateRegex.FindStringSubmatch(postURL); m != nil {
+		urn = m[1]
+	} else if m := linkedinPostsRegex.FindStringSubmatch(postURL); m != nil {
+		urn = "urn:li:" + m[1] + ":" + m[2]
+	} else {
+		return "", "", "", ErrInvalidID
+	}
+
+	client := v.guardedClient(func(u *url.URL) bool {
+		return strings.Contains(u.Path, "authwall") || strings.HasPrefix(u.Path, "/login") || strings.HasPrefix(u.Path, "/uas/login")
+	}, errLinkedInAuthwall)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.linkedin.com/embed/feed/update/"+urn, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	body, err := v.doRequestWith(client, "linkedin", req, v.MaxBodySize)
+	if errors.Is(err, errLinkedInAuthwall) || isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusForbidden) {
+		return "", "", "", &PlatformError{Code: CodeNotPublic, Msg: "LinkedIn post " + postURL + " is not public"}
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	page := string(body)
+
+	text, ok := elementText(page, "attributed-text-segment-list__content")
+	if !ok {
+		return "", "", "", &PlatformError{Code: CodeNotPublic, Msg: "LinkedIn post " + postURL + " is not public"}
+	}
+	author, _ = elementText(page, "share-update-card__actor-text")
+
+	name, txid, err = v.matchVerification(ctx, text)
+	return name, txid, author, err
+}
+
+type linkedinPlatform struct {
+	v *Verifier
+}
+
+func (l linkedinPlatform) Name() string {
+	return "linkedin"
+}
+
+func (l linkedinPlatform) Verify(ctx context.Context, postURL string) (string, string, error) {
+	name, txid, _, err := l.v.VerifyLinkedIn(ctx, postURL)
+	return name, txid, err
+}
+
+func (l linkedinPlatform) VerifyAuthor(ctx context.Context, postURL string) (string, string, string, error) {
+	return l.v.VerifyLinkedIn(ctx, postURL)
+}
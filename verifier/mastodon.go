@@ -0,0 +1,113 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mastodonMaxBodySize caps status responses, which come from arbitrary
+// instances.
+const mastodonMaxBodySize = 256 << 10
+
+type mastodonStatus struct {
+	Content string `json:"content"`
+	Account struct {
+		Acct     string `json:"acct"`
+		Username string `json:"username"`
+	} `json:"account"`
+}
+
+// mastodonPathRegex matches the paths of status URLs,
+// /@user/123 or /users/user/statuses/123.
+var mastodonPathRegex = regexp.MustCompile(`^/(?:@([^/]+)|users/([^/]+)/statuses)/([0-9]+)/?$`)
+
+// parseMastodonURL returns the instance host, the account, and the status id
+// of a status URL such as https://mastodon.social/@user/123456.
+func parseMastodonURL(statusURL string) (host, account, id string, err error) {
+	u, err := url.Parse(statusURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", "", "", ErrInvalidID
+	}
+	m := mastodonPathRegex.FindStringSubmatch(u.Path)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+	account = m[1]
+	if account == "" {
+		account = m[2]
+	}
+	return u.Host, account, m[3], nil
+}
+
+// VerifyMastodon fetches the status at statusURL from its instance's public
+// api and returns the publisher name and txid from its verification statement
+// along with the author's account.
+func (v *Verifier) VerifyMastodon(ctx context.Context, statusURL string) (name string, txid string, account string, err error) {
+	host, _, id, err := parseMastodonURL(statusURL)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	status, err := v.mastodonStatus(ctx, "mastodon", host, id)
+	if err != nil {
+		return "", "", "", err
+	}
+	account = status.Account.Acct
+	if account == "" {
+		account = status.Account.Username
+	}
+
+	name, txid, err = v.matchVerification(ctx, htmlToText(status.Content))
+	return name, txid, account, err
+}
+
+// mastodonStatus fetches status id from the public api of the Mastodon
+// compatible instance host, reporting the request as target.
+func (v *Verifier) mastodonStatus(ctx context.Context, target, host, id string) (*mastodonStatus, error) {
+	body, err := v.httpGetLimit(toClaimHost(ctx), target, "https://"+host+"/api/v1/statuses/"+id, mastodonMaxBodySize)
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && (se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden) {
+			return nil, &PlatformError{Code: CodeAuthRequired, Msg: "Instance " + host + " does not allow anonymous status access"}
+		}
+		return nil, err
+	}
+
+	status := &mastodonStatus{}
+	err = json.Unmarshal(body, status)
+	if err != nil {
+		return nil, &DecodeError{URL: target + " status " + host + "/" + id, Err: err}
+	}
+	return status, nil
+}
+
+type mastodonPlatform struct {
+	v *Verifier
+}
+
+func (m mastodonPlatform) Name() string {
+	return "mastodon"
+}
+
+func (m mastodonPlatform) Verify(ctx context.Context, statusURL string) (string, string, error) {
+	name, txid, _, err := m.v.VerifyMastodon(ctx, statusURL)
+	return name, txid, err
+}
+
+func (m mastodonPlatform) VerifyAuthor(ctx context.Context, statusURL string) (string, string, string, error) {
+	return m.v.VerifyMastodon(ctx, statusURL)
+}
+
+// mastodonClaimAccount returns the account named in the claim's status URL.
+func mastodonClaimAccount(vc *VerificationClaim) string {
+	_, account, _, err := parseMastodonURL(vc.MastodonUrl)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(account, "@")
+}
@@ -0,0 +1,184 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type matrixEvent struct {
+	Sender  string `json:"sender"`
+	Content struct {
+		Body string `json:"body"`
+	} `json:"content"`
+	Unsigned struct {
+		RedactedBecause json.RawMessage `json:"redacted_because"`
+	} `json:"unsigned"`
+}
+
+// matrixGuest holds the access token of the guest account registered when no
+// MatrixToken is configured.
+type matrixGuest struct {
+	mu    sync.Mutex
+	token string
+}
+
+// parseMatrixEvent returns the room (an id or alias) and event id of a
+// matrix.to link or a roomId/eventId pair.
+func parseMatrixEvent(ref string) (room, event string, err error) {
+	if strings.HasPrefix(ref, "https://matrix.to/#/") {
+		ref = strings.TrimPrefix(ref, "https://matrix.to/#/")
+		if i := strings.Index(ref, "?"); i >= 0 {
+			ref = ref[:i]
+		}
+		if unescaped, err := url.PathUnescape(ref); err == nil {
+			ref = unescaped
+		}
+	}
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || len(parts[0]) < 2 || (parts[0][0] != '!' && parts[0][0] != '#') || !strings.HasPrefix(parts[1], "$") {
+		return "", "", ErrInvalidID
+	}
+	return parts[0], parts[1], nil
+}
+
+// VerifyMatrix fetches the event from MatrixHomeserver and returns the
+// publisher name and txid from its verification statement along with its
+// sender's MXID. The room must be world readable.
+func (v *Verifier) VerifyMatrix(ctx context.Context, ref string) (name string, txid string, sender string, err error) {
+	room, eventID, err := parseMatrixEvent(ref)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if strings.HasPrefix(room, "#") {
+		var alias struct {
+			RoomID string `json:"room_id"`
+		}
+		err = v.matrixGet(ctx, "/_matrix/client/v3/directory/room/"+url.PathEscape(room), &alias)
+		if err != nil {
+			return "", "", "", matrixError(err, "Matrix room "+room+" does not exist")
+		}
+		room = alias.RoomID
+	}
+
+	var visibility struct {
+		HistoryVisibility string `json:"history_visibility"`
+	}
+	err = v.matrixGet(ctx, "/_matrix/client/v3/rooms/"+url.PathEscape(room)+"/state/m.room.history_visibility", &visibility)
+	if err != nil && !isStatus(err, http.StatusForbidden) {
+		return "", "", "", matrixError(err, "Matrix room "+room+" does not exist")
+	}
+	if err != nil || visibility.HistoryVisibility != "world_readable" {
+		return "", "", "", &PlatformError{Code: CodeNotPublic, Msg: "Matrix room " + room + " is not world readable"}
+	}
+
+	event := &matrixEvent{}
+	err = v.matrixGet(ctx, "/_matrix/client/v3/rooms/"+url.PathEscape(room)+"/event/"+url.PathEscape(eventID), event)
+	if err != nil {
+		return "", "", "", matrixError(err, "Matrix event "+eventID+" does not exist")
+	}
+	if len(event.Unsigned.RedactedBecause) != 0 {
+		return "", "", event.Sender, &PlatformError{Code: CodePostRemoved, Msg: "Matrix event " + eventID + " has been redacted"}
+	}
+
+	name, txid, err = v.matchVerification(ctx, event.Content.Body)
+	return name, txid, event.Sender, err
+}
+
+// matrixError turns a 404 into a PlatformError with notFound as the message.
+func matrixError(err error, notFound string) error {
+	if isStatus(err, http.StatusNotFound) {
+		return &PlatformError{Code: CodePostNotFound, Msg: notFound}
+	}
+	return err
+}
+
+func isStatus(err error, code int) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == code
+}
+
+// matrixGet fetches path from the homeserver's client api into out,
+// authenticated with MatrixToken or a guest account.
+func (v *Verifier) matrixGet(ctx context.Context, path string, out interface{}) error {
+	token, err := v.matrixToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.MatrixHomeserver+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	body, err := v.doRequest("matrix", req, v.MaxBodySize)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, out)
+	if err != nil {
+		return &DecodeError{URL: "matrix " + path, Err: err}
+	}
+	return nil
+}
+
+// matrixToken returns MatrixToken, or else registers a guest account once
+// and returns its token.
+func (v *Verifier) matrixToken(ctx context.Context) (string, error) {
+	if v.MatrixToken != "" {
+		return v.MatrixToken, nil
+	}
+	v.matrixGuest.mu.Lock()
+	defer v.matrixGuest.mu.Unlock()
+	if v.matrixGuest.token != "" {
+		return v.matrixGuest.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.MatrixHomeserver+"/_matrix/client/v3/register?kind=guest", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	body, err := v.doRequest("matrix", req, v.MaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusForbidden) {
+			return "", &PlatformError{Code: CodeNotConfigured, Msg: "Matrix homeserver does not allow guest access and no access token is configured"}
+		}
+		return "", err
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err == nil && res.AccessToken == "" {
+		err = errors.New("missing access_token")
+	}
+	if err != nil {
+		return "", &DecodeError{URL: "matrix guest registration", Err: err}
+	}
+	v.matrixGuest.token = res.AccessToken
+	return res.AccessToken, nil
+}
+
+type matrixPlatform struct {
+	v *Verifier
+}
+
+func (m matrixPlatform) Name() string {
+	return "matrix"
+}
+
+func (m matrixPlatform) Verify(ctx context.Context, ref string) (string, string, error) {
+	name, txid, _, err := m.v.VerifyMatrix(ctx, ref)
+	return name, txid, err
+}
+
+func (m matrixPlatform) VerifyAuthor(ctx context.Context, ref string) (string, string, string, error) {
+	return m.v.VerifyMatrix(ctx, ref)
+}
@@ -0,0 +1,148 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type apolloRef struct {
+	Ref string `json:"__ref"`
+}
+
+type mediumPost struct {
+	IsLocked bool      `json:"isLocked"`
+	Creator  apolloRef `json:"creator"`
+}
+
+type mediumContent struct {
+	BodyModel struct {
+		Paragraphs []apolloRef `json:"paragraphs"`
+	} `json:"bodyModel"`
+}
+
+var (
+	mediumPostIDRegex    = regexp.MustCompile(`-?([0-9a-f]{8,12})$`)
+	mediumStateRegex     = regexp.MustCompile(`(?s)window\.__APOLLO_STATE__\s*=\s*(\{.*?\})\s*</script>`)
+	mediumCanonicalRegex = regexp.MustCompile(`<link[^>]+rel="canonical"[^>]+href="([^"]+)"`)
+)
+
+// VerifyMedium fetches the story at storyURL, on medium.com or a custom
+// domain, and returns the verification statement in its text along with its
+// author's username. The text is read from the page's embedded Apollo state,
+// falling back to the article HTML.
+func (v *Verifier) VerifyMedium(ctx context.Context, storyURL string) (*Proof, error) {
+	u, err := url.Parse(storyURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidID
+	}
+	m := mediumPostIDRegex.FindStringSubmatch(strings.TrimSuffix(u.Path, "/"))
+	if m == nil {
+		return nil, ErrInvalidID
+	}
+	postID := m[1]
+
+	body, err := v.httpGetPage(toClaimHost(ctx), "medium", "https://"+u.Host+u.EscapedPath(), v.MaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusGone) {
+			return nil, &PlatformError{Code: CodePostNotFound, Msg: "Medium story " + storyURL + " has been deleted or does not exist"}
+		}
+		return nil, err
+	}
+	page := string(body)
+
+	proof := &Proof{}
+	if cm := mediumCanonicalRegex.FindStringSubmatch(page); cm != nil {
+		proof.Details = map[string]string{"canonical_url": cm[1]}
+	}
+
+	text, locked, err := mediumStateText(page, postID, proof)
+	if err != nil {
+		Logger(ctx).Info("Falling back to medium article HTML", "url", storyURL, "err", err)
+		text, _ = elementText(page, "meteredContent")
+		if text == "" {
+			text, _ = elementText(page, "postArticle-content")
+		}
+	}
+
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, text)
+	if err != nil && locked {
+		return proof, &PlatformError{Code: CodePaywalled, Msg: "Medium story " + storyURL + " is behind the paywall"}
+	}
+	return proof, err
+}
+
+// mediumStateText returns the paragraphs of post postID from the page's
+// Apollo state and whether it is a member-only story, setting the proof's
+// author to its creator's username.
+func mediumStateText(page, postID string, proof *Proof) (string, bool, error) {
+	sm := mediumStateRegex.FindStringSubmatch(page)
+	if sm == nil {
+		return "", false, errors.New("no apollo state")
+	}
+	var state map[string]json.RawMessage
+	err := json.Unmarshal([]byte(sm[1]), &state)
+	if err != nil {
+		return "", false, err
+	}
+
+	rawPost, ok := state["Post:"+postID]
+	if !ok {
+		return "", false, errors.New("post " + postID + " missing from apollo state")
+	}
+	post := &mediumPost{}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(rawPost, post) != nil || json.Unmarshal(rawPost, &fields) != nil {
+		return "", false, errors.New("malformed post in apollo state")
+	}
+	var user struct {
+		Username string `json:"username"`
+	}
+	if json.Unmarshal(state[post.Creator.Ref], &user) == nil {
+		proof.Author = user.Username
+	}
+
+	// the content field's name includes its query arguments, e.g.
+	// content({"postMeteringOptions":{}})
+	var content mediumContent
+	for k, raw := range fields {
+		if strings.HasPrefix(k, "content") {
+			_ = json.Unmarshal(raw, &content)
+			break
+		}
+	}
+	var paragraphs []string
+	for _, ref := range content.BodyModel.Paragraphs {
+		var p struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(state[ref.Ref], &p) == nil {
+			paragraphs = append(paragraphs, p.Text)
+		}
+	}
+	return strings.Join(paragraphs, "\n"), post.IsLocked, nil
+}
+
+type mediumPlatform struct {
+	v *Verifier
+}
+
+func (m mediumPlatform) Name() string {
+	return "medium"
+}
+
+func (m mediumPlatform) Verify(ctx context.Context, storyURL string) (string, string, error) {
+	proof, err := m.v.VerifyMedium(ctx, storyURL)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (m mediumPlatform) VerifyProof(ctx context.Context, storyURL string) (*Proof, error) {
+	return m.v.VerifyMedium(ctx, storyURL)
+}
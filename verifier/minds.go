@@ -0,0 +1,78 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+type mindsActivity struct {
+	Status   string `json:"status"`
+	Activity *struct {
+		Message string `json:"message"`
+		Mature  bool   `json:"mature"`
+		NSFW    []int  `json:"nsfw"`
+		Owner   struct {
+			Username string `json:"username"`
+		} `json:"ownerObj"`
+	} `json:"activity"`
+}
+
+var mindsGUIDRegex = regexp.MustCompile(`^[0-9]{10,25}$`)
+
+// VerifyMinds fetches the Minds activity guid and returns the publisher name
+// and txid from the verification statement in its message along with its
+// owner's username.
+func (v *Verifier) VerifyMinds(ctx context.Context, guid string) (name string, txid string, owner string, err error) {
+	if !mindsGUIDRegex.MatchString(guid) {
+		return "", "", "", ErrInvalidID
+	}
+
+	body, err := v.httpGet(ctx, "minds", "https://www.minds.com/api/v1/newsfeed/single/"+guid)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return "", "", "", mindsNotFound(guid)
+		}
+		return "", "", "", err
+	}
+
+	res := &mindsActivity{}
+	err = json.Unmarshal(body, res)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "minds activity " + guid, Err: err}
+	}
+	if res.Status != "success" || res.Activity == nil {
+		return "", "", "", mindsNotFound(guid)
+	}
+	a := res.Activity
+	owner = a.Owner.Username
+	// logged out clients get mature activities without their message
+	if a.Message == "" && (a.Mature || len(a.NSFW) != 0) {
+		return "", "", owner, &PlatformError{Code: CodeNotPublic, Msg: "Minds post " + guid + " is marked NSFW and can't be viewed without logging in"}
+	}
+
+	name, txid, err = v.matchVerification(ctx, a.Message)
+	return name, txid, owner, err
+}
+
+func mindsNotFound(guid string) error {
+	return &PlatformError{Code: CodePostNotFound, Msg: "Minds post " + guid + " has been deleted or does not exist"}
+}
+
+type mindsPlatform struct {
+	v *Verifier
+}
+
+func (m mindsPlatform) Name() string {
+	return "minds"
+}
+
+func (m mindsPlatform) Verify(ctx context.Context, guid string) (string, string, error) {
+	name, txid, _, err := m.v.VerifyMinds(ctx, guid)
+	return name, txid, err
+}
+
+func (m mindsPlatform) VerifyAuthor(ctx context.Context, guid string) (string, string, string, error) {
+	return m.v.VerifyMinds(ctx, guid)
+}
@@ -0,0 +1,260 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/gorilla/websocket"
+)
+
+type nostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+var nostrHexIDRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var errNostrEventNotFound = errors.New("nostr event not found on relay")
+
+// parseNostrID returns the hex event id of a hex or note1 bech32 event id.
+func parseNostrID(id string) (string, error) {
+	id = strings.TrimPrefix(strings.ToLower(id), "nostr:")
+	if nostrHexIDRegex.MatchString(id) {
+		return id, nil
+	}
+	hrp, data, err := bech32.Decode(id)
+	if err != nil || hrp != "note" {
+		return "", ErrInvalidID
+	}
+	b, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil || len(b) != 32 {
+		return "", ErrInvalidID
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// npub encodes a hex public key as a bech32 npub.
+func npub(pubkey string) string {
+	b, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return ""
+	}
+	data, err := bech32.ConvertBits(b, 8, 5, true)
+	if err != nil {
+		return ""
+	}
+	s, err := bech32.Encode("npub", data)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// verifySignature checks that the event id is the hash of its contents and
+// that sig is pubkey's schnorr signature of it (NIP-01).
+func (e *nostrEvent) verifySignature() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode([]interface{}{0, e.PubKey, e.CreatedAt, e.Kind, e.Tags, e.Content})
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	if hex.EncodeToString(hash[:]) != e.ID {
+		return errors.New("event id does not match its contents")
+	}
+
+	pubkey, err := hex.DecodeString(e.PubKey)
+	if err != nil {
+		return err
+	}
+	pk, err := schnorr.ParsePubKey(pubkey)
+	if err != nil {
+		return err
+	}
+	sigBytes, err := hex.DecodeString(e.Sig)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.ParseSignature(sigBytes)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(hash[:], pk) {
+		return errors.New("invalid event signature")
+	}
+	return nil
+}
+
+// VerifyNostr requests the event id from every relay in NostrRelays and
+// returns the publisher name and txid from the verification statement of the
+// first validly signed copy received, along with the author's npub. Relays
+// that are unreachable or don't have the event are skipped.
+func (v *Verifier) VerifyNostr(ctx context.Context, id string) (name string, txid string, author string, err error) {
+	hexID, err := parseNostrID(id)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(v.NostrRelays) == 0 {
+		return "", "", "", &UnavailableError{What: "nostr relays", Err: errors.New("no relays configured")}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.NostrTimeout)
+	defer cancel()
+
+	type relayResult struct {
+		event *nostrEvent
+		err   error
+	}
+	results := make(chan relayResult, len(v.NostrRelays))
+	for _, relay := range v.NostrRelays {
+		go func(relay string) {
+			event, err := v.fetchNostrEvent(ctx, relay, hexID)
+			if err == nil {
+				err = event.verifySignature()
+			}
+			if err != nil && !errors.Is(err, errNostrEventNotFound) {
+				Logger(ctx).Warn("Unable to fetch nostr event from relay", "relay", relay, "id", hexID, "err", err)
+			}
+			results <- relayResult{event, err}
+		}(relay)
+	}
+
+	var lastErr error
+	notFound := 0
+	for range v.NostrRelays {
+		r := <-results
+		if r.err == nil {
+			cancel()
+			name, txid, err = v.matchVerification(ctx, r.event.Content)
+			return name, txid, npub(r.event.PubKey), err
+		}
+		if errors.Is(r.err, errNostrEventNotFound) {
+			notFound++
+		}
+		lastErr = r.err
+	}
+	if notFound > 0 {
+		return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "No relay returned a validly signed nostr event with ID " + id}
+	}
+	return "", "", "", &UnavailableError{What: "nostr relays", Err: lastErr}
+}
+
+// fetchNostrEvent sends a REQ for the event id to relay and waits for the
+// event or the relay's EOSE.
+func (v *Verifier) fetchNostrEvent(ctx context.Context, relay, id string) (_ *nostrEvent, err error) {
+	host := relay
+	if u, err := url.Parse(relay); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	err = v.allowUpstream("nostr", host)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer func() {
+		// a relay without the event is working
+		failure := err
+		if errors.Is(failure, errNostrEventNotFound) {
+			failure = nil
+		}
+		v.observeUpstream(ctx, "nostr", host, start, nil, failure)
+	}()
+
+	dialer := *websocket.DefaultDialer
+	if v.Proxy != nil {
+		dialer.Proxy = v.Proxy
+	}
+	header := make(http.Header)
+	v.setUserAgent(header, "nostr")
+	conn, _, err := dialer.DialContext(ctx, relay, header)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	// unblock the reads below when ctx is cancelled early
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	conn.SetReadLimit(v.MaxBodySize)
+
+	const subID = "oip-verifier"
+	err = conn.WriteJSON([]interface{}{"REQ", subID, map[string]interface{}{"ids": []string{id}, "limit": 1}})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var msg []json.RawMessage
+		err = conn.ReadJSON(&msg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if len(msg) < 2 {
+			continue
+		}
+		var typ, sub string
+		_ = json.Unmarshal(msg[0], &typ)
+		_ = json.Unmarshal(msg[1], &sub)
+		if sub != subID {
+			continue
+		}
+		switch typ {
+		case "EVENT":
+			if len(msg) < 3 {
+				continue
+			}
+			event := &nostrEvent{}
+			err = json.Unmarshal(msg[2], event)
+			if err != nil {
+				return nil, &DecodeError{URL: "nostr event from " + relay, Err: err}
+			}
+			if event.ID != id {
+				continue
+			}
+			_ = conn.WriteJSON([]interface{}{"CLOSE", subID})
+			return event, nil
+		case "EOSE", "CLOSED":
+			return nil, errNostrEventNotFound
+		}
+	}
+}
+
+type nostrPlatform struct {
+	v *Verifier
+}
+
+func (n nostrPlatform) Name() string {
+	return "nostr"
+}
+
+func (n nostrPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := n.v.VerifyNostr(ctx, id)
+	return name, txid, err
+}
+
+func (n nostrPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return n.v.VerifyNostr(ctx, id)
+}
@@ -0,0 +1,82 @@
+package verifier
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+type lbryClaim struct {
+	CanonicalURL string `json:"canonical_url"`
+	Value        struct {
+		Description string `json:"description"`
+	} `json:"value"`
+	SigningChannel *struct {
+		Name string `json:"name"`
+	} `json:"signing_channel"`
+	Error *struct {
+		Name string `json:"name"`
+		Text string `json:"text"`
+	} `json:"error"`
+}
+
+// VerifyOdysee resolves the lbry:// or odysee.com URL with the LBRY SDK at
+// LBRYApi and returns the verification statement in the claim's description
+// along with the channel that signed it.
+func (v *Verifier) VerifyOdysee(ctx context.Context, ref string) (*Proof, error) {
+	lbryURL, err := lbryURL(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]lbryClaim
+	err = v.jsonRPC(ctx, "odysee", v.LBRYApi, "resolve", map[string]interface{}{"urls": []string{lbryURL}}, &res)
+	if err != nil {
+		return nil, err
+	}
+	claim, ok := res[lbryURL]
+	if !ok || claim.Error != nil {
+		return nil, &PlatformError{Code: CodePostNotFound, Msg: "Unable to resolve LBRY claim " + lbryURL}
+	}
+
+	proof := &Proof{Details: map[string]string{"canonical_url": claim.CanonicalURL}}
+	if claim.SigningChannel == nil {
+		return proof, &PlatformError{Code: CodeNotSigned, Msg: "LBRY claim " + lbryURL + " is not signed by a channel"}
+	}
+	proof.Author = claim.SigningChannel.Name
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, claim.Value.Description)
+	return proof, err
+}
+
+// lbryURL converts an odysee.com URL, where claim ids follow a ':', to the
+// lbry:// URL it shows.
+func lbryURL(ref string) (string, error) {
+	if strings.HasPrefix(ref, "lbry://") {
+		return ref, nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "https" || (u.Host != "odysee.com" && u.Host != "www.odysee.com") || len(u.Path) < 2 {
+		return "", ErrInvalidID
+	}
+	return "lbry://" + strings.Replace(strings.TrimPrefix(u.Path, "/"), ":", "#", -1), nil
+}
+
+type odyseePlatform struct {
+	v *Verifier
+}
+
+func (o odyseePlatform) Name() string {
+	return "odysee"
+}
+
+func (o odyseePlatform) Verify(ctx context.Context, ref string) (string, string, error) {
+	proof, err := o.v.VerifyOdysee(ctx, ref)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (o odyseePlatform) VerifyProof(ctx context.Context, ref string) (*Proof, error) {
+	return o.v.VerifyOdysee(ctx, ref)
+}
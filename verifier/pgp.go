@@ -0,0 +1,131 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+)
+
+// pgpMaxBodySize caps downloaded proofs and keys.
+const pgpMaxBodySize = 64 << 10
+
+// VerifyPGP downloads the clearsigned message at proofURL and checks that it
+// is signed by the PGP key of the publisher its verification statement
+// names, returning the signing key's fingerprint in the proof's details. The
+// publisher's key is fetched from its pgpKeyUrl, or by its pgpFingerprint
+// from PGPKeyServer.
+func (v *Verifier) VerifyPGP(ctx context.Context, proofURL string) (*Proof, error) {
+	u, err := url.Parse(proofURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidID
+	}
+
+	msg, err := v.httpGetText(toClaimHost(ctx), "pgp", proofURL)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := clearsign.Decode(msg)
+	if block == nil {
+		return nil, &PlatformError{Code: CodeBadFormat, Msg: "Proof at " + proofURL + " is not a clearsigned PGP message"}
+	}
+
+	proof := &Proof{}
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, string(block.Plaintext))
+	if err != nil {
+		return proof, err
+	}
+
+	pub, _, err := v.getPublisher(ctx, proof.Txid)
+	if err != nil {
+		if _, upstream := UpstreamMsg(err); upstream {
+			return proof, err
+		}
+		return proof, &PlatformError{Code: CodePublisherNotFound, Msg: "Unable to locate publisher with ID " + proof.Txid}
+	}
+	keyring, err := v.publisherKeyring(ctx, pub)
+	if err != nil {
+		return proof, err
+	}
+
+	signer, err := block.VerifySignature(keyring, nil)
+	if err != nil {
+		if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+			return proof, &PlatformError{Code: CodeKeyMismatch, Msg: "Proof is not signed by publisher " + proof.Txid + "'s PGP key"}
+		}
+		return proof, &PlatformError{Code: CodeSignatureInvalid, Msg: "Proof signature is invalid: " + err.Error()}
+	}
+
+	fingerprint := strings.ToUpper(hex.EncodeToString(signer.PrimaryKey.Fingerprint))
+	proof.Details = map[string]string{"fingerprint": fingerprint}
+	if fp := normalizeFingerprint(pub.PgpFingerprint); fp != "" && fp != fingerprint {
+		return proof, &PlatformError{Code: CodeKeyMismatch, Msg: "Proof is signed by key " + fingerprint + " but publisher's key is " + fp}
+	}
+	return proof, nil
+}
+
+// publisherKeyring fetches pub's PGP public key.
+func (v *Verifier) publisherKeyring(ctx context.Context, pub *Publisher) (openpgp.EntityList, error) {
+	keyURL, keyCtx := pub.PgpKeyUrl, toClaimHost(ctx)
+	if keyURL == "" {
+		fp := normalizeFingerprint(pub.PgpFingerprint)
+		if fp == "" {
+			return nil, &PlatformError{Code: CodeKeyMismatch, Msg: "Publisher has no PGP key"}
+		}
+		keyURL, keyCtx = v.PGPKeyServer+"/vks/v1/by-fingerprint/"+fp, ctx
+	}
+
+	armored, err := v.httpGetText(keyCtx, "pgp", keyURL)
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+			return nil, &PlatformError{Code: CodeKeyMismatch, Msg: "Unable to find publisher's PGP key at " + keyURL}
+		}
+		return nil, err
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armored))
+	if err != nil {
+		return nil, &PlatformError{Code: CodeKeyMismatch, Msg: "Publisher's PGP key at " + keyURL + " is invalid"}
+	}
+	return keyring, nil
+}
+
+func (v *Verifier) httpGetText(ctx context.Context, target, rawurl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain, application/pgp-keys")
+	return v.doRequest(target, req, pgpMaxBodySize)
+}
+
+func normalizeFingerprint(fp string) string {
+	return strings.ToUpper(strings.Replace(strings.TrimPrefix(fp, "0x"), " ", "", -1))
+}
+
+type pgpPlatform struct {
+	v *Verifier
+}
+
+func (p pgpPlatform) Name() string {
+	return "pgp"
+}
+
+func (p pgpPlatform) Verify(ctx context.Context, proofURL string) (string, string, error) {
+	proof, err := p.v.VerifyPGP(ctx, proofURL)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (p pgpPlatform) VerifyProof(ctx context.Context, proofURL string) (*Proof, error) {
+	return p.v.VerifyPGP(ctx, proofURL)
+}
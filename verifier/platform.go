@@ -0,0 +1,552 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PlatformVerifier fetches verification statements from one platform.
+type PlatformVerifier interface {
+	Name() string
+	// Verify fetches the post identified by id (as given in the claim) and
+	// returns the publisher name and txid its verification statement claims.
+	// It returns ErrBadFormat when the post has no verification statement.
+	// The name may be "" for proofs that only carry the txid, in which case
+	// it isn't compared with the publisher's.
+	Verify(ctx context.Context, id string) (claimedName, txid string, err error)
+}
+
+// AuthorVerifier is implemented by platform verifiers that can also report
+// who posted the verification statement.
+type AuthorVerifier interface {
+	PlatformVerifier
+	// VerifyAuthor is Verify, additionally returning the post's author. The
+	// author should be returned even when err is ErrBadFormat.
+	VerifyAuthor(ctx context.Context, id string) (claimedName, txid, author string, err error)
+}
+
+// Proof is everything a ProofVerifier found in a post.
+type Proof struct {
+	Name   string
+	Txid   string
+	Author string
+	// Source is the server the proof came from when the platform has
+	// several, e.g. the nameserver that answered a DNS lookup.
+	Source string
+	// Details are other facts about the post worth reporting, e.g. a
+	// channel id.
+	Details map[string]string
+	// Template is the id of the Template the post's statement matched.
+	Template string
+	// Text is what the templates were matched against, only kept when
+	// DebugRequested.
+	Text string
+}
+
+// ProofVerifier is implemented by platform verifiers that report more about
+// a post than AuthorVerifier can. The Proof should be returned, as far as it
+// is known, even when err is ErrBadFormat.
+type ProofVerifier interface {
+	PlatformVerifier
+	VerifyProof(ctx context.Context, id string) (*Proof, error)
+}
+
+// Platform registers a PlatformVerifier with the claim fields it reads.
+type Platform struct {
+	Verifier PlatformVerifier
+	// ClaimID returns the claim's identifier for a post on this platform, or
+	// "" when the claim doesn't reference this platform.
+	ClaimID func(*VerificationClaim) string
+	// ClaimAuthor optionally returns the author the claim expects to have
+	// posted the statement; the author check is skipped when it returns "".
+	ClaimAuthor func(*VerificationClaim) string
+	// CheckPublisher optionally applies a platform specific check of the
+	// post's author against the publisher record once everything else has
+	// verified. A *PlatformError it returns is reported as is.
+	CheckPublisher func(author string, pub *Publisher) error
+	// Noun is what posts on the platform are called in messages, e.g. "tweet".
+	Noun string
+	// CacheTTL, when longer than the Verifier's, is how long the platform's
+	// successful lookups are cached, for platforms that throttle heavily.
+	CacheTTL time.Duration
+	// Configured optionally reports whether the server has the credentials
+	// the platform needs; without them its posts are NOT_CONFIGURED.
+	Configured func() bool
+}
+
+// builtinPlatforms returns the platforms New registers, in check order.
+func builtinPlatforms(v *Verifier) []Platform {
+	return []Platform{
+		{
+			Verifier:    twitterPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.TwitterId },
+			ClaimAuthor: twitterClaimHandle,
+			Noun:        "tweet",
+			Configured:  v.TwitterConfigured,
+		},
+		{
+			Verifier:    gabPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.GabId },
+			ClaimAuthor: gabClaimUsername,
+			Noun:        "post",
+		},
+		{
+			Verifier:    mastodonPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.MastodonUrl },
+			ClaimAuthor: mastodonClaimAccount,
+			Noun:        "status",
+		},
+		{
+			Verifier:    blueskyPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.BlueskyUri },
+			ClaimAuthor: blueskyClaimHandle,
+			Noun:        "post",
+		},
+		{
+			Verifier: nostrPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.NostrEventId },
+			Noun:     "note",
+		},
+		{
+			Verifier: redditPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.RedditId },
+			Noun:     "post",
+		},
+		{
+			Verifier:    githubPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.GistId },
+			ClaimAuthor: func(vc *VerificationClaim) string { return vc.GitHubHandle },
+			Noun:        "gist",
+		},
+		{
+			Verifier: dnsPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.DnsDomain },
+			Noun:     "domain",
+		},
+		{
+			Verifier: websitePlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.WebsiteUrl },
+			Noun:     "website",
+		},
+		{
+			Verifier:   youtubePlatform{v},
+			ClaimID:    func(vc *VerificationClaim) string { return vc.YoutubeVideoId },
+			Noun:       "video",
+			Configured: func() bool { return v.YouTubeAPIKey != "" },
+		},
+		{
+			Verifier: telegramPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.TelegramPost },
+			Noun:     "post",
+		},
+		{
+			Verifier: pgpPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.SignedProofUrl },
+			Noun:     "signed proof",
+		},
+		{
+			Verifier: matrixPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.MatrixEvent },
+			Noun:     "event",
+		},
+		{
+			Verifier: farcasterPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.FarcasterCast },
+			Noun:     "cast",
+		},
+		{
+			Verifier:       hivePlatform{v},
+			ClaimID:        func(vc *VerificationClaim) string { return vc.HivePermlink },
+			CheckPublisher: hiveCheckPublisher,
+			Noun:           "post",
+		},
+		{
+			Verifier: odyseePlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.OdyseeUrl },
+			Noun:     "claim",
+		},
+		{
+			Verifier: instagramPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.InstagramShortcode },
+			Noun:     "post",
+		},
+		{
+			Verifier:   twitchPlatform{v},
+			ClaimID:    func(vc *VerificationClaim) string { return vc.TwitchLogin },
+			Noun:       "channel",
+			Configured: func() bool { return v.TwitchClientID != "" && v.TwitchClientSecret != "" },
+		},
+		{
+			Verifier: tumblrPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.TumblrPost },
+			Noun:     "post",
+		},
+		{
+			Verifier: substackPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.SubstackUrl },
+			Noun:     "post",
+		},
+		{
+			Verifier: mediumPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.MediumUrl },
+			Noun:     "story",
+		},
+		{
+			Verifier: linkedinPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.LinkedinUrl },
+			Noun:     "post",
+		},
+		{
+			Verifier:   facebookPlatform{v},
+			ClaimID:    func(vc *VerificationClaim) string { return vc.FacebookPostId },
+			Noun:       "post",
+			Configured: func() bool { return v.FacebookToken != "" },
+		},
+		{
+			Verifier: threadsPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.ThreadsPostUrl },
+			Noun:     "post",
+		},
+		{
+			Verifier: tiktokPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.TiktokVideoUrl },
+			Noun:     "video",
+			// TikTok throttles clients it doesn't recognise
+			CacheTTL: 6 * time.Hour,
+		},
+		{
+			Verifier:    rumblePlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.RumbleUrl },
+			ClaimAuthor: func(vc *VerificationClaim) string { return vc.RumbleChannel },
+			Noun:        "video",
+		},
+		{
+			Verifier: soundcloudPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.SoundcloudUrl },
+			Noun:     "track",
+		},
+		{
+			Verifier: vimeoPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.VimeoVideoId },
+			Noun:     "video",
+		},
+		{
+			Verifier:    gitlabPlatform{v},
+			ClaimID:     func(vc *VerificationClaim) string { return vc.GitLabSnippetId },
+			ClaimAuthor: func(vc *VerificationClaim) string { return vc.GitLabHandle },
+			Noun:        "snippet",
+		},
+		{
+			Verifier: mindsPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.MindsGuid },
+			Noun:     "post",
+		},
+		{
+			Verifier:   discordPlatform{v},
+			ClaimID:    func(vc *VerificationClaim) string { return vc.DiscordMessage },
+			Noun:       "message",
+			Configured: func() bool { return v.DiscordBotToken != "" },
+		},
+		{
+			Verifier: floProofPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.ProofUrl },
+			Noun:     "signed proof",
+		},
+		{
+			Verifier: emailPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.EmailProofUrl },
+			Noun:     "message",
+		},
+		{
+			Verifier: gettrPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.GettrPostId },
+			Noun:     "post",
+		},
+		{
+			Verifier: truthSocialPlatform{v},
+			ClaimID:  func(vc *VerificationClaim) string { return vc.TruthSocialPostId },
+			Noun:     "post",
+		},
+	}
+}
+
+// Register adds a platform to those checked by CheckClaim, replacing any
+// platform already registered under the same name.
+func (v *Verifier) Register(p Platform) {
+	name := p.Verifier.Name()
+	for i, existing := range v.platforms {
+		if existing.Verifier.Name() == name {
+			v.platforms[i] = p
+			return
+		}
+	}
+	v.platforms = append(v.platforms, p)
+}
+
+// Restrict unregisters every platform not named in names. It returns an
+// error, leaving the registry unchanged, if any name isn't registered.
+func (v *Verifier) Restrict(names ...string) error {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	var platforms []Platform
+	for _, p := range v.platforms {
+		if keep[p.Verifier.Name()] {
+			platforms = append(platforms, p)
+			delete(keep, p.Verifier.Name())
+		}
+	}
+	for name := range keep {
+		return errors.New("unknown platform " + name + " (expected one of " + strings.Join(v.Platforms(), ", ") + ")")
+	}
+	v.platforms = platforms
+	return nil
+}
+
+// Platforms returns the names of the registered platforms in check order.
+func (v *Verifier) Platforms() []string {
+	names := make([]string, len(v.platforms))
+	for i, p := range v.platforms {
+		names[i] = p.Verifier.Name()
+	}
+	return names
+}
+
+// Configured reports whether the named platform is registered and has what
+// it needs to verify posts.
+func (v *Verifier) Configured(name string) bool {
+	for _, p := range v.platforms {
+		if p.Verifier.Name() == name {
+			return p.Configured == nil || p.Configured()
+		}
+	}
+	return false
+}
+
+// verifyPost runs p's verifier for id through the post cache.
+func (v *Verifier) verifyPost(ctx context.Context, p Platform, id string) (*Proof, error) {
+	pv := p.Verifier
+	ttl := v.settingsFor(ctx).CacheTTL
+	if p.CacheTTL > ttl {
+		ttl = p.CacheTTL
+	}
+	r, err := v.cachedFor(ctx, v.posts, pv.Name()+":"+id, ttl, func() (interface{}, error) {
+		m := &templateMatch{}
+		ctx := withTemplateMatch(ctx, m)
+		proof := &Proof{}
+		var err error
+		if prv, ok := pv.(ProofVerifier); ok {
+			proof, err = prv.VerifyProof(ctx, id)
+			if proof == nil {
+				proof = &Proof{}
+			}
+		} else if av, ok := pv.(AuthorVerifier); ok {
+			proof.Name, proof.Txid, proof.Author, err = av.VerifyAuthor(ctx, id)
+		} else {
+			proof.Name, proof.Txid, err = pv.Verify(ctx, id)
+		}
+		m.mu.Lock()
+		proof.Template = m.id
+		if DebugRequested(ctx) {
+			proof.Text = m.text
+		}
+		m.mu.Unlock()
+		return proof, err
+	})
+	return r.(*Proof), err
+}
+
+// A post is what checkPlatform verifies: a post on a platform and what it
+// is expected to say.
+type post struct {
+	platform Platform
+	id       string
+	// author is the expected author, if any, without an @.
+	author string
+	// publisher is the txid the post must point at, if any.
+	publisher string
+	// claim is the claim record the post is for, or nil for ad hoc checks,
+	// which skip the signer check.
+	claim *RMeta
+}
+
+// claimPost returns the claim's post on platform p.
+func claimPost(p Platform, vc *VerificationClaim, meta *RMeta) post {
+	pp := post{platform: p, id: p.ClaimID(vc), publisher: vc.RegisteredPublisher, claim: meta}
+	if p.ClaimAuthor != nil {
+		pp.author = strings.TrimPrefix(p.ClaimAuthor(vc), "@")
+	}
+	return pp
+}
+
+// capitalize upper-cases the first letter of s, for the Nouns of platforms
+// at the start of a message. Nouns are ASCII.
+func capitalize(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
+
+// checkPlatform verifies the post. It returns the platform's status, the
+// publisher txid the post points at, and that publisher when its record was
+// found.
+func (v *Verifier) checkPlatform(ctx context.Context, pp post) (*PlatformStatus, string, *PublisherMeta) {
+	p, id := pp.platform, pp.id
+	status := &PlatformStatus{Post: id}
+	noun := p.Noun
+
+	ctx, span := startSpan(ctx, platformSpanName(p.Verifier.Name()), attribute.String("platform", p.Verifier.Name()), attribute.String("post", id))
+	outcome := OutcomeVerified
+	defer func() {
+		v.outcome(p.Verifier.Name(), outcome)
+		span.SetAttributes(attribute.String("outcome", outcome), attribute.String("code", status.Code), attribute.Bool("verified", status.Verified))
+		span.End()
+	}()
+
+	pr, err := v.verifyPost(ctx, p, id)
+	name, txid, author := pr.Name, pr.Txid, pr.Author
+	status.Author, status.Source, status.Template = author, pr.Source, pr.Template
+	status.Name, status.Txid = name, txid
+	if len(pr.Details) != 0 {
+		status.Details = make(map[string]string, len(pr.Details))
+		for k, d := range pr.Details {
+			status.Details[k] = d
+		}
+	}
+	if DebugRequested(ctx) {
+		status.Debug = &PlatformDebug{Text: pr.Text, Matched: pr.Template != "", Name: name, Txid: txid}
+		if err != nil {
+			status.Debug.Error = err.Error()
+		}
+	}
+	if err != nil {
+		var rl *RateLimitError
+		var pe *PlatformError
+		if errors.As(err, &rl) {
+			outcome = OutcomeRateLimited
+			status.RetryAfter = rl.RetryAfterSeconds()
+			status.fail(CodeRateLimited, rateLimitedMsg(status.RetryAfter))
+		} else if errors.As(err, &pe) {
+			outcome = strings.ToLower(pe.Code)
+			status.fail(pe.Code, pe.Msg)
+		} else if err == ErrInvalidID {
+			outcome = OutcomeNotFound
+			status.fail(CodeInvalidId, "Invalid "+noun+" ID "+id)
+		} else if err == ErrBadFormat {
+			outcome = OutcomeBadFormat
+			status.fail(CodeBadFormat, capitalize(noun)+" contents not properly formatted")
+		} else if msg, ok := UpstreamMsg(err); ok {
+			outcome = OutcomeUpstreamError
+			status.fail(CodeUpstreamError, "Unable to fetch "+noun+" with ID "+id+": "+msg)
+		} else {
+			outcome = OutcomeNotFound
+			status.fail(CodePostNotFound, "Unable to locate "+noun+" with ID "+id)
+		}
+		return status, "", nil
+	}
+
+	expectedAuthor := pp.author
+	pub, pubMeta, err := v.getPublisher(ctx, txid)
+	if status.Debug != nil {
+		status.Debug.ExpectedAuthor = expectedAuthor
+		if err == nil {
+			status.Debug.PublisherName = pub.Name
+		} else {
+			status.Debug.Error = err.Error()
+		}
+	}
+	if err != nil {
+		if msg, ok := UpstreamMsg(err); ok {
+			outcome = OutcomeUpstreamError
+			status.fail(CodeUpstreamError, "Unable to fetch publisher with ID "+txid+": "+msg)
+		} else {
+			outcome = OutcomeNotFound
+			status.fail(CodePublisherNotFound, "Unable to locate publisher with ID "+txid)
+		}
+	} else if pubMeta.Deactivated {
+		outcome = OutcomeDeactivated
+		status.fail(CodeDeactivated, "Publisher record has been deactivated")
+	} else if pp.claim != nil && !v.signersMatch(pp.claim, pubMeta) {
+		outcome = OutcomeSignerMismatch
+		status.fail(CodeSignerMismatch, signerMismatchMsg)
+	} else if len(pp.publisher) != 0 && pp.publisher != txid {
+		outcome = OutcomePublisherMismatch
+		status.fail(CodePublisherMismatch, capitalize(noun)+" points at publisher "+txid+" but claim is for publisher "+pp.publisher)
+	} else if len(name) != 0 && v.normalizeName(pub.Name) != v.normalizeName(name) {
+		outcome = OutcomePublisherMismatch
+		status.fail(CodeNameMismatch, "Claimed name doesn't match publisher name")
+	} else if len(expectedAuthor) != 0 && !strings.EqualFold(expectedAuthor, author) {
+		outcome = OutcomeAuthorMismatch
+		status.fail(CodeAuthorMismatch, capitalize(noun)+" was posted by @"+author+" but claim is for @"+expectedAuthor)
+	} else if err := checkPublisher(p, author, pub); err != nil {
+		outcome = OutcomeAuthorMismatch
+		var pe *PlatformError
+		if errors.As(err, &pe) {
+			status.fail(pe.Code, pe.Msg)
+		} else {
+			status.fail(CodeAuthorMismatch, err.Error())
+		}
+	} else {
+		status.Verified = true
+		status.Code = CodeOK
+		if len(name) != 0 && name != pub.Name {
+			status.Note = "Claimed name \"" + name + "\" differs from publisher name \"" + pub.Name + "\" in case, spacing, or Unicode form"
+		}
+	}
+	if pub == nil {
+		return status, txid, nil
+	}
+	return status, txid, &PublisherMeta{Txid: txid, Name: pub.Name, FloBip44XPub: pub.FloBip44XPub, SignedBy: pubMeta.SignedBy, Time: pubMeta.Time}
+}
+
+func rateLimitedMsg(retryAfter int) string {
+	return "Verification temporarily unavailable, retry after " + strconv.Itoa(retryAfter) + " seconds"
+}
+
+func checkPublisher(p Platform, author string, pub *Publisher) error {
+	if p.CheckPublisher == nil {
+		return nil
+	}
+	return p.CheckPublisher(author, pub)
+}
+
+type twitterPlatform struct {
+	v *Verifier
+}
+
+func (t twitterPlatform) Name() string {
+	return "twitter"
+}
+
+func (t twitterPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTwitter(ctx, id)
+	return name, txid, err
+}
+
+func (t twitterPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return t.v.VerifyTwitter(ctx, id)
+}
+
+type gabPlatform struct {
+	v *Verifier
+}
+
+func (g gabPlatform) Name() string {
+	return "gab"
+}
+
+func (g gabPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := g.v.VerifyGab(ctx, id)
+	return name, txid, err
+}
+
+func (g gabPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return g.v.VerifyGab(ctx, id)
+}
@@ -0,0 +1,129 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redditUserAgent is the default UserAgents entry for reddit, which
+// rejects requests without a descriptive user agent.
+const redditUserAgent = "oip-verifier/1.0 (+https://github.com/oipwg/verifier)"
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data redditThing `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditThing struct {
+	ID       string `json:"id"`
+	Author   string `json:"author"`
+	Selftext string `json:"selftext"`
+	Body     string `json:"body"`
+}
+
+// parseRedditPermalink returns the path of a post or comment permalink such
+// as r/openindex/comments/abc123/title/ and, for comments, the comment id.
+func parseRedditPermalink(permalink string) (path, commentID string, err error) {
+	path = strings.Trim(permalink, "/")
+	path = strings.TrimPrefix(path, "https://www.reddit.com/")
+	path = strings.TrimPrefix(path, "https://reddit.com/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 || parts[0] != "r" || parts[2] != "comments" {
+		return "", "", ErrInvalidID
+	}
+	if len(parts) >= 6 {
+		commentID = parts[5]
+	}
+	return path, commentID, nil
+}
+
+// VerifyReddit fetches the post or comment at permalink and returns the
+// publisher name and txid from its verification statement along with its
+// author's username.
+func (v *Verifier) VerifyReddit(ctx context.Context, permalink string) (name string, txid string, author string, err error) {
+	path, commentID, err := parseRedditPermalink(permalink)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.reddit.com/"+path+"/.json?raw_json=1", nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	body, err := v.doRequest("reddit", req, v.MaxBodySize)
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests {
+			return "", "", "", &RateLimitError{Target: "reddit", Reset: redditReset(se.Header)}
+		}
+		return "", "", "", err
+	}
+
+	var listings []redditListing
+	err = json.Unmarshal(body, &listings)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "reddit " + permalink, Err: err}
+	}
+
+	var thing *redditThing
+	var text string
+	if commentID == "" {
+		if len(listings) > 0 && len(listings[0].Data.Children) > 0 {
+			thing = &listings[0].Data.Children[0].Data
+			text = thing.Selftext
+		}
+	} else if len(listings) > 1 {
+		for _, c := range listings[1].Data.Children {
+			if c.Data.ID == commentID {
+				thing = &c.Data
+				text = thing.Body
+				break
+			}
+		}
+	}
+	if thing == nil {
+		return "", "", "", ErrPostNotFound
+	}
+	if text == "[removed]" || text == "[deleted]" || thing.Author == "[deleted]" {
+		return "", "", "", &PlatformError{Code: CodePostRemoved, Msg: "Reddit post " + permalink + " has been removed or deleted"}
+	}
+
+	name, txid, err = v.matchVerification(ctx, text)
+	return name, txid, thing.Author, err
+}
+
+// redditReset returns when Reddit's rate limit resets, from the seconds in
+// the X-Ratelimit-Reset header.
+func redditReset(h http.Header) time.Time {
+	secs, err := strconv.ParseFloat(h.Get("X-Ratelimit-Reset"), 64)
+	if err != nil {
+		secs = 60
+	}
+	return time.Now().Add(time.Duration(math.Ceil(secs)) * time.Second)
+}
+
+type redditPlatform struct {
+	v *Verifier
+}
+
+func (r redditPlatform) Name() string {
+	return "reddit"
+}
+
+func (r redditPlatform) Verify(ctx context.Context, permalink string) (string, string, error) {
+	name, txid, _, err := r.v.VerifyReddit(ctx, permalink)
+	return name, txid, err
+}
+
+func (r redditPlatform) VerifyAuthor(ctx context.Context, permalink string) (string, string, string, error) {
+	return r.v.VerifyReddit(ctx, permalink)
+}
@@ -0,0 +1,67 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+var rumbleURLRegex = regexp.MustCompile(`^https://(?:www\.)?rumble\.com/(v[0-9a-z]+-[^/?#]*\.html)(?:\?.*)?$`)
+
+// VerifyRumble fetches the page of the video at videoURL and returns the
+// publisher name and txid from the verification statement in its
+// description along with its channel name, both read from the page's
+// VideoObject JSON-LD.
+func (v *Verifier) VerifyRumble(ctx context.Context, videoURL string) (name string, txid string, channel string, err error) {
+	m := rumbleURLRegex.FindStringSubmatch(videoURL)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+
+	body, err := v.httpGetPage(ctx, "rumble", "https://rumble.com/"+m[1], v.MaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) || isStatus(err, http.StatusGone) {
+			return "", "", "", &PlatformError{Code: CodePostRemoved, Msg: "Rumble video " + videoURL + " has been removed or does not exist"}
+		}
+		return "", "", "", err
+	}
+
+	var video map[string]interface{}
+	for _, script := range jsonLD(string(body)) {
+		var ld interface{}
+		if json.Unmarshal([]byte(script), &ld) != nil {
+			continue
+		}
+		video = findObject(ld, func(o map[string]interface{}) bool {
+			return o["@type"] == "VideoObject"
+		})
+		if video != nil {
+			break
+		}
+	}
+	if video == nil {
+		return "", "", "", &PlatformError{Code: CodePostRemoved, Msg: "Rumble video " + videoURL + " has been removed or does not exist"}
+	}
+
+	channel = jsonString(video, "author", "name")
+	name, txid, err = v.matchVerification(ctx, htmlToText(jsonString(video, "description")))
+	return name, txid, channel, err
+}
+
+type rumblePlatform struct {
+	v *Verifier
+}
+
+func (r rumblePlatform) Name() string {
+	return "rumble"
+}
+
+func (r rumblePlatform) Verify(ctx context.Context, videoURL string) (string, string, error) {
+	name, txid, _, err := r.v.VerifyRumble(ctx, videoURL)
+	return name, txid, err
+}
+
+func (r rumblePlatform) VerifyAuthor(ctx context.Context, videoURL string) (string, string, string, error) {
+	return r.v.VerifyRumble(ctx, videoURL)
+}
@@ -0,0 +1,157 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// claimSearchLimit is how many of a publisher's claims are considered when
+// looking for its latest.
+const claimSearchLimit = 50
+
+// oipSearch runs the OIP api record search q, an elasticsearch query string,
+// returning up to limit records, newest first.
+func (v *Verifier) oipSearch(ctx context.Context, q string, limit int) (*oipApiResult, error) {
+	query := url.Values{"q": {q}, "limit": {strconv.Itoa(limit)}, "sort": {"meta.time:desc"}}
+	body, err := v.oipGetURL(ctx, v.OipURL("o5", "record", "search")+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	results := &oipApiResult{}
+	err = json.Unmarshal(body, results)
+	if err != nil {
+		return nil, &DecodeError{URL: "record search", Err: err}
+	}
+	return results, nil
+}
+
+// searchQuote quotes s as an elasticsearch query string phrase.
+func searchQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// claimSearch is the result of finding a publisher's latest claim.
+type claimSearch struct {
+	txid        string
+	deactivated bool
+}
+
+// findClaim returns the txid of the latest verification claim signed by the
+// same address as the publisher record pubTxid, preferring claims that
+// haven't been deactivated. It returns ErrClaimNotFound if there are none.
+func (v *Verifier) findClaim(ctx context.Context, pubTxid string) (claimSearch, error) {
+	r, err := v.cached(ctx, v.claimSearches, pubTxid, func() (interface{}, error) {
+		_, pubMeta, err := v.getPublisher(ctx, pubTxid)
+		if err != nil {
+			return claimSearch{}, err
+		}
+		results, err := v.oipSearch(ctx, "_exists_:record.details.tmpl_F471DFF9 AND meta.signed_by:"+searchQuote(pubMeta.SignedBy), claimSearchLimit)
+		if err != nil {
+			return claimSearch{}, err
+		}
+		claims := results.Results
+		if len(claims) == 0 {
+			return claimSearch{}, ErrClaimNotFound
+		}
+		sort.SliceStable(claims, func(i, j int) bool {
+			if claims[i].Meta.Deactivated != claims[j].Meta.Deactivated {
+				return !claims[i].Meta.Deactivated
+			}
+			return claims[i].Meta.Time > claims[j].Meta.Time
+		})
+		return claimSearch{txid: claims[0].Meta.Txid, deactivated: claims[0].Meta.Deactivated}, nil
+	})
+	return r.(claimSearch), err
+}
+
+// CheckPublisherClaim finds the latest verification claim of the publisher
+// record pubTxid and checks it as CheckClaim does. The claim's txid is
+// reported in the response's Claim.
+func (v *Verifier) CheckPublisherClaim(ctx context.Context, pubTxid string) (*Result, error) {
+	found, err := v.findClaim(ctx, pubTxid)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		status := &Result{}
+		if msg, ok := UpstreamMsg(err); ok {
+			status.Code = CodeUpstreamError
+			status.Msg = "Unable to search for verification claims of publisher " + pubTxid + ": " + msg
+		} else if err == ErrPublisherNotFound {
+			status.Code = CodePublisherNotFound
+			status.Msg = "Unable to locate publisher with ID " + pubTxid
+		} else {
+			status.Code = CodeClaimNotFound
+			status.Msg = "Publisher " + pubTxid + " has no verification claims"
+		}
+		return status, nil
+	}
+
+	status, err := v.CheckClaim(ctx, found.txid)
+	if err == nil && found.deactivated {
+		status.Msg = "All of the publisher's verification claims have been deactivated"
+	}
+	return status, err
+}
+
+// nameSearchLimit caps how many publishers with a name are checked.
+const nameSearchLimit = 10
+
+// PublisherCheck is the check of one of the publishers with a name.
+type PublisherCheck struct {
+	Publisher PublisherMeta `json:"publisher"`
+	Result    *Result       `json:"result"`
+}
+
+// searchEscape escapes the characters elasticsearch query strings reserve.
+var searchEscape = strings.NewReplacer(
+	`\`, `\\`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `&`, `\&`, `|`, `\|`, `>`, `\>`, `<`, `\<`,
+	`!`, `\!`, `(`, `\(`, `)`, `\)`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`, `^`, `\^`,
+	`"`, `\"`, `~`, `\~`, `*`, `\*`, `?`, `\?`, `:`, `\:`, `/`, `\/`,
+)
+
+// CheckPublisherName finds the publisher records named name and checks the
+// latest claim of each, up to nameSearchLimit of them, newest first. Names
+// must match exactly unless fuzzy, when the OIP api's analyzer decides. The
+// error is only non-nil when ctx ended or the search itself failed.
+func (v *Verifier) CheckPublisherName(ctx context.Context, name string, fuzzy bool) ([]*PublisherCheck, error) {
+	q := "record.details.tmpl_433C2783.name:" + searchQuote(name)
+	if fuzzy {
+		q = "record.details.tmpl_433C2783.name:(" + searchEscape.Replace(name) + ")"
+	}
+	results, err := v.oipSearch(ctx, q, claimSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []*PublisherCheck
+	for _, r := range results.Results {
+		pub := &r.Record.Details.Publisher
+		if !fuzzy && pub.Name != name {
+			continue
+		}
+		checks = append(checks, &PublisherCheck{Publisher: PublisherMeta{Txid: r.Meta.Txid, Name: pub.Name, FloBip44XPub: pub.FloBip44XPub, SignedBy: r.Meta.SignedBy, Time: r.Meta.Time}})
+		if len(checks) == nameSearchLimit {
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c *PublisherCheck) {
+			defer wg.Done()
+			c.Result, _ = v.CheckPublisherClaim(ctx, c.Publisher.Txid)
+		}(c)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return checks, nil
+}
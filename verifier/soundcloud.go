@@ -0,0 +1,122 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type soundcloudResource struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Permalink   string `json:"permalink"`
+	Sharing     string `json:"sharing"`
+	User        struct {
+		Permalink string `json:"permalink"`
+	} `json:"user"`
+}
+
+type soundcloudOEmbed struct {
+	Description string `json:"description"`
+	AuthorURL   string `json:"author_url"`
+}
+
+var soundcloudURLRegex = regexp.MustCompile(`^https://(?:www\.|m\.)?soundcloud\.com/[A-Za-z0-9_-]+(?:/[A-Za-z0-9_-]+)*/?$`)
+
+// VerifySoundCloud resolves the track or profile at resourceURL and returns
+// the publisher name and txid from the verification statement in its
+// description along with the uploader's permalink. With a SoundCloudClientID
+// the api's resolve endpoint is used, otherwise oEmbed.
+func (v *Verifier) VerifySoundCloud(ctx context.Context, resourceURL string) (name string, txid string, permalink string, err error) {
+	if !soundcloudURLRegex.MatchString(resourceURL) {
+		return "", "", "", ErrInvalidID
+	}
+
+	var description string
+	if v.SoundCloudClientID != "" {
+		description, permalink, err = v.soundcloudResolve(ctx, resourceURL)
+	} else {
+		description, permalink, err = v.soundcloudOEmbed(ctx, resourceURL)
+	}
+	if err != nil {
+		switch {
+		case isStatus(err, http.StatusForbidden) || isStatus(err, http.StatusUnauthorized):
+			return "", "", "", &PlatformError{Code: CodeNotPublic, Msg: "SoundCloud track " + resourceURL + " is private"}
+		case isStatus(err, http.StatusNotFound):
+			return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "SoundCloud track " + resourceURL + " is private, has been deleted, or does not exist"}
+		}
+		return "", "", permalink, err
+	}
+
+	name, txid, err = v.matchVerification(ctx, description)
+	return name, txid, permalink, err
+}
+
+func (v *Verifier) soundcloudResolve(ctx context.Context, resourceURL string) (string, string, error) {
+	q := url.Values{"url": {resourceURL}, "client_id": {v.SoundCloudClientID}}
+	body, err := v.httpGet(ctx, "soundcloud", "https://api-v2.soundcloud.com/resolve?"+q.Encode())
+	if err != nil {
+		var se *StatusError
+		if isStatus(err, http.StatusUnauthorized) {
+			return "", "", &PlatformError{Code: CodeNotConfigured, Msg: "SoundCloud rejected this server's client id"}
+		} else if errors.As(err, &se) {
+			// the URL has the client id in it
+			se.URL = "soundcloud resolve " + resourceURL
+		}
+		return "", "", err
+	}
+
+	res := &soundcloudResource{}
+	err = json.Unmarshal(body, res)
+	if err != nil {
+		return "", "", &DecodeError{URL: "soundcloud resolve " + resourceURL, Err: err}
+	}
+	permalink := res.User.Permalink
+	if res.Kind == "user" {
+		permalink = res.Permalink
+	}
+	if res.Sharing == "private" {
+		return "", permalink, &StatusError{URL: "soundcloud resolve " + resourceURL, StatusCode: http.StatusForbidden}
+	}
+	return res.Description, permalink, nil
+}
+
+func (v *Verifier) soundcloudOEmbed(ctx context.Context, resourceURL string) (string, string, error) {
+	q := url.Values{"url": {resourceURL}, "format": {"json"}}
+	body, err := v.httpGet(ctx, "soundcloud", "https://soundcloud.com/oembed?"+q.Encode())
+	if err != nil {
+		return "", "", err
+	}
+
+	res := &soundcloudOEmbed{}
+	err = json.Unmarshal(body, res)
+	if err != nil {
+		return "", "", &DecodeError{URL: "soundcloud oembed " + resourceURL, Err: err}
+	}
+	var permalink string
+	if u, err := url.Parse(res.AuthorURL); err == nil {
+		permalink = strings.Trim(u.Path, "/")
+	}
+	return res.Description, permalink, nil
+}
+
+type soundcloudPlatform struct {
+	v *Verifier
+}
+
+func (s soundcloudPlatform) Name() string {
+	return "soundcloud"
+}
+
+func (s soundcloudPlatform) Verify(ctx context.Context, resourceURL string) (string, string, error) {
+	name, txid, _, err := s.v.VerifySoundCloud(ctx, resourceURL)
+	return name, txid, err
+}
+
+func (s soundcloudPlatform) VerifyAuthor(ctx context.Context, resourceURL string) (string, string, string, error) {
+	return s.v.VerifySoundCloud(ctx, resourceURL)
+}
@@ -0,0 +1,91 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type substackArticle struct {
+	ArticleBody         string      `json:"articleBody"`
+	IsAccessibleForFree interface{} `json:"isAccessibleForFree"`
+}
+
+// substackMaxBodySize bounds a post page, which is much smaller than
+// MaxBodySize allows.
+const substackMaxBodySize = 2 << 20
+
+var substackHostRegex = regexp.MustCompile(`^([a-z0-9][a-z0-9-]{0,62})\.substack\.com$`)
+
+// VerifySubstack fetches the post at postURL and returns the verification
+// statement in its body along with the newsletter's subdomain, both as the
+// author and in the "newsletter" detail.
+func (v *Verifier) VerifySubstack(ctx context.Context, postURL string) (*Proof, error) {
+	u, err := url.Parse(postURL)
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Path, "/p/") {
+		return nil, ErrInvalidID
+	}
+	m := substackHostRegex.FindStringSubmatch(strings.ToLower(u.Host))
+	if m == nil {
+		return nil, ErrInvalidID
+	}
+	proof := &Proof{Author: m[1], Details: map[string]string{"newsletter": m[1]}}
+
+	body, err := v.httpGetPage(ctx, "substack", "https://"+m[0]+u.Path, substackMaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return proof, &PlatformError{Code: CodePostNotFound, Msg: "Substack post " + postURL + " has been deleted or does not exist"}
+		}
+		return proof, err
+	}
+	page := string(body)
+
+	paywalled := strings.Contains(page, `class="paywall`)
+	var text []string
+	for _, script := range jsonLD(page) {
+		article := &substackArticle{}
+		if json.Unmarshal([]byte(script), article) != nil {
+			continue
+		}
+		text = append(text, article.ArticleBody)
+		// schema.org allows the flag as a boolean or text
+		switch free := article.IsAccessibleForFree.(type) {
+		case bool:
+			paywalled = paywalled || !free
+		case string:
+			paywalled = paywalled || strings.EqualFold(free, "false")
+		}
+	}
+	if body, ok := elementText(page, "available-content"); ok {
+		text = append(text, body)
+	}
+
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, strings.Join(text, "\n"))
+	if err != nil && paywalled {
+		return proof, &PlatformError{Code: CodePaywalled, Msg: "Substack post " + postURL + " is paywalled and its free preview has no verification statement"}
+	}
+	return proof, err
+}
+
+type substackPlatform struct {
+	v *Verifier
+}
+
+func (s substackPlatform) Name() string {
+	return "substack"
+}
+
+func (s substackPlatform) Verify(ctx context.Context, postURL string) (string, string, error) {
+	proof, err := s.v.VerifySubstack(ctx, postURL)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (s substackPlatform) VerifyProof(ctx context.Context, postURL string) (*Proof, error) {
+	return s.v.VerifySubstack(ctx, postURL)
+}
@@ -0,0 +1,71 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	telegramPostRegex  = regexp.MustCompile(`^(?:https://t\.me/)?([A-Za-z][A-Za-z0-9_]{3,31})/([0-9]+)$`)
+	telegramOwnerRegex = regexp.MustCompile(`data-post="([A-Za-z0-9_]+)/[0-9]+"`)
+	telegramErrorRegex = regexp.MustCompile(`(?s)class="tgme_widget_message_error"[^>]*>(.*?)</div>`)
+)
+
+// VerifyTelegram fetches the embed page of the public channel post
+// channel/messageId and returns the publisher name and txid from its
+// verification statement along with the channel's username.
+func (v *Verifier) VerifyTelegram(ctx context.Context, post string) (name string, txid string, channel string, err error) {
+	m := telegramPostRegex.FindStringSubmatch(post)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+	channel = m[1]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://t.me/"+m[1]+"/"+m[2]+"?embed=1", nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	body, err := v.doRequest("telegram", req, v.MaxBodySize)
+	if err != nil {
+		return "", "", "", err
+	}
+	page := string(body)
+
+	if em := telegramErrorRegex.FindStringSubmatch(page); em != nil {
+		msg := strings.ToLower(htmlToText(em[1]))
+		if strings.Contains(msg, "not found") {
+			return "", "", channel, &PlatformError{Code: CodePostNotFound, Msg: "Telegram post " + post + " has been deleted or does not exist"}
+		}
+		return "", "", channel, &PlatformError{Code: CodeNotPublic, Msg: "Telegram channel " + channel + " is private or the post is unavailable"}
+	}
+	if om := telegramOwnerRegex.FindStringSubmatch(page); om != nil {
+		channel = om[1]
+	}
+
+	text, ok := elementText(page, "tgme_widget_message_text")
+	if !ok {
+		return "", "", channel, &PlatformError{Code: CodeNotPublic, Msg: "Telegram channel " + channel + " is private or the post is unavailable"}
+	}
+	name, txid, err = v.matchVerification(ctx, text)
+	return name, txid, channel, err
+}
+
+type telegramPlatform struct {
+	v *Verifier
+}
+
+func (t telegramPlatform) Name() string {
+	return "telegram"
+}
+
+func (t telegramPlatform) Verify(ctx context.Context, post string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTelegram(ctx, post)
+	return name, txid, err
+}
+
+func (t telegramPlatform) VerifyAuthor(ctx context.Context, post string) (string, string, string, error) {
+	return t.v.VerifyTelegram(ctx, post)
+}
@@ -0,0 +1,181 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A Template is one accepted wording of the verification statement. Pattern
+// must have the named groups name and txid, e.g. (?P<name>.+), which capture
+// the publisher name and the publisher record's txid. Format, if set, is the
+// statement for publishers to post, with {name} and {txid} in place of the
+// publisher's; Pattern must match it.
+type Template struct {
+	ID      string `json:"id"`
+	Pattern string `json:"pattern"`
+	Format  string `json:"format,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// BuiltinTemplates is the statement wording used when no templates are
+// configured.
+var BuiltinTemplates = []*Template{
+	mustTemplate("oip-v1", `@OpenIndexProto(?:col)?\p{Zs}verifying\p{Zs}[\p{Pi}"'](?P<name>.+)[\p{Pf}"']\p{Zs}is\p{Zs}publishing\p{Zs}as:[\s\p{Zs}]+(?P<txid>[0-9a-f]{64})`,
+		"@OpenIndexProtocol verifying \"{name}\" is publishing as: \n{txid}"),
+}
+
+func mustTemplate(id, pattern, format string) *Template {
+	t := &Template{ID: id, Pattern: pattern, Format: format}
+	err := t.compile()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *Template) compile() error {
+	if t.ID == "" {
+		return errors.New("template has no id")
+	}
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return fmt.Errorf("template %s: %v", t.ID, err)
+	}
+	if re.SubexpIndex("name") < 0 || re.SubexpIndex("txid") < 0 {
+		return fmt.Errorf("template %s: pattern needs groups (?P<name>...) and (?P<txid>...)", t.ID)
+	}
+	t.re = re
+	if t.Format != "" {
+		const sampleName, sampleTxid = "Example Publisher", "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		name, txid, ok := t.match(normalizeText(t.Render(sampleName, sampleTxid)))
+		if !ok || name != sampleName || txid != sampleTxid {
+			return fmt.Errorf("template %s: pattern doesn't match format", t.ID)
+		}
+	}
+	return nil
+}
+
+// Render returns the statement for the publisher to post, or "" if t has no
+// Format.
+func (t *Template) Render(name, txid string) string {
+	return strings.NewReplacer("{name}", name, "{txid}", txid).Replace(t.Format)
+}
+
+// A Statement is the text a publisher should post to verify.
+type Statement struct {
+	Template string `json:"template"`
+	Text     string `json:"text"`
+	// Warning is set when the text wouldn't verify as the publisher's name,
+	// e.g. because the name has a line break in it.
+	Warning string `json:"warning,omitempty"`
+}
+
+// Statement returns the statement publisher pubTxid should post, in the
+// first of Templates that has a Format.
+func (v *Verifier) Statement(ctx context.Context, pubTxid string) (*Statement, error) {
+	ctx = v.withSettings(ctx)
+	var t *Template
+	for _, tt := range v.settingsFor(ctx).Templates {
+		if tt.Format != "" {
+			t = tt
+			break
+		}
+	}
+	if t == nil {
+		return nil, ErrNoTemplateFormat
+	}
+
+	pub, _, err := v.getPublisher(ctx, pubTxid)
+	if err != nil {
+		return nil, err
+	}
+	s := &Statement{Template: t.ID, Text: t.Render(pub.Name, pubTxid)}
+	name, _, err := v.matchVerification(ctx, s.Text)
+	if err != nil || v.normalizeName(name) != v.normalizeName(pub.Name) {
+		s.Warning = "Publisher name has characters the verification statement can't carry, so it won't verify"
+	}
+	return s, nil
+}
+
+// match returns the name and txid captured from text, if it matches.
+func (t *Template) match(text string) (name, txid string, ok bool) {
+	m := t.re.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", false
+	}
+	return m[t.re.SubexpIndex("name")], m[t.re.SubexpIndex("txid")], true
+}
+
+// LoadTemplates reads a JSON array of templates from path, e.g.
+// [{"id": "oip-v2", "pattern": "..."}], checking that every pattern compiles
+// with both groups and that the ids are unique.
+func LoadTemplates(path string) ([]*Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var templates []*Template
+	err = json.Unmarshal(b, &templates)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%s: no templates", path)
+	}
+	ids := make(map[string]bool)
+	for _, t := range templates {
+		err = t.compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("%s: duplicate template id %s", path, t.ID)
+		}
+		ids[t.ID] = true
+	}
+	return templates, nil
+}
+
+// templateMatch records the id of the template a post matched and the text
+// it was matched against, so they can be reported without every platform
+// returning them.
+type templateMatch struct {
+	mu   sync.Mutex
+	id   string
+	text string
+}
+
+func withTemplateMatch(ctx context.Context, m *templateMatch) context.Context {
+	return context.WithValue(ctx, templateKey, m)
+}
+
+// matchVerification extracts the publisher name and txid from a verification
+// statement in text using the first of Templates it matches, returning
+// ErrBadFormat when there is none.
+func (v *Verifier) matchVerification(ctx context.Context, text string) (name, txid string, err error) {
+	text = normalizeText(text)
+	m, _ := ctx.Value(templateKey).(*templateMatch)
+	if m != nil {
+		m.mu.Lock()
+		m.text = text
+		m.mu.Unlock()
+	}
+	for _, t := range v.settingsFor(ctx).Templates {
+		if name, txid, ok := t.match(text); ok {
+			if m != nil {
+				m.mu.Lock()
+				m.id = t.ID
+				m.mu.Unlock()
+			}
+			return name, txid, nil
+		}
+	}
+	return "", "", ErrBadFormat
+}
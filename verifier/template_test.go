@@ -0,0 +1,53 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMatchVerificationTricky(t *testing.T) {
+	tests := []struct {
+		what, text, name string
+	}{
+		{"straight quotes", `@OpenIndexProtocol verifying "Example Publisher" is publishing as: ` + "\n" + testPubTxid, "Example Publisher"},
+		{"curly double quotes", "@OpenIndexProtocol verifying \u201cExample Publisher\u201d is publishing as: \n" + testPubTxid, "Example Publisher"},
+		{"low double quote", "@OpenIndexProtocol verifying \u201eExample Publisher\u201d is publishing as: \n" + testPubTxid, "Example Publisher"},
+		{"curly single quotes", "@OpenIndexProtocol verifying \u2018Example Publisher\u2019 is publishing as: \n" + testPubTxid, "Example Publisher"},
+		{"apostrophe in name", "@OpenIndexProtocol verifying \u201cO\u2019Brien\u201d is publishing as: \n" + testPubTxid, "O'Brien"},
+		{"combining accent", "@OpenIndexProtocol verifying \"Cafe\u0301\" is publishing as: \n" + testPubTxid, "Caf\u00e9"},
+		{"precomposed accent", "@OpenIndexProtocol verifying \"Caf\u00e9\" is publishing as: \n" + testPubTxid, "Caf\u00e9"},
+		{"no-break spaces", "@OpenIndexProtocol\u00a0verifying\u00a0\"Example Publisher\"\u00a0is\u00a0publishing\u00a0as:\u00a0" + testPubTxid, "Example Publisher"},
+		{"ideographic spaces", "@OpenIndexProtocol\u3000verifying\u3000\"Example Publisher\"\u3000is\u3000publishing\u3000as:\u3000" + testPubTxid, "Example Publisher"},
+		{"several newlines", `@OpenIndexProtocol verifying "Example Publisher" is publishing as:` + "\n\n\n" + testPubTxid, "Example Publisher"},
+		{"CRLF", `@OpenIndexProtocol verifying "Example Publisher" is publishing as:` + "\r\n\r\n" + testPubTxid, "Example Publisher"},
+		{"spaces and newlines", `@OpenIndexProtocol verifying "Example Publisher" is publishing as: ` + "\n \u00a0\n" + testPubTxid, "Example Publisher"},
+		{"old handle", `@OpenIndexProto verifying "Example Publisher" is publishing as: ` + testPubTxid, "Example Publisher"},
+		{"surrounding text", "gm \U0001F305\n@OpenIndexProtocol verifying \u201cExample Publisher\u201d is publishing as: \n" + testPubTxid + " https://t.co/abc", "Example Publisher"},
+	}
+	v := New(nil, testOipApi, nil)
+	for _, tt := range tests {
+		name, txid, err := v.matchVerification(context.Background(), tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.what, err)
+			continue
+		}
+		if name != tt.name || txid != testPubTxid {
+			t.Errorf("%s: got %q, %s, want %q, %s", tt.what, name, txid, tt.name, testPubTxid)
+		}
+	}
+
+	bad := []struct {
+		what, text string
+	}{
+		{"no quotes", `@OpenIndexProtocol verifying Example Publisher is publishing as: ` + testPubTxid},
+		{"newline between words", "@OpenIndexProtocol verifying \"Example Publisher\"\nis publishing as: " + testPubTxid},
+		{"short txid", `@OpenIndexProtocol verifying "Example Publisher" is publishing as: ` + testPubTxid[:63]},
+		{"uppercase txid", `@OpenIndexProtocol verifying "Example Publisher" is publishing as: 4F03` + testPubTxid[4:]},
+		{"zero-width space", "@OpenIndexProtocol\u200bverifying \"Example Publisher\" is publishing as: " + testPubTxid},
+	}
+	for _, tt := range bad {
+		if _, _, err := v.matchVerification(context.Background(), tt.text); err != ErrBadFormat {
+			t.Errorf("%s: got %v, want ErrBadFormat", tt.what, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package verifier
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	htmlBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+	htmlTagRegex   = regexp.MustCompile(`<[^>]*>`)
+	jsonLDRegex    = regexp.MustCompile(`(?s)<script[^>]+type="application/ld\+json"[^>]*>(.*?)</script>`)
+)
+
+// htmlToText reduces the HTML of a post body to its text, turning line and
+// paragraph breaks into newlines.
+func htmlToText(s string) string {
+	s = htmlBreakRegex.ReplaceAllString(s, "\n")
+	s = htmlTagRegex.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
+
+// typographicQuotes maps the curly quotes phone keyboards substitute to their
+// ASCII equivalents.
+var typographicQuotes = strings.NewReplacer(
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`,
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'",
+)
+
+// normalizeText puts fetched post text in NFC and replaces typographic
+// quotes so that it matches the templates however it was typed.
+func normalizeText(s string) string {
+	return typographicQuotes.Replace(norm.NFC.String(s))
+}
+
+// normalizeName folds the differences between a publisher's name in its
+// record and in a verification statement that don't make it a different
+// name: Unicode form, quote style, case, and runs of whitespace. Width and
+// other compatibility variants are folded too with NameNFKC.
+func (v *Verifier) normalizeName(s string) string {
+	s = normalizeText(s)
+	if v.NameNFKC {
+		s = norm.NFKC.String(s)
+	}
+	return cases.Fold().String(strings.Join(strings.Fields(s), " "))
+}
+
+// elementText returns the text of the first element whose class attribute
+// includes class, tolerating nested elements of the same tag. It is not a
+// full HTML parser, just enough for well-formed embed pages.
+func elementText(page, class string) (string, bool) {
+	classAt := regexp.MustCompile(`<([a-z]+)[^>]*class="[^"]*\b` + regexp.QuoteMeta(class) + `\b[^"]*"[^>]*>`).FindStringSubmatchIndex(page)
+	if classAt == nil {
+		return "", false
+	}
+	tag := page[classAt[2]:classAt[3]]
+	start := classAt[1]
+	open, close := "<"+tag, "</"+tag+">"
+
+	depth := 1
+	for i := start; i < len(page); {
+		next := strings.Index(page[i:], "<")
+		if next < 0 {
+			break
+		}
+		i += next
+		switch {
+		case strings.HasPrefix(page[i:], close):
+			depth--
+			if depth == 0 {
+				return htmlToText(page[start:i]), true
+			}
+			i += len(close)
+		case strings.HasPrefix(page[i:], open) && len(page) > i+len(open) && (page[i+len(open)] == ' ' || page[i+len(open)] == '>'):
+			depth++
+			i += len(open)
+		default:
+			i++
+		}
+	}
+	return "", false
+}
+
+// jsonLD returns the contents of the page's JSON-LD scripts.
+func jsonLD(page string) []string {
+	var scripts []string
+	for _, m := range jsonLDRegex.FindAllStringSubmatch(page, -1) {
+		scripts = append(scripts, m[1])
+	}
+	return scripts
+}
+
+// findObject returns the first JSON object nested anywhere in v, depth first,
+// for which match returns true. The members of objects are searched in order
+// of their keys, so that the same object is found every time when several
+// match.
+func findObject(v interface{}, match func(map[string]interface{}) bool) map[string]interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if match(v) {
+			return v
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if o := findObject(v[k], match); o != nil {
+				return o
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if o := findObject(e, match); o != nil {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// jsonString returns the string at the path of keys in the JSON object o, or
+// "" if there is none.
+func jsonString(o map[string]interface{}, path ...string) string {
+	for i, k := range path {
+		if i == len(path)-1 {
+			s, _ := o[k].(string)
+			return s
+		}
+		o, _ = o[k].(map[string]interface{})
+	}
+	return ""
+}
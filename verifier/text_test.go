@@ -0,0 +1,63 @@
+package verifier
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFindObjectDeterministic(t *testing.T) {
+	var v interface{}
+	err := json.Unmarshal([]byte(`{
+		"z": {"@type": "VideoObject", "name": "z"},
+		"b": [{"other": {"@type": "VideoObject", "name": "b"}}],
+		"m": {"@type": "VideoObject", "name": "m"},
+		"a": {"nested": {"deeper": {"@type": "VideoObject", "name": "a"}}}
+	}`), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isVideo := func(o map[string]interface{}) bool { return o["@type"] == "VideoObject" }
+	for i := 0; i < 50; i++ {
+		o := findObject(v, isVideo)
+		if o == nil || o["name"] != "a" {
+			t.Fatalf("got %v, want the object under the first key", o)
+		}
+	}
+	if o := findObject(v, func(map[string]interface{}) bool { return false }); o != nil {
+		t.Errorf("got %v for no match", o)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		same, nfkc bool
+	}{
+		{"Example Publisher", "Example Publisher", true, true},
+		{"Example Publisher", "example PUBLISHER", true, true},
+		{"Example Publisher", "  Example   Publisher ", true, true},
+		{"Example Publisher", "Example\u00a0Publisher", true, true},
+		{"Example Publisher", "Example\tPublisher", true, true},
+		{"Caf\u00e9", "Cafe\u0301", true, true},
+		{"Stra\u00dfe", "STRASSE", true, true},
+		{"O'Brien", "O\u2019Brien", true, true},
+		{"Example Publisher", "\uff25\uff58\uff41\uff4d\uff50\uff4c\uff45 Publisher", false, true},
+		{"Example Publisher", "Example\u3000Publisher", true, true},
+		{"\ufb01sh", "fish", true, true},
+		{"Example Publisher", "Example Publishers", false, false},
+		{"Example Publisher", "ExamplePublisher", false, false},
+	}
+	v := New(nil, testOipApi, nil)
+	for _, tt := range tests {
+		for _, nfkc := range []bool{false, true} {
+			v.NameNFKC = nfkc
+			want := tt.same
+			if nfkc {
+				want = tt.nfkc
+			}
+			if got := v.normalizeName(tt.a) == v.normalizeName(tt.b); got != want {
+				t.Errorf("NameNFKC %v: %q and %q same = %v, want %v", nfkc, tt.a, tt.b, got, want)
+			}
+		}
+	}
+}
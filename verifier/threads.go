@@ -0,0 +1,89 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	threadsURLRegex    = regexp.MustCompile(`^https://(?:www\.)?threads\.(?:net|com)/@([A-Za-z0-9._]{1,30})/post/([A-Za-z0-9_-]{5,20})/?$`)
+	threadsScriptRegex = regexp.MustCompile(`(?s)<script type="application/json"[^>]*data-sjs[^>]*>(.*?)</script>`)
+)
+
+var errThreadsDenied = errors.New("threads denied the request")
+
+// VerifyThreads fetches the public Threads post at postURL and returns the
+// publisher name and txid from its verification statement along with its
+// author's username. The post is read from the JSON the page ships for the
+// web client.
+func (v *Verifier) VerifyThreads(ctx context.Context, postURL string) (name string, txid string, username string, err error) {
+	m := threadsURLRegex.FindStringSubmatch(postURL)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+	code := m[2]
+
+	client := v.guardedClient(func(u *url.URL) bool {
+		return strings.HasPrefix(u.Path, "/login") || strings.Contains(u.Host, "instagram.com")
+	}, errThreadsDenied)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.threads.net/@"+m[1]+"/post/"+code, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	body, err := v.doRequestWith(client, "threads", req, v.MaxBodySize)
+	switch {
+	case isStatus(err, http.StatusNotFound):
+		return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Threads post " + postURL + " has been deleted or does not exist"}
+	case errors.Is(err, errThreadsDenied) || isStatus(err, http.StatusUnauthorized) || isStatus(err, http.StatusForbidden) || isStatus(err, http.StatusTooManyRequests):
+		return "", "", "", &PlatformError{Code: CodeBlocked, Msg: "Threads denied the request for post " + postURL}
+	case err != nil:
+		return "", "", "", err
+	}
+
+	var post map[string]interface{}
+	for _, sm := range threadsScriptRegex.FindAllSubmatch(body, -1) {
+		var payload interface{}
+		if json.Unmarshal(sm[1], &payload) != nil {
+			continue
+		}
+		post = findObject(payload, func(o map[string]interface{}) bool {
+			c, _ := o["code"].(string)
+			_, hasCaption := o["caption"]
+			return c == code && hasCaption
+		})
+		if post != nil {
+			break
+		}
+	}
+	if post == nil {
+		// the page renders without the post for clients Threads won't serve
+		return "", "", "", &PlatformError{Code: CodeBlocked, Msg: "Threads denied the request for post " + postURL}
+	}
+
+	username = jsonString(post, "user", "username")
+	name, txid, err = v.matchVerification(ctx, jsonString(post, "caption", "text"))
+	return name, txid, username, err
+}
+
+type threadsPlatform struct {
+	v *Verifier
+}
+
+func (t threadsPlatform) Name() string {
+	return "threads"
+}
+
+func (t threadsPlatform) Verify(ctx context.Context, postURL string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyThreads(ctx, postURL)
+	return name, txid, err
+}
+
+func (t threadsPlatform) VerifyAuthor(ctx context.Context, postURL string) (string, string, string, error) {
+	return t.v.VerifyThreads(ctx, postURL)
+}
@@ -0,0 +1,153 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tiktokURLRegex    = regexp.MustCompile(`^https://(?:www\.)?tiktok\.com/@([A-Za-z0-9._]{2,24})/video/([0-9]{10,25})/?(?:\?.*)?$`)
+	tiktokScriptRegex = regexp.MustCompile(`(?s)<script id="(__UNIVERSAL_DATA_FOR_REHYDRATION__|SIGI_STATE)" type="application/json">(.*?)</script>`)
+)
+
+// tiktokItem is a video in either the current rehydration data or the older
+// SIGI_STATE.
+type tiktokItem struct {
+	ID     string          `json:"id"`
+	Desc   string          `json:"desc"`
+	Author json.RawMessage `json:"author"`
+}
+
+// tiktok status codes reported for videos that can't be shown
+const (
+	tiktokItemNotFound     = 10204
+	tiktokItemPrivate      = 10222
+	tiktokItemRegionLocked = 10216
+)
+
+var errTiktokBlocked = errors.New("tiktok refused to serve the video page")
+
+// VerifyTikTok fetches the page of the video at videoURL and returns the
+// publisher name and txid from the verification statement in its description
+// along with its author's uniqueId.
+func (v *Verifier) VerifyTikTok(ctx context.Context, videoURL string) (name string, txid string, uniqueID string, err error) {
+	m := tiktokURLRegex.FindStringSubmatch(videoURL)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+	id := m[2]
+
+	client := v.guardedClient(func(u *url.URL) bool {
+		return strings.HasPrefix(u.Path, "/login")
+	}, errTiktokBlocked)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.tiktok.com/@"+m[1]+"/video/"+id, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	req.Header.Set("Accept", "text/html")
+	body, err := v.doRequestWith(client, "tiktok", req, v.MaxBodySize)
+	switch {
+	case isStatus(err, http.StatusNotFound):
+		return "", "", "", tiktokRemoved(videoURL)
+	case errors.Is(err, errTiktokBlocked) || isStatus(err, http.StatusForbidden) || isStatus(err, http.StatusTooManyRequests):
+		return "", "", "", &PlatformError{Code: CodeBlocked, Msg: "TikTok blocked the request for video " + videoURL}
+	case err != nil:
+		return "", "", "", err
+	}
+
+	sm := tiktokScriptRegex.FindSubmatch(body)
+	if sm == nil {
+		return "", "", "", &PlatformError{Code: CodeBlocked, Msg: "TikTok blocked the request for video " + videoURL}
+	}
+	item, status, err := tiktokVideo(string(sm[1]), sm[2], id)
+	if err != nil {
+		return "", "", "", &DecodeError{URL: "tiktok video " + id, Err: err}
+	}
+	switch status {
+	case 0:
+	case tiktokItemRegionLocked:
+		return "", "", "", &PlatformError{Code: CodeRegionBlocked, Msg: "TikTok video " + videoURL + " is not available in this server's region"}
+	case tiktokItemPrivate:
+		return "", "", "", &PlatformError{Code: CodeNotPublic, Msg: "TikTok video " + videoURL + " is private"}
+	default:
+		return "", "", "", tiktokRemoved(videoURL)
+	}
+	if item == nil {
+		return "", "", "", tiktokRemoved(videoURL)
+	}
+
+	// the author is an object in the rehydration data and the uniqueId
+	// itself in SIGI_STATE
+	var author struct {
+		UniqueID string `json:"uniqueId"`
+	}
+	if json.Unmarshal(item.Author, &author) != nil {
+		_ = json.Unmarshal(item.Author, &author.UniqueID)
+	}
+
+	name, txid, err = v.matchVerification(ctx, item.Desc)
+	return name, txid, author.UniqueID, err
+}
+
+// tiktokVideo finds video id and the status TikTok reports for it in the
+// page's embedded data of the given kind.
+func tiktokVideo(kind string, data []byte, id string) (*tiktokItem, int, error) {
+	if kind == "SIGI_STATE" {
+		var state struct {
+			ItemModule map[string]*tiktokItem `json:"ItemModule"`
+		}
+		err := json.Unmarshal(data, &state)
+		if err != nil {
+			return nil, 0, err
+		}
+		return state.ItemModule[id], 0, nil
+	}
+
+	var state struct {
+		DefaultScope struct {
+			VideoDetail struct {
+				StatusCode json.Number `json:"statusCode"`
+				ItemInfo   struct {
+					ItemStruct *tiktokItem `json:"itemStruct"`
+				} `json:"itemInfo"`
+			} `json:"webapp.video-detail"`
+		} `json:"__DEFAULT_SCOPE__"`
+	}
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, 0, err
+	}
+	detail := state.DefaultScope.VideoDetail
+	status, _ := strconv.Atoi(detail.StatusCode.String())
+	if item := detail.ItemInfo.ItemStruct; item != nil && item.ID != id {
+		return nil, tiktokItemNotFound, nil
+	}
+	return detail.ItemInfo.ItemStruct, status, nil
+}
+
+func tiktokRemoved(videoURL string) error {
+	return &PlatformError{Code: CodePostRemoved, Msg: "TikTok video " + videoURL + " has been removed or does not exist"}
+}
+
+type tiktokPlatform struct {
+	v *Verifier
+}
+
+func (t tiktokPlatform) Name() string {
+	return "tiktok"
+}
+
+func (t tiktokPlatform) Verify(ctx context.Context, videoURL string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTikTok(ctx, videoURL)
+	return name, txid, err
+}
+
+func (t tiktokPlatform) VerifyAuthor(ctx context.Context, videoURL string) (string, string, string, error) {
+	return t.v.VerifyTikTok(ctx, videoURL)
+}
@@ -0,0 +1,37 @@
+package verifier
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of checks with the global tracer provider, which
+// doesn't record anything unless the embedding program installs one.
+var tracer = otel.Tracer("github.com/oipwg/verifier/verifier")
+
+// startSpan starts a span called name as a child of the span of ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it failed with err if that is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// platformSpanName names the span of a check on platform, e.g. getTwitter.
+func platformSpanName(platform string) string {
+	if platform == "" {
+		return "getPost"
+	}
+	return "get" + strings.ToUpper(platform[:1]) + platform[1:]
+}
@@ -0,0 +1,41 @@
+package verifier
+
+import (
+	"context"
+	"regexp"
+)
+
+var truthSocialIDRegex = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// VerifyTruthSocial fetches the Truth Social status id from its Mastodon
+// compatible api and returns the publisher name and txid from its
+// verification statement along with its author's username.
+func (v *Verifier) VerifyTruthSocial(ctx context.Context, id string) (name string, txid string, username string, err error) {
+	if !truthSocialIDRegex.MatchString(id) {
+		return "", "", "", ErrInvalidID
+	}
+
+	status, err := v.mastodonStatus(ctx, "truth", "truthsocial.com", id)
+	if err != nil {
+		return "", "", "", err
+	}
+	name, txid, err = v.matchVerification(ctx, htmlToText(status.Content))
+	return name, txid, status.Account.Username, err
+}
+
+type truthSocialPlatform struct {
+	v *Verifier
+}
+
+func (t truthSocialPlatform) Name() string {
+	return "truth"
+}
+
+func (t truthSocialPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTruthSocial(ctx, id)
+	return name, txid, err
+}
+
+func (t truthSocialPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return t.v.VerifyTruthSocial(ctx, id)
+}
@@ -0,0 +1,144 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// tumblrPostV2 is a post in the legacy format, whose text is in one of
+// several fields depending on the post type.
+type tumblrPostV2 struct {
+	BlogName string `json:"blog_name"`
+	Body     string `json:"body"`
+	Caption  string `json:"caption"`
+	Text     string `json:"text"`
+	Summary  string `json:"summary"`
+}
+
+type tumblrPostV1 struct {
+	RegularBody  string `json:"regular-body"`
+	PhotoCaption string `json:"photo-caption"`
+	QuoteText    string `json:"quote-text"`
+	LinkText     string `json:"link-description"`
+}
+
+var tumblrPostRegex = regexp.MustCompile(`^([A-Za-z0-9-]{1,32})/([0-9]{1,20})$`)
+
+// VerifyTumblr fetches the post blogname/postId with the Tumblr api, or the
+// public v1 api when no TumblrAPIKey is configured, and returns the publisher
+// name and txid from its verification statement along with the blog name.
+// Like every post its result is cached, which keeps within Tumblr's small
+// api quota.
+func (v *Verifier) VerifyTumblr(ctx context.Context, post string) (name string, txid string, blog string, err error) {
+	m := tumblrPostRegex.FindStringSubmatch(post)
+	if m == nil {
+		return "", "", "", ErrInvalidID
+	}
+	blog = strings.ToLower(m[1])
+
+	var text string
+	if v.TumblrAPIKey != "" {
+		text, err = v.tumblrV2(ctx, blog, m[2])
+	} else {
+		text, err = v.tumblrV1(ctx, blog, m[2])
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errTumblrSensitive):
+			return "", "", blog, &PlatformError{Code: CodeNotPublic, Msg: "Tumblr post " + post + " is marked sensitive and can't be viewed without logging in"}
+		case isStatus(err, http.StatusNotFound):
+			return "", "", blog, &PlatformError{Code: CodePostNotFound, Msg: "Tumblr post " + post + " has been deleted or does not exist"}
+		}
+		return "", "", blog, err
+	}
+
+	name, txid, err = v.matchVerification(ctx, htmlToText(text))
+	return name, txid, blog, err
+}
+
+var errTumblrSensitive = errors.New("tumblr post is behind the sensitive content gate")
+
+func (v *Verifier) tumblrV2(ctx context.Context, blog, id string) (string, error) {
+	q := url.Values{"id": {id}, "api_key": {v.TumblrAPIKey}}
+	body, err := v.httpGet(ctx, "tumblr", "https://api.tumblr.com/v2/blog/"+blog+".tumblr.com/posts?"+q.Encode())
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Response struct {
+			Blog struct {
+				IsNSFW bool `json:"is_nsfw"`
+			} `json:"blog"`
+			Posts []tumblrPostV2 `json:"posts"`
+		} `json:"response"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return "", &DecodeError{URL: "tumblr post " + blog + "/" + id, Err: err}
+	}
+	if len(res.Response.Posts) == 0 {
+		if res.Response.Blog.IsNSFW {
+			return "", errTumblrSensitive
+		}
+		return "", &StatusError{URL: "tumblr post " + blog + "/" + id, StatusCode: http.StatusNotFound}
+	}
+	p := res.Response.Posts[0]
+	return strings.Join([]string{p.Body, p.Caption, p.Text, p.Summary}, "\n"), nil
+}
+
+// tumblrV1 reads the post from the blog's public v1 api, which serves it as
+// javascript assigning an object to tumblr_api_read. Sensitive posts redirect
+// to the safe mode page instead.
+func (v *Verifier) tumblrV1(ctx context.Context, blog, id string) (string, error) {
+	client := v.guardedClient(func(u *url.URL) bool {
+		return strings.Contains(u.Path, "safe-mode") || strings.HasPrefix(u.Path, "/login")
+	}, errTumblrSensitive)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+blog+".tumblr.com/api/read/json?"+url.Values{"id": {id}}.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	body, err := v.doRequestWith(client, "tumblr", req, v.MaxBodySize)
+	if err != nil {
+		return "", err
+	}
+
+	start, end := bytes.IndexByte(body, '{'), bytes.LastIndexByte(body, '}')
+	if start < 0 || end < start {
+		return "", &DecodeError{URL: "tumblr post " + blog + "/" + id, Err: errors.New("missing tumblr_api_read object")}
+	}
+	var res struct {
+		Posts []tumblrPostV1 `json:"posts"`
+	}
+	err = json.Unmarshal(body[start:end+1], &res)
+	if err != nil {
+		return "", &DecodeError{URL: "tumblr post " + blog + "/" + id, Err: err}
+	}
+	if len(res.Posts) == 0 {
+		return "", &StatusError{URL: "tumblr post " + blog + "/" + id, StatusCode: http.StatusNotFound}
+	}
+	p := res.Posts[0]
+	return strings.Join([]string{p.RegularBody, p.PhotoCaption, p.QuoteText, p.LinkText}, "\n"), nil
+}
+
+type tumblrPlatform struct {
+	v *Verifier
+}
+
+func (t tumblrPlatform) Name() string {
+	return "tumblr"
+}
+
+func (t tumblrPlatform) Verify(ctx context.Context, post string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTumblr(ctx, post)
+	return name, txid, err
+}
+
+func (t tumblrPlatform) VerifyAuthor(ctx context.Context, post string) (string, string, string, error) {
+	return t.v.VerifyTumblr(ctx, post)
+}
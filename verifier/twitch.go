@@ -0,0 +1,162 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type twitchUsers struct {
+	Data []struct {
+		Login       string `json:"login"`
+		DisplayName string `json:"display_name"`
+		Description string `json:"description"`
+	} `json:"data"`
+}
+
+// twitchToken holds the app access token obtained with TwitchClientID and
+// TwitchClientSecret, until shortly before it expires.
+type twitchToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var twitchLoginRegex = regexp.MustCompile(`^[a-z0-9_]{3,25}$`)
+
+// VerifyTwitch fetches the Twitch user login with the Helix api and returns
+// the publisher name and txid from the verification statement in their
+// channel description along with their display name.
+func (v *Verifier) VerifyTwitch(ctx context.Context, login string) (name string, txid string, displayName string, err error) {
+	login = strings.ToLower(login)
+	if !twitchLoginRegex.MatchString(login) {
+		return "", "", "", ErrInvalidID
+	}
+	if v.TwitchClientID == "" || v.TwitchClientSecret == "" {
+		return "", "", "", &PlatformError{Code: CodeNotConfigured, Msg: "Twitch verification is not configured on this server"}
+	}
+
+	users := &twitchUsers{}
+	err = v.twitchGet(ctx, "/helix/users?"+url.Values{"login": {login}}.Encode(), users)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(users.Data) == 0 {
+		return "", "", "", &PlatformError{Code: CodeHandleNotFound, Msg: "Twitch user " + login + " does not exist"}
+	}
+	user := users.Data[0]
+
+	name, txid, err = v.matchVerification(ctx, user.Description)
+	return name, txid, user.DisplayName, err
+}
+
+// twitchGet fetches path from the Helix api into out. A rejected token is
+// replaced and the request tried once more.
+func (v *Verifier) twitchGet(ctx context.Context, path string, out interface{}) error {
+	for retried := false; ; retried = true {
+		token, err := v.twitchAppToken(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.twitch.tv"+path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Client-Id", v.TwitchClientID)
+		req.Header.Set("Authorization", "Bearer "+token)
+		body, err := v.doRequest("twitch", req, v.MaxBodySize)
+		if isStatus(err, http.StatusUnauthorized) {
+			v.twitchToken.mu.Lock()
+			if v.twitchToken.token == token {
+				v.twitchToken.token = ""
+			}
+			v.twitchToken.mu.Unlock()
+			if !retried {
+				continue
+			}
+			return &PlatformError{Code: CodeTokenExpired, Msg: "Twitch rejected this server's app access token"}
+		}
+		var se *StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests {
+			rl := &RateLimitError{Target: "twitch", Reset: time.Now().Add(time.Minute)}
+			if reset, err := strconv.ParseInt(se.Header.Get("Ratelimit-Reset"), 10, 64); err == nil {
+				rl.Reset = time.Unix(reset, 0)
+			}
+			return rl
+		}
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(body, out)
+		if err != nil {
+			return &DecodeError{URL: "twitch " + path, Err: err}
+		}
+		return nil
+	}
+}
+
+// twitchAppToken returns the cached app access token, getting a new one with
+// the client credentials flow when there is none or it is about to expire.
+func (v *Verifier) twitchAppToken(ctx context.Context) (string, error) {
+	v.twitchToken.mu.Lock()
+	defer v.twitchToken.mu.Unlock()
+	if v.twitchToken.token != "" && time.Now().Before(v.twitchToken.expires) {
+		return v.twitchToken.token, nil
+	}
+
+	form := url.Values{"client_id": {v.TwitchClientID}, "client_secret": {v.TwitchClientSecret}, "grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://id.twitch.tv/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := v.doRequest("twitch", req, v.MaxBodySize)
+	if err != nil {
+		if isStatus(err, http.StatusBadRequest) || isStatus(err, http.StatusForbidden) {
+			return "", &PlatformError{Code: CodeNotConfigured, Msg: "Twitch rejected this server's client credentials"}
+		}
+		return "", err
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err == nil && res.AccessToken == "" {
+		err = errors.New("missing access_token")
+	}
+	if err != nil {
+		return "", &DecodeError{URL: "twitch app access token", Err: err}
+	}
+
+	// renew a minute early so requests in flight don't see it expire
+	v.twitchToken.token = res.AccessToken
+	v.twitchToken.expires = time.Now().Add(time.Duration(res.ExpiresIn)*time.Second - time.Minute)
+	return res.AccessToken, nil
+}
+
+type twitchPlatform struct {
+	v *Verifier
+}
+
+func (t twitchPlatform) Name() string {
+	return "twitch"
+}
+
+func (t twitchPlatform) Verify(ctx context.Context, login string) (string, string, error) {
+	name, txid, _, err := t.v.VerifyTwitch(ctx, login)
+	return name, txid, err
+}
+
+func (t twitchPlatform) VerifyAuthor(ctx context.Context, login string) (string, string, string, error) {
+	return t.v.VerifyTwitch(ctx, login)
+}
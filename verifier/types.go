@@ -0,0 +1,456 @@
+package verifier
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type elasticOip5Record struct {
+	Record record `json:"record"`
+	Meta   RMeta  `json:"meta"`
+}
+
+type RMeta struct {
+	Deactivated bool   `json:"deactivated"`
+	SignedBy    string `json:"signed_by"`
+	Time        int64  `json:"time"`
+	Txid        string `json:"txid"`
+}
+
+type oipApiResult struct {
+	Count   int
+	Total   int
+	Results []elasticOip5Record
+	After   string
+}
+
+type record struct {
+	Details details `json:"details"`
+}
+
+type details struct {
+	Publisher         Publisher         `json:"tmpl_433C2783"`
+	VerificationClaim VerificationClaim `json:"tmpl_F471DFF9"`
+}
+
+type tmpl433C2783 struct {
+	Name           string `json:"name"`
+	FloBip44XPub   string `json:"floBip44XPub"`
+	PgpFingerprint string `json:"pgpFingerprint"`
+	PgpKeyUrl      string `json:"pgpKeyUrl"`
+	Domain         string `json:"domain"`
+}
+
+type tmplF471DFF9 struct {
+	GabId              string `json:"gabId"`
+	TwitterId          string `json:"twitterId"`
+	TwitterHandle      string `json:"twitterHandle"`
+	MastodonUrl        string `json:"mastodonUrl"`
+	BlueskyUri         string `json:"blueskyUri"`
+	NostrEventId       string `json:"nostrEventId"`
+	RedditId           string `json:"redditId"`
+	GistId             string `json:"gistId"`
+	YoutubeVideoId     string `json:"youtubeVideoId"`
+	TelegramPost       string `json:"telegramPost"`
+	SignedProofUrl     string `json:"signedProofUrl"`
+	MatrixEvent        string `json:"matrixEvent"`
+	FarcasterCast      string `json:"farcasterCast"`
+	HivePermlink       string `json:"hivePermlink"`
+	OdyseeUrl          string `json:"odyseeUrl"`
+	InstagramShortcode string `json:"instagramShortcode"`
+	TwitchLogin        string `json:"twitchLogin"`
+	TumblrPost         string `json:"tumblrPost"`
+	SubstackUrl        string `json:"substackUrl"`
+	MediumUrl          string `json:"mediumUrl"`
+	LinkedinUrl        string `json:"linkedinUrl"`
+	FacebookPostId     string `json:"facebookPostId"`
+	ThreadsPostUrl     string `json:"threadsPostUrl"`
+	TiktokVideoUrl     string `json:"tiktokVideoUrl"`
+	RumbleUrl          string `json:"rumbleUrl"`
+	// RumbleChannel, when set, is the channel the Rumble video must be
+	// from, since its URL doesn't say.
+	RumbleChannel string `json:"rumbleChannel"`
+	SoundcloudUrl string `json:"soundcloudUrl"`
+	VimeoVideoId  string `json:"vimeoVideoId"`
+	// GitLabSnippetId is a snippet id on the configured GitLab instance or
+	// a snippet URL on any instance.
+	GitLabSnippetId   string `json:"gitlabSnippetId"`
+	GitLabHandle      string `json:"gitlabHandle"`
+	MindsGuid         string `json:"mindsGuid"`
+	DiscordMessage    string `json:"discordMessage"`
+	ProofUrl          string `json:"proofUrl"`
+	EmailProofUrl     string `json:"emailProofUrl"`
+	GettrPostId       string `json:"gettrPostId"`
+	TruthSocialPostId string `json:"truthSocialPostId"`
+	GitHubHandle      string `json:"githubHandle"`
+	DnsDomain         string `json:"dnsDomain"`
+	WebsiteUrl        string `json:"websiteUrl"`
+	// RegisteredPublisher, when set, is the txid of the publisher the claim
+	// is for. Proofs pointing at any other publisher are rejected.
+	RegisteredPublisher string `json:"registeredPublisher"`
+}
+
+type VerificationClaim struct {
+	tmplF471DFF9
+}
+
+type Publisher struct {
+	tmpl433C2783
+}
+
+// tcoSuffixRegex matches the shortened links Twitter appends to tweets with
+// media or quotes.
+var tcoSuffixRegex = regexp.MustCompile(`(?:\s*https://t\.co/[0-9A-Za-z]+)+\s*$`)
+
+var txidRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Result is the outcome of checking a claim, and the /verified/v1 schema.
+type Result struct {
+	Code string `json:"code"`
+	// Verified is the overall result under Policy.
+	Verified bool   `json:"verified"`
+	Policy   string `json:"policy,omitempty"`
+	// Adhoc is set when posts were checked without a claim record.
+	Adhoc            bool       `json:"adhoc,omitempty"`
+	Msg              string     `json:"msg,omitempty"`
+	CrossPlatformMsg string     `json:"cross_platform_msg,omitempty"`
+	Claim            *ClaimMeta `json:"claim,omitempty"`
+	// Publisher is the publisher the claim's posts point at, when they
+	// agree and its record was found.
+	Publisher *PublisherMeta             `json:"publisher,omitempty"`
+	Platforms map[string]*PlatformStatus `json:"platforms,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying a
+	// RATE_LIMITED response.
+	RetryAfter int `json:"retry_after,omitempty"`
+
+	// legacyFields is set once the claim was found, when VerificationResponse
+	// has its twitter and gab fields filled.
+	legacyFields bool
+}
+
+// VerificationResponse is the response of the unversioned endpoints, which
+// must not change. Build it from a Result with Legacy.
+type VerificationResponse struct {
+	Code             string                     `json:"code"`
+	Verified         bool                       `json:"verified"`
+	Policy           string                     `json:"policy,omitempty"`
+	Adhoc            bool                       `json:"adhoc,omitempty"`
+	Twitter          bool                       `json:"twitter"`
+	TwitterCode      string                     `json:"twitter_code,omitempty"`
+	TwitterMsg       string                     `json:"twitter_msg,omitempty"`
+	TwitterHandle    string                     `json:"twitter_handle,omitempty"`
+	Gab              bool                       `json:"gab"`
+	GabCode          string                     `json:"gab_code,omitempty"`
+	GabMsg           string                     `json:"gab_msg,omitempty"`
+	CrossPlatformMsg string                     `json:"cross_platform_msg,omitempty"`
+	Msg              string                     `json:"msg,omitempty"`
+	Claim            *ClaimMeta                 `json:"claim,omitempty"`
+	Publisher        *PublisherMeta             `json:"publisher,omitempty"`
+	Platforms        map[string]*PlatformStatus `json:"platforms,omitempty"`
+	RetryAfter       int                        `json:"retry_after,omitempty"`
+}
+
+// PlatformStatus is the result of verifying a claim's post on one platform.
+type PlatformStatus struct {
+	Verified bool   `json:"verified"`
+	Code     string `json:"code"`
+	Msg      string `json:"msg,omitempty"`
+	// Post is the post's id or URL as given in the claim.
+	Post   string `json:"post,omitempty"`
+	Author string `json:"author,omitempty"`
+	// Source is the server the proof was fetched from, when the platform
+	// has several, e.g. the nameserver that answered a DNS lookup.
+	Source string `json:"source,omitempty"`
+	// Details are other platform specific facts, e.g. a channel id.
+	Details map[string]string `json:"details,omitempty"`
+	// Template is the id of the statement template the post matched.
+	Template string `json:"template,omitempty"`
+	// Debug is only set when the claim was checked WithDebug.
+	Debug *PlatformDebug `json:"debug,omitempty"`
+	// Name and Txid are the publisher name and txid the post's statement
+	// claims.
+	Name string `json:"name,omitempty"`
+	Txid string `json:"txid,omitempty"`
+	// Note is information about a successful verification, e.g. that the
+	// names only matched once normalized.
+	Note string `json:"note,omitempty"`
+	// RetryAfter is set with code RATE_LIMITED.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// PlatformDebug shows how a post was checked, so that publishers can see
+// why it failed.
+type PlatformDebug struct {
+	// Text is the fetched text the templates were matched against.
+	Text    string `json:"text"`
+	Matched bool   `json:"matched"`
+	// Name and Txid are what the statement claimed, PublisherName and
+	// ExpectedAuthor what they were compared with.
+	Name           string `json:"name,omitempty"`
+	Txid           string `json:"txid,omitempty"`
+	PublisherName  string `json:"publisher_name,omitempty"`
+	ExpectedAuthor string `json:"expected_author,omitempty"`
+	// Error is the underlying error of a failed post or publisher lookup.
+	Error string `json:"error,omitempty"`
+}
+
+func (ps *PlatformStatus) fail(code, msg string) {
+	ps.Verified = false
+	ps.Code = code
+	ps.Msg = msg
+}
+
+// Legacy returns r as the unversioned endpoints respond with it, with the
+// top-level twitter and gab fields, which predate Platforms, filled from the
+// platform statuses.
+func (r *Result) Legacy() *VerificationResponse {
+	v := &VerificationResponse{
+		Code:             r.Code,
+		Verified:         r.Verified,
+		Policy:           r.Policy,
+		Adhoc:            r.Adhoc,
+		CrossPlatformMsg: r.CrossPlatformMsg,
+		Msg:              r.Msg,
+		Claim:            r.Claim,
+		Publisher:        r.Publisher,
+		Platforms:        r.Platforms,
+		RetryAfter:       r.RetryAfter,
+	}
+	if !r.legacyFields {
+		return v
+	}
+	v.TwitterCode, v.TwitterMsg = CodeNoId, "No tweet ID provided"
+	if ps, ok := r.Platforms["twitter"]; ok {
+		v.Twitter, v.TwitterCode, v.TwitterMsg, v.TwitterHandle = ps.Verified, ps.Code, ps.Msg, ps.Author
+	}
+	v.GabCode, v.GabMsg = CodeNoId, "No post ID provided"
+	if ps, ok := r.Platforms["gab"]; ok {
+		v.Gab, v.GabCode, v.GabMsg = ps.Verified, ps.Code, ps.Msg
+	}
+	return v
+}
+
+type ClaimMeta struct {
+	Txid     string `json:"txid"`
+	SignedBy string `json:"signed_by"`
+	Time     int64  `json:"time"`
+}
+
+type PublisherMeta struct {
+	Txid         string `json:"txid"`
+	Name         string `json:"name"`
+	FloBip44XPub string `json:"floBip44XPub,omitempty"`
+	SignedBy     string `json:"signed_by"`
+	Time         int64  `json:"time"`
+}
+
+// Clone returns a copy of v that shares no memory with it.
+func (v *Result) Clone() *Result {
+	c := *v
+	if v.Claim != nil {
+		claim := *v.Claim
+		c.Claim = &claim
+	}
+	if v.Publisher != nil {
+		pub := *v.Publisher
+		c.Publisher = &pub
+	}
+	if v.Platforms != nil {
+		c.Platforms = make(map[string]*PlatformStatus, len(v.Platforms))
+		for name, ps := range v.Platforms {
+			p := *ps
+			if ps.Details != nil {
+				p.Details = make(map[string]string, len(ps.Details))
+				for k, d := range ps.Details {
+					p.Details[k] = d
+				}
+			}
+			c.Platforms[name] = &p
+		}
+	}
+	return &c
+}
+
+// Codes of Result.Code and ErrorResponse.Code. CodeOK means the
+// claim was checked, not that it verified.
+const (
+	CodeOK             = "OK"
+	CodeNotFound       = "NOT_FOUND"
+	CodeBadRequest     = "BAD_REQUEST"
+	CodeNotAcceptable  = "NOT_ACCEPTABLE"
+	CodeInvalidId      = "INVALID_ID"
+	CodeClaimNotFound  = "CLAIM_NOT_FOUND"
+	CodeDeactivated    = "DEACTIVATED"
+	CodeSignerMismatch = "SIGNER_MISMATCH"
+	CodeUpstreamError  = "UPSTREAM_ERROR"
+	CodeNoPlatforms    = "NO_PLATFORMS"
+	CodeBusy           = "BUSY"
+	CodeInternal       = "INTERNAL"
+	CodeUnauthorized   = "UNAUTHORIZED"
+	CodeTooLarge       = "TOO_LARGE"
+)
+
+// Codes of PlatformStatus.Code and the legacy twitter_code and gab_code.
+// Besides these, platforms may report CodeOK, CodeInvalidId, CodeDeactivated,
+// CodeSignerMismatch, and CodeUpstreamError.
+const (
+	CodeNoId              = "NO_ID"
+	CodeBadFormat         = "BAD_FORMAT"
+	CodePostNotFound      = "POST_NOT_FOUND"
+	CodePublisherNotFound = "PUBLISHER_NOT_FOUND"
+	CodeNameMismatch      = "NAME_MISMATCH"
+	CodeAuthorMismatch    = "AUTHOR_MISMATCH"
+	CodeRateLimited       = "RATE_LIMITED"
+	CodeAuthRequired      = "AUTH_REQUIRED"
+	CodeHandleNotFound    = "HANDLE_NOT_FOUND"
+	CodePostRemoved       = "POST_REMOVED"
+	CodeTruncated         = "TRUNCATED"
+	CodeDomainNotFound    = "DOMAIN_NOT_FOUND"
+	CodePublisherMismatch = "PUBLISHER_MISMATCH"
+	CodeBadRedirect       = "BAD_REDIRECT"
+	CodeNotConfigured     = "NOT_CONFIGURED"
+	CodeNotPublic         = "NOT_PUBLIC"
+	CodeKeyMismatch       = "KEY_MISMATCH"
+	CodeSignatureInvalid  = "SIGNATURE_INVALID"
+	CodeNotSigned         = "NOT_SIGNED"
+	CodeBlocked           = "BLOCKED"
+	CodeTokenExpired      = "TOKEN_EXPIRED"
+	CodePaywalled         = "PAYWALLED"
+	CodeRegionBlocked     = "REGION_BLOCKED"
+	CodeAddressMismatch   = "ADDRESS_MISMATCH"
+	CodeDKIMFail          = "DKIM_FAIL"
+	CodeDomainMismatch    = "DOMAIN_MISMATCH"
+	CodeSuspended         = "SUSPENDED"
+)
+
+// Outcomes reported to Hooks.Outcome for each platform verification.
+const (
+	OutcomeVerified          = "verified"
+	OutcomeBadFormat         = "bad_format"
+	OutcomeNotFound          = "not_found"
+	OutcomePublisherMismatch = "publisher_mismatch"
+	OutcomeAuthorMismatch    = "author_mismatch"
+	OutcomeSignerMismatch    = "signer_mismatch"
+	OutcomeDeactivated       = "deactivated"
+	OutcomeUpstreamError     = "upstream_error"
+	OutcomeRateLimited       = "rate_limited"
+)
+
+var (
+	ErrBadFormat         = errors.New("message contents did not match expected format")
+	ErrClaimNotFound     = errors.New("unable to find verification claim by txid")
+	ErrPublisherNotFound = errors.New("unable to find publisher by txid")
+	ErrBodyTooLarge      = errors.New("upstream response exceeded maximum body size")
+	ErrRateLimited       = errors.New("upstream rate limit exceeded")
+	ErrInvalidID         = errors.New("invalid post identifier")
+	ErrPostNotFound      = errors.New("unable to find post")
+	ErrNoTemplateFormat  = errors.New("no verification template has a format")
+)
+
+// PlatformError is returned by a PlatformVerifier to report a failure that
+// has its own response code and message. Its outcome is the lowercased code.
+type PlatformError struct {
+	Code string
+	Msg  string
+}
+
+func (e *PlatformError) Error() string {
+	return e.Msg
+}
+
+// RateLimitError is returned when an upstream's rate limit is exhausted until
+// Reset. It matches ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	Target string
+	Reset  time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Target + " rate limit exceeded until " + e.Reset.Format(time.RFC3339)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfterSeconds returns how many whole seconds remain until Reset, at
+// least 1.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	s := int(math.Ceil(time.Until(e.Reset).Seconds()))
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+// StatusError is returned by httpGet when the upstream responds with a
+// non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	// Body is the start of the response body, which often explains the
+	// error.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return "upstream returned " + strconv.Itoa(e.StatusCode) + " for " + e.URL
+}
+
+// DecodeError is returned when an upstream response body could not be parsed.
+type DecodeError struct {
+	URL string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return "unable to decode " + e.URL + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// UnavailableError is returned when none of the upstreams that could answer a
+// request were reachable.
+type UnavailableError struct {
+	What string
+	Err  error
+}
+
+func (e *UnavailableError) Error() string {
+	return "unable to reach " + e.What + ": " + e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// UpstreamMsg describes err for a response message when it came from an
+// unreachable or broken upstream rather than a missing record.
+func UpstreamMsg(err error) (string, bool) {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return "upstream returned " + strconv.Itoa(se.StatusCode), se.StatusCode >= 500
+	}
+	var ue *UnavailableError
+	if errors.As(err, &ue) {
+		return ue.What + " unreachable", true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return "upstream unreachable", true
+	}
+	var de *DecodeError
+	if errors.As(err, &de) || errors.Is(err, ErrBodyTooLarge) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return "upstream returned an invalid response", true
+	}
+	return "", false
+}
@@ -0,0 +1,537 @@
+// Package verifier checks OIP verification claims against the social media
+// posts they reference.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+// Hooks lets the embedding program observe verifications, e.g. for metrics.
+// Any of the functions may be nil.
+type Hooks struct {
+	// Outcome is called once per platform verification with the platform
+	// name and one of the Outcome constants.
+	Outcome func(platform, outcome string)
+	// Upstream is called after each outbound request with its target (oip or
+	// a platform name) and how long it took.
+	Upstream func(target string, duration time.Duration)
+	// RateLimit is called with the requests remaining in the current rate
+	// limit window whenever an upstream reports it.
+	RateLimit func(target string, remaining int)
+	// Breaker is called when the circuit breaker of an upstream host
+	// changes to state, one of the Breaker constants.
+	Breaker func(target, host, state string)
+	// Concurrency is called when the requests in flight to an upstream
+	// target with a MaxConcurrent limit, or waiting for it, change.
+	Concurrency func(target string, inUse, waiting int)
+}
+
+type Verifier struct {
+	// Twitter is the v1.1 api client tweets are looked up with. When it is
+	// nil they are looked up with the v2 api and TwitterBearerToken instead.
+	// Once v is in use, they are replaced with SetTwitter.
+	Twitter            *twitter.Client
+	TwitterBearerToken string
+	OipApi             string
+	// HTTPClient makes every http request. Its transport should dial with
+	// GuardDialer, so that hosts taken from claims can't reach internal
+	// services.
+	HTTPClient *http.Client
+
+	// OipTimeout, when non-zero, bounds each request to the OIP api.
+	OipTimeout time.Duration
+	// OipAttempts is how many times a request to the OIP api is tried when it
+	// fails with a 5xx, timeout, or dropped connection. OipRetryDelay is the
+	// base of the jittered exponential backoff between attempts.
+	OipAttempts   int
+	OipRetryDelay time.Duration
+
+	// TwitterRateLimitWait is the longest a tweet lookup waits for the
+	// Twitter rate limit to reset before giving up with ErrRateLimited.
+	TwitterRateLimitWait time.Duration
+	// TwitterLimiter, when set, is consulted before every tweet lookup that
+	// misses the cache. Lookups it doesn't allow immediately fail with
+	// ErrRateLimited instead of calling the Twitter api.
+	TwitterLimiter *rate.Limiter
+
+	// BlueskyAppView is the base URL of the Bluesky appview (or PDS) that
+	// posts and handles are resolved with.
+	BlueskyAppView string
+	// NostrRelays are the relay websocket URLs nostr events are requested
+	// from, each bounded by NostrTimeout.
+	NostrRelays  []string
+	NostrTimeout time.Duration
+	// GitHubToken, when set, authenticates gist lookups for a higher rate
+	// limit.
+	GitHubToken string
+	// DNSResolver is the host:port of the nameserver DNS proofs are looked
+	// up with, or "" for the system resolver. DNSTimeout bounds each lookup.
+	DNSResolver string
+	DNSTimeout  time.Duration
+	// YouTubeAPIKey is the YouTube Data API key videos are looked up with;
+	// YouTube proofs can't be checked without one.
+	YouTubeAPIKey string
+	// PGPKeyServer is the base URL of the keyserver that publishers' PGP
+	// keys are fetched from by fingerprint.
+	PGPKeyServer string
+	// MatrixHomeserver is the base URL of the homeserver Matrix events are
+	// fetched from, with MatrixToken or, when it is empty, a guest account.
+	MatrixHomeserver string
+	MatrixToken      string
+	// FarcasterHub is the base URL of the hub HTTP api casts are fetched
+	// from.
+	FarcasterHub string
+	// HiveNode is the URL of the Hive RPC node posts are fetched from.
+	HiveNode string
+	// LBRYApi is the URL of the LBRY SDK api Odysee claims are resolved with.
+	LBRYApi string
+	// InstagramSession, when set, is the sessionid cookie of an Instagram
+	// account to fetch posts as, which Instagram blocks far less often.
+	InstagramSession string
+	// TwitchClientID and TwitchClientSecret are the credentials of the
+	// Twitch app channels are looked up as; Twitch proofs can't be checked
+	// without them.
+	TwitchClientID     string
+	TwitchClientSecret string
+	// TumblrAPIKey, when set, is the Tumblr api key posts are fetched with;
+	// otherwise they are read from the blogs' public v1 api.
+	TumblrAPIKey string
+	// FacebookToken is the app access token posts are read from the Graph
+	// API with; Facebook proofs can't be checked without one.
+	FacebookToken string
+	// SoundCloudClientID, when set, is the client id SoundCloud URLs are
+	// resolved with; otherwise their oEmbed is used.
+	SoundCloudClientID string
+	// VimeoToken, when set, is the Vimeo api token videos are fetched with;
+	// otherwise their oEmbed is used.
+	VimeoToken string
+	// GitLabURL is the base URL of the GitLab instance snippet ids refer
+	// to. GitLabToken, when set, authenticates requests to it.
+	GitLabURL   string
+	GitLabToken string
+	// DiscordBotToken is the token of the bot Discord messages are read as;
+	// Discord proofs can't be checked without one.
+	DiscordBotToken string
+	// FLOAddressIndex is the index on the external chain of a publisher's
+	// floBip44XPub of the address signed proofs must be signed by.
+	FLOAddressIndex uint32
+	// NameNFKC makes name comparisons fold compatibility variants, e.g.
+	// fullwidth letters, as well as case and whitespace.
+	NameNFKC bool
+	// Templates are the accepted wordings of the verification statement,
+	// tried in order. Once v is in use, they are replaced with Reconfigure,
+	// as are CacheTTL and CacheNegativeTTL.
+	Templates []*Template
+
+	MaxBodySize      int64
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	// CacheMaxEntries caps the entries of each cache, 0 for no cap. Full
+	// caches evict entries close to expiring to make room for new ones.
+	CacheMaxEntries int
+	SkipSignerCheck bool
+	// Breakers configure the circuit breakers requests to each upstream
+	// host go through.
+	Breakers BreakerSettings
+	// MaxConcurrent limits the requests in flight to upstream targets, e.g.
+	// oip or twitter, by target. Requests over the limit wait for one to
+	// finish, or for their context to be done.
+	MaxConcurrent map[string]int64
+	// UserAgent is sent on requests to upstreams, except to targets with
+	// their own in UserAgents.
+	UserAgent  string
+	UserAgents map[string]string
+	// Proxy, when set, returns the proxy websocket connections, e.g. to
+	// nostr relays, are made through; otherwise they use the proxy of the
+	// environment. Http requests use HTTPClient's transport's.
+	Proxy func(*http.Request) (*url.URL, error)
+	Hooks Hooks
+
+	platforms  []Platform
+	policy     string
+	claims     *ttlCache
+	publishers *ttlCache
+	posts      *ttlCache
+	dkimKeys   *ttlCache
+	// claimSearches are publishers' latest claims, by publisher txid.
+	claimSearches *ttlCache
+
+	breakers        breakerSet
+	limits          upstreamLimits
+	twitterMu       sync.RWMutex
+	settings        atomic.Value
+	matrixGuest     matrixGuest
+	twitchToken     twitchToken
+	facebookBackoff facebookBackoff
+	lastTweet       tweetLookup
+}
+
+// Settings are the options of a Verifier that Reconfigure changes while it
+// is in use.
+type Settings struct {
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	Templates        []*Template
+}
+
+// Reconfigure replaces the settings of a Verifier that is in use. Checks
+// already under way finish with the settings they started with.
+func (v *Verifier) Reconfigure(s Settings) {
+	v.settings.Store(&s)
+}
+
+// CurrentSettings returns the settings new checks start with.
+func (v *Verifier) CurrentSettings() Settings {
+	if s, ok := v.settings.Load().(*Settings); ok {
+		return *s
+	}
+	return Settings{CacheTTL: v.CacheTTL, CacheNegativeTTL: v.CacheNegativeTTL, Templates: v.Templates}
+}
+
+// withSettings pins the current settings in ctx, unless it already has them,
+// so that everything a check looks up sees the same ones.
+func (v *Verifier) withSettings(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(settingsKey).(*Settings); ok {
+		return ctx
+	}
+	s := v.CurrentSettings()
+	return context.WithValue(ctx, settingsKey, &s)
+}
+
+// settingsFor returns the settings pinned in ctx, or the current ones.
+func (v *Verifier) settingsFor(ctx context.Context) Settings {
+	if s, ok := ctx.Value(settingsKey).(*Settings); ok {
+		return *s
+	}
+	return v.CurrentSettings()
+}
+
+// SetTwitter replaces the Twitter client and bearer token of a Verifier that
+// is in use, for rotated credentials. The lookups made with the old ones no
+// longer count for LastTweetLookup.
+func (v *Verifier) SetTwitter(client *twitter.Client, bearerToken string) {
+	v.twitterMu.Lock()
+	v.Twitter, v.TwitterBearerToken = client, bearerToken
+	v.twitterMu.Unlock()
+
+	v.lastTweet.mu.Lock()
+	v.lastTweet.at, v.lastTweet.err = time.Time{}, nil
+	v.lastTweet.mu.Unlock()
+}
+
+// TwitterCredentials returns the Twitter client and bearer token.
+func (v *Verifier) TwitterCredentials() (*twitter.Client, string) {
+	v.twitterMu.RLock()
+	defer v.twitterMu.RUnlock()
+	return v.Twitter, v.TwitterBearerToken
+}
+
+// New returns a Verifier that looks up tweets with twitterClient, which may
+// be nil to use the v2 api, and OIP records from the OIP daemon api at
+// oipApi (e.g. https://api.oip.io/oip), making all other requests with
+// httpClient. All of the package's platforms are registered.
+func New(twitterClient *twitter.Client, oipApi string, httpClient *http.Client) *Verifier {
+	v := &Verifier{
+		Twitter:    twitterClient,
+		OipApi:     strings.TrimSuffix(oipApi, "/"),
+		HTTPClient: httpClient,
+
+		OipAttempts:          3,
+		OipRetryDelay:        200 * time.Millisecond,
+		TwitterRateLimitWait: 5 * time.Second,
+		HiveNode:             "https://api.hive.blog",
+		LBRYApi:              "https://api.na-backend.odysee.com/api/v1/proxy",
+		GitLabURL:            "https://gitlab.com",
+		MaxBodySize:          4 << 20,
+		CacheTTL:             10 * time.Minute,
+		CacheNegativeTTL:     30 * time.Second,
+		CacheMaxEntries:      100000,
+		Templates:            BuiltinTemplates,
+		UserAgent:            DefaultUserAgent,
+		UserAgents:           map[string]string{"reddit": redditUserAgent},
+		Breakers:             BreakerSettings{FailureRate: 0.5, MinRequests: 10, Window: time.Minute, Cooldown: 30 * time.Second, Probes: 1},
+		policy:               PolicyAny,
+
+		claims:     newTTLCache("claim"),
+		publishers: newTTLCache("publisher"),
+		posts:      newTTLCache("post"),
+		dkimKeys:   newTTLCache("dkim-key"),
+
+		claimSearches: newTTLCache("claim-search"),
+	}
+
+	for _, p := range builtinPlatforms(v) {
+		v.Register(p)
+	}
+	return v
+}
+
+// CheckClaim verifies the claim record txid on every registered platform it
+// references. A claim that can't be verified is not an error; the reasons are
+// reported in the response. The error is only non-nil when ctx ended first.
+func (v *Verifier) CheckClaim(ctx context.Context, txid string) (res *Result, err error) {
+	ctx = v.withSettings(ctx)
+	ctx, span := startSpan(ctx, "checkClaim", attribute.String("txid", txid))
+	defer func() {
+		if res != nil {
+			span.SetAttributes(attribute.String("code", res.Code), attribute.Bool("verified", res.Verified))
+		}
+		endSpan(span, err)
+	}()
+	status := &Result{}
+
+	vc, meta, err := v.getVerificationClaim(ctx, txid)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if msg, ok := UpstreamMsg(err); ok {
+			status.Code = CodeUpstreamError
+			status.Msg = "Unable to fetch verification claim with ID " + txid + ": " + msg
+			return status, nil
+		}
+		status.Code = CodeClaimNotFound
+		status.Msg = "Unable to locate verification claim with ID " + txid
+		return status, nil
+	}
+
+	status.Claim = &ClaimMeta{Txid: txid, SignedBy: meta.SignedBy, Time: meta.Time}
+	if meta.Deactivated {
+		status.Code = CodeDeactivated
+		status.Msg = "Verification claim has been deactivated"
+		status.legacyFields = true
+		return status, nil
+	}
+
+	var posts []post
+	for _, p := range v.platforms {
+		if len(p.ClaimID(vc)) != 0 {
+			posts = append(posts, claimPost(p, vc, meta))
+		}
+	}
+	if len(posts) == 0 {
+		status.Code = CodeNoPlatforms
+		status.Msg = "Verification claim doesn't reference a post on any platform"
+		status.legacyFields = true
+		return status, nil
+	}
+	err = v.checkPosts(ctx, status, posts)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// CheckPosts verifies posts, by platform name, without a claim record: each
+// must point at the publisher record pubTxid, and there is no signer check.
+// It is for publishers to try their posts before publishing the claim.
+func (v *Verifier) CheckPosts(ctx context.Context, pubTxid string, ids map[string]string) (*Result, error) {
+	status := &Result{Adhoc: true}
+	var posts []post
+	for _, p := range v.platforms {
+		if id := ids[p.Verifier.Name()]; len(id) != 0 {
+			posts = append(posts, post{platform: p, id: id, publisher: pubTxid})
+		}
+	}
+	if len(posts) == 0 {
+		status.Code = CodeNoPlatforms
+		status.Msg = "No posts were given to check"
+		status.legacyFields = true
+		return status, nil
+	}
+	err := v.checkPosts(ctx, status, posts)
+	if err != nil {
+		return nil, err
+	}
+	if status.Msg == "" {
+		status.Msg = "Checked without an on-chain verification claim"
+	}
+	return status, nil
+}
+
+// checkPosts verifies posts concurrently and fills in status from the
+// results. The error is only non-nil when ctx ended first.
+func (v *Verifier) checkPosts(ctx context.Context, status *Result, posts []post) error {
+	status.Policy = v.policy
+	status.Platforms = make(map[string]*PlatformStatus)
+	txids := make(map[string]string)
+	pubs := make(map[string]*PublisherMeta)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, pp := range posts {
+		wg.Add(1)
+		go func(pp post) {
+			defer wg.Done()
+			ps, pubTxid, pub := v.checkPlatform(ctx, pp)
+			name := pp.platform.Verifier.Name()
+			mu.Lock()
+			defer mu.Unlock()
+			status.Platforms[name] = ps
+			if len(pubTxid) != 0 {
+				txids[name] = pubTxid
+			}
+			if pub != nil {
+				pubs[pub.Txid] = pub
+			}
+		}(pp)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	status.CrossPlatformMsg = crossPlatformMsg(v.Platforms(), txids)
+	if status.CrossPlatformMsg == "" {
+		for _, pubTxid := range txids {
+			status.Publisher = pubs[pubTxid]
+		}
+	}
+	status.legacyFields = true
+	status.Verified = v.verified(status)
+
+	status.Code = CodeOK
+	for _, ps := range status.Platforms {
+		switch {
+		case ps.Code == CodeRateLimited:
+			status.Code = CodeRateLimited
+			if ps.RetryAfter > status.RetryAfter {
+				status.RetryAfter = ps.RetryAfter
+			}
+		case ps.Code == CodeUpstreamError && status.Code != CodeRateLimited:
+			status.Code = CodeUpstreamError
+		case ps.Code == CodeSignerMismatch && status.Code == CodeOK:
+			status.Code = CodeSignerMismatch
+		}
+	}
+	if status.Code == CodeRateLimited {
+		status.Msg = rateLimitedMsg(status.RetryAfter)
+	}
+	return nil
+}
+
+// Policies for Result.Verified. Any other policy is the name
+// of the one platform that must verify.
+const (
+	// PolicyAny requires at least one platform to verify.
+	PolicyAny = "any"
+	// PolicyAll requires every platform the claim references to verify.
+	PolicyAll = "all"
+)
+
+// SetPolicy sets the policy that decides Result.Verified,
+// PolicyAny unless it is set. It returns an error if policy is neither
+// PolicyAny, PolicyAll, nor a registered platform.
+func (v *Verifier) SetPolicy(policy string) error {
+	if policy == PolicyAny || policy == PolicyAll {
+		v.policy = policy
+		return nil
+	}
+	for _, name := range v.Platforms() {
+		if name == policy {
+			v.policy = policy
+			return nil
+		}
+	}
+	return errors.New("unknown policy " + policy + " (expected any, all, or one of " + strings.Join(v.Platforms(), ", ") + ")")
+}
+
+// verified applies the policy to the platform statuses. Platforms pointing
+// at different publishers never verify.
+func (v *Verifier) verified(status *Result) bool {
+	if status.CrossPlatformMsg != "" {
+		return false
+	}
+	switch v.policy {
+	case PolicyAny:
+		for _, ps := range status.Platforms {
+			if ps.Verified {
+				return true
+			}
+		}
+		return false
+	case PolicyAll:
+		for _, ps := range status.Platforms {
+			if !ps.Verified {
+				return false
+			}
+		}
+		return true
+	default:
+		ps, ok := status.Platforms[v.policy]
+		return ok && ps.Verified
+	}
+}
+
+// crossPlatformMsg describes the disagreement when posts on different
+// platforms point at different publishers, or returns "" when they agree.
+func crossPlatformMsg(order []string, txids map[string]string) string {
+	var claimed []string
+	distinct := make(map[string]bool)
+	for _, name := range order {
+		if txid, ok := txids[name]; ok {
+			claimed = append(claimed, name+": "+txid)
+			distinct[txid] = true
+		}
+	}
+	if len(distinct) < 2 {
+		return ""
+	}
+	return "Platforms claim different publishers (" + strings.Join(claimed, ", ") + ")"
+}
+
+const signerMismatchMsg = "Verification claim and publisher record are signed by different addresses"
+
+// signersMatch reports whether the claim and publisher records were signed by
+// the same address, which is always true when SkipSignerCheck is set.
+func (v *Verifier) signersMatch(claim, publisher *RMeta) bool {
+	return v.SkipSignerCheck || claim.SignedBy == publisher.SignedBy
+}
+
+func (v *Verifier) outcome(platform, outcome string) {
+	if v.Hooks.Outcome != nil {
+		v.Hooks.Outcome(platform, outcome)
+	}
+}
+
+func (v *Verifier) observeRateLimit(target string, h http.Header) {
+	if v.Hooks.RateLimit == nil {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-Rate-Limit-Remaining"))
+	if err == nil {
+		v.Hooks.RateLimit(target, remaining)
+	}
+}
+
+// observeUpstream reports a call to host of target that started at start
+// and got res or err to the hooks and host's breaker, and logs it at debug
+// level.
+func (v *Verifier) observeUpstream(ctx context.Context, target, host string, start time.Time, res *http.Response, err error) {
+	d := time.Since(start)
+	v.recordUpstream(target, host, res, err)
+	if v.Hooks.Upstream != nil {
+		v.Hooks.Upstream(target, d)
+	}
+	attrs := []interface{}{"target", target, "host", host, "duration", d.String()}
+	if res != nil {
+		attrs = append(attrs, "status", res.StatusCode)
+	}
+	if err != nil {
+		attrs = append(attrs, "err", err)
+	}
+	Logger(ctx).Debug("Upstream request", attrs...)
+}
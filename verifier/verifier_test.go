@@ -0,0 +1,505 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dghubble/go-twitter/twitter"
+)
+
+const (
+	testOipApi    = "https://api.oip.io/oip"
+	testSigner    = "FJw3DFuLyrEbr8Ba7AutMt3DSGMRzBcyuM"
+	testPubName   = "Example Publisher"
+	testPubTxid   = "4f03a060b7eb032972dcb327f2c989b14d5e73c35b3d5928a6f875ca39c159ba"
+	testOtherTxid = "2a53d651afdf99f8b5a9324f47f69f013f538236e386ddbcc7460e57df8c448d"
+	testClaimTxid = "63715582e8b5549240b257727fe6ac17b99e4272e69b30887922398a1778c133"
+)
+
+// upstream fakes every service a Verifier talks to: each request is served
+// by the handler of its host, without touching the network, and counted.
+type upstream struct {
+	mu       sync.Mutex
+	handlers map[string]http.Handler
+	requests map[string]int
+	tweets   map[string]string
+	posts    map[string]string
+	records  map[string]interface{}
+}
+
+func newUpstream() *upstream {
+	u := &upstream{
+		handlers: make(map[string]http.Handler),
+		requests: make(map[string]int),
+		tweets:   make(map[string]string),
+		posts:    make(map[string]string),
+		records:  make(map[string]interface{}),
+	}
+	u.handle("api.oip.io", u.serveRecord)
+	u.handle("api.twitter.com", u.serveTweet)
+	u.handle("gab.com", u.servePost)
+	return u
+}
+
+// handle serves the requests to host with h.
+func (u *upstream) handle(host string, h http.HandlerFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.handlers[host] = h
+}
+
+func (u *upstream) RoundTrip(req *http.Request) (*http.Response, error) {
+	u.mu.Lock()
+	h := u.handlers[req.URL.Host]
+	u.requests[req.URL.Host+req.URL.Path]++
+	u.mu.Unlock()
+	if h == nil {
+		return nil, fmt.Errorf("no fake upstream for %s", req.URL.Host)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
+
+// count returns how many requests were made to url, without its query.
+func (u *upstream) count(url string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.requests[strings.TrimPrefix(url, "https://")]
+}
+
+// countHost returns how many requests were made to host.
+func (u *upstream) countHost(host string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	n := 0
+	for url, c := range u.requests {
+		if strings.HasPrefix(url, host+"/") {
+			n += c
+		}
+	}
+	return n
+}
+
+// claim adds the claim record txid to the OIP api, with the posts in fields
+// of the verification claim template.
+func (u *upstream) claim(txid string, fields map[string]string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.records[txid] = map[string]interface{}{"tmpl_F471DFF9": fields}
+}
+
+// publisher adds the publisher record txid named name to the OIP api.
+func (u *upstream) publisher(txid, name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.records[txid] = map[string]interface{}{"tmpl_433C2783": map[string]string{"name": name}}
+}
+
+// tweet adds tweet id, by examplepub, to Twitter.
+func (u *upstream) tweet(id, text string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.tweets[id] = text
+}
+
+// post adds post id to Gab.
+func (u *upstream) post(id, body string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.posts[id] = body
+}
+
+func (u *upstream) serveRecord(w http.ResponseWriter, r *http.Request) {
+	txid := strings.TrimPrefix(r.URL.Path, "/oip/o5/record/get/")
+	u.mu.Lock()
+	details, ok := u.records[txid]
+	u.mu.Unlock()
+	results := []interface{}{}
+	if ok {
+		results = append(results, map[string]interface{}{
+			"record": map[string]interface{}{"details": details},
+			"meta":   RMeta{SignedBy: testSigner, Time: 1700000000, Txid: txid},
+		})
+	}
+	writeJSON(w, map[string]interface{}{"count": len(results), "total": len(results), "results": results})
+}
+
+func (u *upstream) serveTweet(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	u.mu.Lock()
+	text, ok := u.tweets[id]
+	u.mu.Unlock()
+	if r.URL.Path != "/1.1/statuses/show.json" || !ok {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]interface{}{"errors": []interface{}{map[string]interface{}{"code": 144, "message": "No status found with that ID."}}})
+		return
+	}
+	n, _ := strconv.ParseInt(id, 10, 64)
+	writeJSON(w, twitter.Tweet{ID: n, IDStr: id, FullText: text, User: &twitter.User{ID: 1234567890, ScreenName: "examplepub"}})
+}
+
+func (u *upstream) servePost(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/statuses/")
+	u.mu.Lock()
+	content, ok := u.posts[id]
+	u.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]string{"error": "Record not found"})
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"id":      id,
+		"content": content,
+		"account": map[string]string{"id": "1234", "username": "examplepub", "acct": "examplepub"},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newTestVerifier returns a Verifier that makes all its requests to u.
+func newTestVerifier(u *upstream) *Verifier {
+	client := &http.Client{Transport: u, Timeout: 5 * time.Second}
+	return New(twitter.NewClient(client), testOipApi, client)
+}
+
+// statement returns the verification statement of txid as name.
+func statement(name, txid string) string {
+	return `@OpenIndexProtocol verifying "` + name + `" is publishing as: ` + txid
+}
+
+// checkCode fails t unless err is, or wraps, a PlatformError with code.
+func checkCode(t *testing.T, what string, err error, code string) {
+	t.Helper()
+	var pe *PlatformError
+	if !errors.As(err, &pe) || pe.Code != code {
+		t.Errorf("%s: got error %v, want %s", what, err, code)
+	}
+}
+
+func TestCheckClaimVerified(t *testing.T) {
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001", "gabId": "111412345678901234"})
+	u.publisher(testPubTxid, testPubName)
+	u.tweet("1724567800000000001", statement(testPubName, testPubTxid))
+	u.post("111412345678901234", statement(testPubName, testPubTxid))
+	v := newTestVerifier(u)
+
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != CodeOK || !res.Verified || !res.Platforms["twitter"].Verified || !res.Platforms["gab"].Verified {
+		t.Fatalf("got %+v, want a claim verified on both platforms", res)
+	}
+	if author := res.Platforms["twitter"].Author; author != "examplepub" {
+		t.Errorf("got handle %q, want examplepub", author)
+	}
+	if res.Claim == nil || res.Claim.SignedBy != testSigner {
+		t.Errorf("got claim %+v", res.Claim)
+	}
+}
+
+func TestFailedPostSkipsPublisher(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		post   func(u *upstream)
+		msg    string
+	}{
+		{"missing tweet", map[string]string{"twitterId": "1724567800000000001"}, func(u *upstream) {}, "Tweet has been deleted"},
+		{"badly formatted tweet", map[string]string{"twitterId": "1724567800000000001"}, func(u *upstream) {
+			u.tweet("1724567800000000001", "Publishing on OIP as Example Publisher, verification coming soon!")
+		}, "Tweet contents not properly formatted"},
+		{"missing gab post", map[string]string{"gabId": "111412345678901234"}, func(u *upstream) {}, "Gab post 111412345678901234 has been deleted or does not exist"},
+		{"badly formatted gab post", map[string]string{"gabId": "111412345678901234"}, func(u *upstream) {
+			u.post("111412345678901234", "verification coming soon!")
+		}, "Post contents not properly formatted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream()
+			u.claim(testClaimTxid, tt.fields)
+			u.publisher(testPubTxid, testPubName)
+			tt.post(u)
+			v := newTestVerifier(u)
+
+			res, err := v.CheckClaim(context.Background(), testClaimTxid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Platforms) != 1 {
+				t.Fatalf("got platforms %v, want one", res.Platforms)
+			}
+			for name, ps := range res.Platforms {
+				if ps.Verified || ps.Msg != tt.msg {
+					t.Errorf("%s: got %+v, want %q", name, ps, tt.msg)
+				}
+			}
+			if n := u.countHost("api.oip.io"); n != 1 {
+				t.Errorf("made %d requests to the OIP api, want only the claim's", n)
+			}
+		})
+	}
+}
+
+func TestPlatformsIndependent(t *testing.T) {
+	const tweetID, gabID = "1724567800000000001", "111412345678901234"
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		gabTxid string
+		twitter bool
+		gab     bool
+		cross   bool
+	}{
+		{"gab only", map[string]string{"gabId": gabID}, testPubTxid, false, true, false},
+		{"twitter only", map[string]string{"twitterId": tweetID}, testPubTxid, true, false, false},
+		{"both matching", map[string]string{"twitterId": tweetID, "gabId": gabID}, testPubTxid, true, true, false},
+		{"both conflicting", map[string]string{"twitterId": tweetID, "gabId": gabID}, testOtherTxid, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream()
+			u.claim(testClaimTxid, tt.fields)
+			u.publisher(testPubTxid, testPubName)
+			u.publisher(testOtherTxid, "Other Publisher")
+			u.tweet(tweetID, statement(testPubName, testPubTxid))
+			gabName := testPubName
+			if tt.gabTxid == testOtherTxid {
+				gabName = "Other Publisher"
+			}
+			u.post(gabID, statement(gabName, tt.gabTxid))
+			v := newTestVerifier(u)
+
+			res, err := v.CheckClaim(context.Background(), testClaimTxid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			twitter, gab := res.Platforms["twitter"], res.Platforms["gab"]
+			if (twitter != nil && twitter.Verified) != tt.twitter || (gab != nil && gab.Verified) != tt.gab {
+				t.Errorf("got twitter %+v, gab %+v, want %v, %v", twitter, gab, tt.twitter, tt.gab)
+			}
+			if tt.cross != (res.CrossPlatformMsg != "") {
+				t.Errorf("got cross platform message %q, want one: %v", res.CrossPlatformMsg, tt.cross)
+			}
+		})
+	}
+}
+
+func TestTwitterHandle(t *testing.T) {
+	tests := []struct {
+		handle  string
+		twitter bool
+	}{
+		{"", true},
+		{"examplepub", true},
+		{"@ExamplePub", true},
+		{"someoneelse", false},
+	}
+	for _, tt := range tests {
+		u := newUpstream()
+		u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001", "twitterHandle": tt.handle})
+		u.publisher(testPubTxid, testPubName)
+		u.tweet("1724567800000000001", statement(testPubName, testPubTxid))
+		v := newTestVerifier(u)
+
+		res, err := v.CheckClaim(context.Background(), testClaimTxid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ps := res.Platforms["twitter"]; ps.Verified != tt.twitter || ps.Author != "examplepub" {
+			t.Errorf("claimed handle %q: got %+v, want twitter %v", tt.handle, ps, tt.twitter)
+		}
+	}
+}
+
+func TestPlatformsConcurrent(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001", "gabId": "111412345678901234"})
+	u.publisher(testPubTxid, testPubName)
+	u.tweet("1724567800000000001", statement(testPubName, testPubTxid))
+	u.post("111412345678901234", statement(testPubName, testPubTxid))
+	slow := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+			}
+			h(w, r)
+		}
+	}
+	u.handle("api.twitter.com", slow(u.serveTweet))
+	u.handle("gab.com", slow(u.servePost))
+	v := newTestVerifier(u)
+
+	start := time.Now()
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Platforms["twitter"].Verified || !res.Platforms["gab"].Verified {
+		t.Fatalf("got %+v %+v, want both verified", res.Platforms["twitter"], res.Platforms["gab"])
+	}
+	if elapsed >= 2*delay {
+		t.Errorf("check took %v, want less than the %v of both posts in turn", elapsed, 2*delay)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001"})
+	u.publisher(testPubTxid, testPubName)
+	u.tweet("1724567800000000001", statement(testPubName, testPubTxid))
+	v := newTestVerifier(u)
+	claimURL := testOipApi + "/o5/record/get/" + testClaimTxid
+
+	for i := 0; i < 2; i++ {
+		res, err := v.CheckClaim(context.Background(), testClaimTxid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Platforms["twitter"].Verified {
+			t.Fatalf("got %+v, want twitter verified", res.Platforms["twitter"])
+		}
+	}
+	if n := u.count(claimURL); n != 1 {
+		t.Errorf("fetched the claim %d times, want it cached after the first", n)
+	}
+
+	_, err := v.CheckClaim(WithRefresh(context.Background()), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := u.count(claimURL); n != 2 {
+		t.Errorf("fetched the claim %d times, want refresh to bypass the cache", n)
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	v := New(nil, testOipApi, nil)
+	v.CacheMaxEntries = 3
+	c := newTTLCache("test")
+	cache := func(ctx context.Context, key string) {
+		v.cached(ctx, c, key, func() (interface{}, error) { return key, nil })
+	}
+	for i := 0; i < 10; i++ {
+		cache(context.Background(), strconv.Itoa(i))
+	}
+	if n := len(c.entries); n != 3 {
+		t.Errorf("cache holds %d entries, want 3", n)
+	}
+	if c.evictions != 7 {
+		t.Errorf("evicted %d entries, want 7", c.evictions)
+	}
+	if _, ok := c.entries["9"]; !ok {
+		t.Error("evicted the entry just cached")
+	}
+
+	// replacing an entry makes no room for itself
+	cache(WithRefresh(context.Background()), "9")
+	if len(c.entries) != 3 || c.evictions != 7 {
+		t.Errorf("refreshing: got %d entries and %d evictions", len(c.entries), c.evictions)
+	}
+}
+
+func TestVerifyGab(t *testing.T) {
+	u := newUpstream()
+	u.post("111412345678901234", statement(testPubName, testPubTxid))
+	v := newTestVerifier(u)
+
+	for _, id := range []string{"111412345678901234", "https://gab.com/examplepub/posts/111412345678901234"} {
+		name, txid, username, err := v.VerifyGab(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != testPubName || txid != testPubTxid || username != "examplepub" {
+			t.Errorf("%s: got %q, %s, %s", id, name, txid, username)
+		}
+	}
+	_, _, _, err := v.VerifyGab(context.Background(), "1")
+	checkCode(t, "missing post", err, CodePostNotFound)
+	_, _, _, err = v.VerifyGab(context.Background(), "https://example.com/posts/1")
+	checkCode(t, "not a Gab URL", err, CodeInvalidId)
+}
+
+func TestCheckClaimNameForms(t *testing.T) {
+	tests := []struct {
+		claimed string
+		code    string
+		note    bool
+	}{
+		{testPubName, CodeOK, false},
+		{"example publisher", CodeOK, true},
+		{"Example  Publisher", CodeOK, true},
+		{"\uff25xample Publisher", CodeNameMismatch, false},
+		{"Other Publisher", CodeNameMismatch, false},
+	}
+	for _, tt := range tests {
+		u := newUpstream()
+		u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001"})
+		u.publisher(testPubTxid, testPubName)
+		u.tweet("1724567800000000001", statement(tt.claimed, testPubTxid))
+		v := newTestVerifier(u)
+
+		res, err := v.CheckClaim(context.Background(), testClaimTxid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := res.Platforms["twitter"]
+		if s == nil {
+			t.Fatalf("%q: no twitter status in %+v", tt.claimed, res)
+		}
+		if s.Code != tt.code {
+			t.Errorf("%q: got %s, want %s", tt.claimed, s.Code, tt.code)
+		}
+		if got := s.Note != ""; got != tt.note {
+			t.Errorf("%q: got note %q, want one %v", tt.claimed, s.Note, tt.note)
+		}
+		if tt.note && !strings.Contains(s.Note, `"`+tt.claimed+`"`) {
+			t.Errorf("%q: note %q doesn't quote the claimed name", tt.claimed, s.Note)
+		}
+	}
+
+	// with NameNFKC, full-width letters are the same name
+	u := newUpstream()
+	u.claim(testClaimTxid, map[string]string{"twitterId": "1724567800000000001"})
+	u.publisher(testPubTxid, testPubName)
+	u.tweet("1724567800000000001", statement("\uff25xample Publisher", testPubTxid))
+	v := newTestVerifier(u)
+	v.NameNFKC = true
+	res, err := v.CheckClaim(context.Background(), testClaimTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := res.Platforms["twitter"]; s == nil || s.Code != CodeOK || s.Note == "" {
+		t.Errorf("NameNFKC: got %+v, want verified with a note", s)
+	}
+}
+
+func TestVerifyTwitterNotConfigured(t *testing.T) {
+	v := New(nil, testOipApi, &http.Client{Transport: newUpstream()})
+
+	_, _, _, err := v.VerifyTwitter(context.Background(), "1724567800000000001")
+	checkCode(t, "without credentials", err, CodeNotConfigured)
+}
@@ -0,0 +1,99 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+type vimeoVideo struct {
+	Description string `json:"description"`
+	User        struct {
+		Name string `json:"name"`
+	} `json:"user"`
+	Privacy struct {
+		View string `json:"view"`
+	} `json:"privacy"`
+}
+
+type vimeoOEmbed struct {
+	Description string `json:"description"`
+	AuthorName  string `json:"author_name"`
+}
+
+var vimeoIDRegex = regexp.MustCompile(`^[0-9]{1,12}$`)
+
+// VerifyVimeo fetches the video id with the Vimeo api, or its oEmbed when no
+// VimeoToken is configured, and returns the publisher name and txid from the
+// verification statement in its description along with the uploader's name.
+func (v *Verifier) VerifyVimeo(ctx context.Context, id string) (name string, txid string, uploader string, err error) {
+	if !vimeoIDRegex.MatchString(id) {
+		return "", "", "", ErrInvalidID
+	}
+
+	var description string
+	if v.VimeoToken != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.vimeo.com/videos/"+id+"?fields=description,user.name,privacy.view", nil)
+		if err != nil {
+			return "", "", "", err
+		}
+		req.Header.Set("Accept", "application/vnd.vimeo.*+json;version=3.4")
+		req.Header.Set("Authorization", "Bearer "+v.VimeoToken)
+		var body []byte
+		body, err = v.doRequest("vimeo", req, v.MaxBodySize)
+		if err == nil {
+			video := &vimeoVideo{}
+			if err = json.Unmarshal(body, video); err != nil {
+				err = &DecodeError{URL: "vimeo video " + id, Err: err}
+			} else if video.Privacy.View == "password" {
+				err = &StatusError{URL: "vimeo video " + id, StatusCode: http.StatusForbidden}
+			}
+			description, uploader = video.Description, video.User.Name
+		}
+		if isStatus(err, http.StatusUnauthorized) {
+			return "", "", "", &PlatformError{Code: CodeNotConfigured, Msg: "Vimeo rejected this server's access token"}
+		}
+	} else {
+		q := url.Values{"url": {"https://vimeo.com/" + id}}
+		var body []byte
+		body, err = v.httpGet(ctx, "vimeo", "https://vimeo.com/api/oembed.json?"+q.Encode())
+		if err == nil {
+			oe := &vimeoOEmbed{}
+			if err = json.Unmarshal(body, oe); err != nil {
+				err = &DecodeError{URL: "vimeo oembed " + id, Err: err}
+			}
+			description, uploader = oe.Description, oe.AuthorName
+		}
+	}
+	if err != nil {
+		switch {
+		case isStatus(err, http.StatusForbidden):
+			return "", "", uploader, &PlatformError{Code: CodeNotPublic, Msg: "Vimeo video " + id + " is password protected or private"}
+		case isStatus(err, http.StatusNotFound):
+			return "", "", "", &PlatformError{Code: CodePostNotFound, Msg: "Vimeo video " + id + " has been deleted or does not exist"}
+		}
+		return "", "", uploader, err
+	}
+
+	name, txid, err = v.matchVerification(ctx, description)
+	return name, txid, uploader, err
+}
+
+type vimeoPlatform struct {
+	v *Verifier
+}
+
+func (p vimeoPlatform) Name() string {
+	return "vimeo"
+}
+
+func (p vimeoPlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	name, txid, _, err := p.v.VerifyVimeo(ctx, id)
+	return name, txid, err
+}
+
+func (p vimeoPlatform) VerifyAuthor(ctx context.Context, id string) (string, string, string, error) {
+	return p.v.VerifyVimeo(ctx, id)
+}
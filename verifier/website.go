@@ -0,0 +1,149 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	wellKnownPath         = "/.well-known/oip-verification"
+	wellKnownMaxBodySize  = 8 << 10
+	wellKnownMaxRedirects = 3
+)
+
+type wellKnownFile struct {
+	Name      string `json:"name"`
+	Publisher string `json:"publisher"`
+}
+
+// websiteHost returns the host of a websiteUrl claim value, which may be a
+// bare domain or an https URL.
+func websiteHost(website string) (string, error) {
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+	u, err := url.Parse(website)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return "", ErrInvalidID
+	}
+	return u.Host, nil
+}
+
+// VerifyWebsite fetches https://<host>/.well-known/oip-verification for the
+// claim's website and returns the publisher name and txid it declares. Only
+// https is used, and at most 3 redirects are followed, all of which must stay
+// on the site's registrable domain.
+func (v *Verifier) VerifyWebsite(ctx context.Context, website string) (name string, txid string, host string, err error) {
+	host, err = websiteHost(website)
+	if err != nil {
+		return "", "", "", err
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(hostname(host)))
+	if err != nil {
+		return "", "", "", ErrInvalidID
+	}
+
+	client := *v.HTTPClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > wellKnownMaxRedirects {
+			return redirectError("more than 3 redirects")
+		}
+		if req.URL.Scheme != "https" {
+			return redirectError("redirected to a non-https URL")
+		}
+		target, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(req.URL.Hostname()))
+		if err != nil || target != site {
+			return redirectError("redirected off " + site)
+		}
+		return nil
+	}
+
+	u := "https://" + host + wellKnownPath
+	req, err := http.NewRequestWithContext(toClaimHost(ctx), http.MethodGet, u, nil)
+	if err != nil {
+		return "", "", host, err
+	}
+	req.Header.Set("Accept", "application/json")
+	v.setUserAgent(req.Header, "website")
+
+	err = v.allowUpstream("website", req.URL.Host)
+	if err != nil {
+		return "", "", host, err
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	v.observeUpstream(ctx, "website", req.URL.Host, start, res, err)
+	if err != nil {
+		var re redirectError
+		if errors.As(err, &re) {
+			return "", "", host, &PlatformError{Code: CodeBadRedirect, Msg: "Unable to fetch " + u + ": " + string(re)}
+		}
+		return "", "", host, notPublicError(err, req.URL.Host)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+		if res.StatusCode == http.StatusNotFound {
+			return "", "", host, &PlatformError{Code: CodePostNotFound, Msg: "No verification file at " + u}
+		}
+		return "", "", host, &StatusError{URL: u, StatusCode: res.StatusCode, Header: res.Header}
+	}
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return "", "", host, &PlatformError{Code: CodeBadFormat, Msg: "Verification file at " + u + " is not JSON (Content-Type " + res.Header.Get("Content-Type") + ")"}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, wellKnownMaxBodySize+1))
+	if err != nil {
+		return "", "", host, err
+	}
+	if len(body) > wellKnownMaxBodySize {
+		return "", "", host, &PlatformError{Code: CodeBadFormat, Msg: "Verification file at " + u + " is larger than 8KB"}
+	}
+
+	file := &wellKnownFile{}
+	err = json.Unmarshal(body, file)
+	if err != nil || !txidRegex.MatchString(file.Publisher) || file.Name == "" {
+		return "", "", host, ErrBadFormat
+	}
+	return file.Name, file.Publisher, host, nil
+}
+
+type redirectError string
+
+func (e redirectError) Error() string {
+	return string(e)
+}
+
+// hostname strips any port from host.
+func hostname(host string) string {
+	return (&url.URL{Host: host}).Hostname()
+}
+
+type websitePlatform struct {
+	v *Verifier
+}
+
+func (w websitePlatform) Name() string {
+	return "website"
+}
+
+func (w websitePlatform) Verify(ctx context.Context, website string) (string, string, error) {
+	name, txid, _, err := w.v.VerifyWebsite(ctx, website)
+	return name, txid, err
+}
+
+func (w websitePlatform) VerifyAuthor(ctx context.Context, website string) (string, string, string, error) {
+	return w.v.VerifyWebsite(ctx, website)
+}
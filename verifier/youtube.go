@@ -0,0 +1,107 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+type youtubeVideos struct {
+	Items []struct {
+		Snippet struct {
+			Description  string `json:"description"`
+			ChannelID    string `json:"channelId"`
+			ChannelTitle string `json:"channelTitle"`
+		} `json:"snippet"`
+		Status struct {
+			PrivacyStatus string `json:"privacyStatus"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type youtubeError struct {
+	Error struct {
+		Errors []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+var youtubeIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// VerifyYouTube fetches the video id with the YouTube Data API and returns
+// the verification statement in its description along with its channel.
+func (v *Verifier) VerifyYouTube(ctx context.Context, id string) (*Proof, error) {
+	if !youtubeIDRegex.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+	if v.YouTubeAPIKey == "" {
+		return nil, &PlatformError{Code: CodeNotConfigured, Msg: "YouTube verification is not configured on this server"}
+	}
+
+	q := url.Values{"part": {"snippet,status"}, "id": {id}, "key": {v.YouTubeAPIKey}}
+	body, err := v.httpGet(ctx, "youtube", "https://www.googleapis.com/youtube/v3/videos?"+q.Encode())
+	if err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.StatusCode == http.StatusForbidden && youtubeQuotaExceeded(se.Body) {
+			// the quota resets daily, but retrying hourly is soon enough
+			return nil, &RateLimitError{Target: "youtube", Reset: time.Now().Add(time.Hour)}
+		}
+		return nil, err
+	}
+
+	videos := &youtubeVideos{}
+	err = json.Unmarshal(body, videos)
+	if err != nil {
+		return nil, &DecodeError{URL: "youtube video " + id, Err: err}
+	}
+	if len(videos.Items) == 0 {
+		return nil, &PlatformError{Code: CodePostNotFound, Msg: "YouTube video " + id + " is private or does not exist"}
+	}
+	video := videos.Items[0]
+	proof := &Proof{
+		Author:  video.Snippet.ChannelTitle,
+		Details: map[string]string{"channel_id": video.Snippet.ChannelID},
+	}
+	if video.Status.PrivacyStatus != "" && video.Status.PrivacyStatus != "public" {
+		return proof, &PlatformError{Code: CodeNotPublic, Msg: "YouTube video " + id + " is " + video.Status.PrivacyStatus + ", not public"}
+	}
+
+	proof.Name, proof.Txid, err = v.matchVerification(ctx, video.Snippet.Description)
+	return proof, err
+}
+
+func youtubeQuotaExceeded(body []byte) bool {
+	ye := &youtubeError{}
+	_ = json.Unmarshal(body, ye)
+	for _, e := range ye.Error.Errors {
+		if e.Reason == "quotaExceeded" || e.Reason == "dailyLimitExceeded" || e.Reason == "rateLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+type youtubePlatform struct {
+	v *Verifier
+}
+
+func (y youtubePlatform) Name() string {
+	return "youtube"
+}
+
+func (y youtubePlatform) Verify(ctx context.Context, id string) (string, string, error) {
+	proof, err := y.v.VerifyYouTube(ctx, id)
+	if proof == nil {
+		return "", "", err
+	}
+	return proof.Name, proof.Txid, err
+}
+
+func (y youtubePlatform) VerifyProof(ctx context.Context, id string) (*Proof, error) {
+	return y.v.VerifyYouTube(ctx, id)
+}
@@ -0,0 +1,5 @@
+// Package verifierpb is the gRPC api of the verifier, served with serve
+// -grpc-listen. Use NewVerifierServiceClient to call it.
+package verifierpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative verifier.proto
@@ -0,0 +1,180 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: verifier.proto
+
+package verifierpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	VerifierService_CheckClaim_FullMethodName = "/oipwg.verifier.v1.VerifierService/CheckClaim"
+	VerifierService_BatchCheck_FullMethodName = "/oipwg.verifier.v1.VerifierService/BatchCheck"
+)
+
+// VerifierServiceClient is the client API for VerifierService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VerifierServiceClient interface {
+	// CheckClaim checks the verification claim with txid.
+	CheckClaim(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+	// BatchCheck checks each of txids, sending each response as its check
+	// finishes.
+	BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (VerifierService_BatchCheckClient, error)
+}
+
+type verifierServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVerifierServiceClient(cc grpc.ClientConnInterface) VerifierServiceClient {
+	return &verifierServiceClient{cc}
+}
+
+func (c *verifierServiceClient) CheckClaim(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, VerifierService_CheckClaim_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *verifierServiceClient) BatchCheck(ctx context.Context, in *BatchCheckRequest, opts ...grpc.CallOption) (VerifierService_BatchCheckClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VerifierService_ServiceDesc.Streams[0], VerifierService_BatchCheck_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &verifierServiceBatchCheckClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VerifierService_BatchCheckClient interface {
+	Recv() (*CheckResponse, error)
+	grpc.ClientStream
+}
+
+type verifierServiceBatchCheckClient struct {
+	grpc.ClientStream
+}
+
+func (x *verifierServiceBatchCheckClient) Recv() (*CheckResponse, error) {
+	m := new(CheckResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VerifierServiceServer is the server API for VerifierService service.
+// All implementations must embed UnimplementedVerifierServiceServer
+// for forward compatibility
+type VerifierServiceServer interface {
+	// CheckClaim checks the verification claim with txid.
+	CheckClaim(context.Context, *CheckRequest) (*CheckResponse, error)
+	// BatchCheck checks each of txids, sending each response as its check
+	// finishes.
+	BatchCheck(*BatchCheckRequest, VerifierService_BatchCheckServer) error
+	mustEmbedUnimplementedVerifierServiceServer()
+}
+
+// UnimplementedVerifierServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedVerifierServiceServer struct {
+}
+
+func (UnimplementedVerifierServiceServer) CheckClaim(context.Context, *CheckRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckClaim not implemented")
+}
+func (UnimplementedVerifierServiceServer) BatchCheck(*BatchCheckRequest, VerifierService_BatchCheckServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchCheck not implemented")
+}
+func (UnimplementedVerifierServiceServer) mustEmbedUnimplementedVerifierServiceServer() {}
+
+// UnsafeVerifierServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VerifierServiceServer will
+// result in compilation errors.
+type UnsafeVerifierServiceServer interface {
+	mustEmbedUnimplementedVerifierServiceServer()
+}
+
+func RegisterVerifierServiceServer(s grpc.ServiceRegistrar, srv VerifierServiceServer) {
+	s.RegisterService(&VerifierService_ServiceDesc, srv)
+}
+
+func _VerifierService_CheckClaim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServiceServer).CheckClaim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VerifierService_CheckClaim_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServiceServer).CheckClaim(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VerifierService_BatchCheck_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BatchCheckRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VerifierServiceServer).BatchCheck(m, &verifierServiceBatchCheckServer{stream})
+}
+
+type VerifierService_BatchCheckServer interface {
+	Send(*CheckResponse) error
+	grpc.ServerStream
+}
+
+type verifierServiceBatchCheckServer struct {
+	grpc.ServerStream
+}
+
+func (x *verifierServiceBatchCheckServer) Send(m *CheckResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// VerifierService_ServiceDesc is the grpc.ServiceDesc for VerifierService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VerifierService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "oipwg.verifier.v1.VerifierService",
+	HandlerType: (*VerifierServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckClaim",
+			Handler:    _VerifierService_CheckClaim_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchCheck",
+			Handler:       _VerifierService_BatchCheck_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "verifier.proto",
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oipwg/verifier/verifier"
+)
+
+// v1Router serves the endpoints that respond with checks in the
+// verifier.Result schema. Their unversioned routes keep responding with
+// verifier.VerificationResponse.
+var v1Router = rootRouter.PathPrefix("/v1").Subrouter()
+
+func init() {
+	v1Router.NotFoundHandler = http.HandlerFunc(handle404)
+	v1Router.Use(markV1)
+	v1Router.HandleFunc("/publisher/check/{id:[a-f0-9]{64}}", handleCheck).Methods(http.MethodGet, http.MethodHead)
+	v1Router.HandleFunc("/publisher/check", limitBody(handleBatchCheck)).Methods(http.MethodPost)
+	protect(v1Router, "/publisher/check/{id:[a-f0-9]{64}}/async", limitBody(handleAsyncCheck)).Methods(http.MethodPost)
+	protect(v1Router, "/jobs/{job:[a-f0-9]{32}}", handleJob).Methods(http.MethodGet).Name("v1-job")
+	v1Router.HandleFunc("/publisher/by-publisher/{id:[a-f0-9]{64}}", handleByPublisher).Methods(http.MethodGet)
+	v1Router.HandleFunc("/publisher/by-name/{name}", handleByName).Methods(http.MethodGet)
+	v1Router.HandleFunc("/adhoc", handleAdhoc).Methods(http.MethodGet)
+}
+
+var (
+	// legacyDeprecation and legacySunset, when set, are sent in the
+	// Deprecation and Sunset headers of unversioned check responses.
+	legacyDeprecation time.Time
+	legacySunset      time.Time
+)
+
+type v1Key struct{}
+
+func markV1(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), v1Key{}, true)))
+	})
+}
+
+func isV1(r *http.Request) bool {
+	v, _ := r.Context().Value(v1Key{}).(bool)
+	return v
+}
+
+// versioned returns res in the schema of the api version r was routed to.
+// Unversioned responses are marked deprecated, if configured, with a link to
+// their /verified/v1 successor.
+func versioned(w http.ResponseWriter, r *http.Request, res *verifier.Result) interface{} {
+	if res == nil || isV1(r) {
+		return res
+	}
+	h := w.Header()
+	if h.Get("Link") == "" && (!legacyDeprecation.IsZero() || !legacySunset.IsZero()) {
+		h.Set("Link", `<`+"/verified/v1"+strings.TrimPrefix(r.URL.Path, "/verified")+`>; rel="successor-version"`)
+	}
+	if !legacyDeprecation.IsZero() {
+		h.Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecation.Unix(), 10))
+	}
+	if !legacySunset.IsZero() {
+		h.Set("Sunset", legacySunset.UTC().Format(http.TimeFormat))
+	}
+	return res.Legacy()
+}
+
+// parseDate parses an optional YYYY-MM-DD date flag.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
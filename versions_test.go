@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// Claims of TestResponseGolden besides those of fakeUpstreams.
+const (
+	deactivatedClaim = "d9c3a1f0d4e5b6a7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1"
+	rateLimitedClaim = "e2b1c0d9f8e7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1"
+)
+
+func TestResponseGolden(t *testing.T) {
+	const signer = "FJw3DFuLyrEbr8Ba7AutMt3DSGMRzBcyuM"
+	records := map[string]string{
+		verifiedClaim:    `{"record": {"details": {"tmpl_F471DFF9": {"twitterId": "1724567800000000001"}}}, "meta": {"signed_by": "` + signer + `", "time": 1700000000, "txid": "` + verifiedClaim + `"}}`,
+		badFormatClaim:   `{"record": {"details": {"tmpl_F471DFF9": {"twitterId": "1724567800000000002"}}}, "meta": {"signed_by": "` + signer + `", "time": 1700000000, "txid": "` + badFormatClaim + `"}}`,
+		deactivatedClaim: `{"record": {"details": {"tmpl_F471DFF9": {"twitterId": "1724567800000000001"}}}, "meta": {"deactivated": true, "signed_by": "` + signer + `", "time": 1700000000, "txid": "` + deactivatedClaim + `"}}`,
+		rateLimitedClaim: `{"record": {"details": {"tmpl_F471DFF9": {"twitterId": "1724567800000000009"}}}, "meta": {"signed_by": "` + signer + `", "time": 1700000000, "txid": "` + rateLimitedClaim + `"}}`,
+		fixturePublisher: `{"record": {"details": {"tmpl_433C2783": {"name": "Example Publisher"}}}, "meta": {"signed_by": "` + signer + `", "time": 1699990000, "txid": "` + fixturePublisher + `"}}`,
+	}
+	oip := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := records[strings.TrimPrefix(r.URL.Path, "/oip/o5/record/get/")]
+		if !ok {
+			fmt.Fprint(w, `{"count": 0, "total": 0, "results": []}`)
+			return
+		}
+		fmt.Fprintf(w, `{"count": 1, "total": 1, "results": [%s]}`, rec)
+	}))
+	defer oip.Close()
+
+	// the rate limit reset in the past makes Retry-After always 1
+	twitter := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/1724567800000000009") {
+			h := http.Header{"Content-Type": {"application/json"}, "X-Rate-Limit-Reset": {"1700000000"}}
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: h, Body: ioutil.NopCloser(strings.NewReader(`{"title": "Too Many Requests"}`)), Request: req}, nil
+		}
+		rec := httptest.NewRecorder()
+		serveFixture(rec, req)
+		return rec.Result(), nil
+	})
+	oldTransport := httpClient.Transport
+	t.Cleanup(func() { httpClient.Transport = oldTransport })
+	httpClient.Transport = hostTransport{map[string]http.RoundTripper{"api.twitter.com": twitter}, oldTransport}
+	err := setupFlags(t, "-oip-api", oip.URL+"/oip", "-bearer-token", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, claim string
+		code        int
+	}{
+		{"verified", verifiedClaim, http.StatusOK},
+		{"bad-format", badFormatClaim, http.StatusOK},
+		{"claim-not-found", noPublisherClaim, http.StatusNotFound},
+		{"deactivated", deactivatedClaim, http.StatusOK},
+		{"rate-limited", rateLimitedClaim, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		for _, version := range []string{"legacy", "v1"} {
+			path := "/verified/publisher/check/" + tt.claim
+			if version == "v1" {
+				path = "/verified/v1/publisher/check/" + tt.claim
+			}
+			rec := get(path + "?refresh=true")
+			if rec.Code != tt.code {
+				t.Errorf("%s %s: got %d, want %d", tt.name, version, rec.Code, tt.code)
+			}
+			golden := filepath.Join("testdata", "golden", tt.name+"."+version+".json")
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, rec.Body.Bytes(), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), want) {
+				t.Errorf("%s %s: got\n%s\nwant\n%s", tt.name, version, rec.Body, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/oipwg/verifier/store"
+	"github.com/oipwg/verifier/verifier"
+)
+
+var (
+	webhookURLs stringsFlag
+	// webhookTargets are the webhookURLs deliveries are queued for,
+	// replaced when the config is reloaded.
+	webhookTargets atomic.Value
+	// webhookSecret is the string deliveries are signed with, reloaded on
+	// SIGHUP.
+	webhookSecret atomic.Value
+	webhookQueue  = make(chan *webhookDelivery, 100)
+
+	// webhookAttempts is how many times a delivery is tried, waiting
+	// webhookBackoff after the first failure and doubling after each one.
+	webhookAttempts = 5
+	webhookBackoff  = time.Second
+)
+
+// stringsFlag is a flag that may be given more than once, or as a comma
+// separated list.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
+
+// A TransitionEvent reports that a claim's verification changed, or, in
+// the check events of /verified/stream, the outcome of a check, with
+// Previous the same as Current.
+type TransitionEvent struct {
+	Claim     string    `json:"claim"`
+	Publisher string    `json:"publisher,omitempty"`
+	Previous  bool      `json:"previous"`
+	Current   bool      `json:"current"`
+	ChangedAt time.Time `json:"changed_at"`
+	// Reasons are the codes of the check that made the transition, by
+	// platform, with the check's own code under "claim".
+	Reasons map[string]string `json:"reasons"`
+}
+
+type webhookDelivery struct {
+	url  string
+	body []byte
+}
+
+func newEvent(txid string, status *verifier.Result, previous bool, at time.Time, code string) *TransitionEvent {
+	ev := &TransitionEvent{
+		Claim:     txid,
+		Previous:  previous,
+		Current:   status.Verified,
+		ChangedAt: at.UTC(),
+		Reasons:   map[string]string{"claim": code},
+	}
+	if status.Publisher != nil {
+		ev.Publisher = status.Publisher.Txid
+	}
+	for name, ps := range status.Platforms {
+		ev.Reasons[name] = ps.Code
+	}
+	return ev
+}
+
+// notifyTransition tells the webhooks and streams that claim txid's
+// verification changed with the check status.
+func notifyTransition(txid string, status *verifier.Result, t *store.Transition) {
+	ev := newEvent(txid, status, !t.Verified, time.Unix(0, t.Time*int64(time.Millisecond)), t.Code)
+	publishEvent("transition", ev)
+
+	urls, _ := webhookTargets.Load().([]string)
+	if len(urls) == 0 {
+		return
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("Unable to encode webhook payload", "txid", txid, "err", err)
+		return
+	}
+	for _, u := range urls {
+		select {
+		case webhookQueue <- &webhookDelivery{url: u, body: body}:
+		default:
+			webhookDeliveries.WithLabelValues("dropped").Inc()
+			slog.Error("Webhook queue is full, dropping delivery", "txid", txid, "url", u)
+		}
+	}
+}
+
+// deliverWebhooks sends queued deliveries until ctx is done.
+func deliverWebhooks(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-webhookQueue:
+			err := d.deliver(ctx)
+			if err != nil {
+				webhookDeliveries.WithLabelValues("failed").Inc()
+				slog.Error("Unable to deliver webhook", "url", d.url, "err", err)
+				continue
+			}
+			webhookDeliveries.WithLabelValues("delivered").Inc()
+		}
+	}
+}
+
+// deliver posts the payload, retrying timeouts, network errors and 5xx
+// responses with backoff.
+func (d *webhookDelivery) deliver(ctx context.Context) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		retry, err = d.post(ctx)
+		if err == nil || !retry || attempt == webhookAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying.
+func (d *webhookDelivery) post(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret, _ := webhookSecret.Load().(string); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(d.body)
+		req.Header.Set("X-Verifier-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode >= 300 {
+		return res.StatusCode >= 500, &verifier.StatusError{URL: d.url, StatusCode: res.StatusCode}
+	}
+	return false, nil
+}